@@ -2,7 +2,9 @@ package main
 
 import (
 	"be/config"
+	"be/internal/fakeworker"
 	"be/internal/mediator"
+	"flag"
 	"strings"
 	"time"
 
@@ -11,6 +13,9 @@ import (
 )
 
 func main() {
+	fakeWorker := flag.Bool("fake-worker", false, "serve generations from an in-process fake worker instead of the python worker")
+	fakeStepDelay := flag.Duration("fake-step-delay", 50*time.Millisecond, "time the fake worker spends per sampling step")
+	flag.Parse()
 
 	cfg, err := gonfig.Load[config.Config](
 		gonfig.WithConfigFile("config/config.yaml"),
@@ -23,6 +28,17 @@ func main() {
 
 	setupLogger(cfg.Api.LogLevel)
 
+	if *fakeWorker {
+		fw := fakeworker.New(*fakeStepDelay)
+		addr, err := fw.Start("127.0.0.1:0")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer fw.Stop()
+		log.Warn("using fake worker; images are placeholders", "addr", addr)
+		cfg.Rpc.Peers = addr
+	}
+
 	app, err := mediator.NewApp(cfg)
 	if err != nil {
 		log.Fatal(err)
@@ -5,28 +5,72 @@ package config
 import "fmt"
 
 type ApiConfig struct {
-	AllowedOrigins string      `yaml:"allowed_origins"`
-	Dl             ApiDlConfig `yaml:"dl"`
-	LogLevel       string      `yaml:"log_level"`
-	Port           string      `yaml:"port"`
+	AllowedOrigins string           `yaml:"allowed_origins"`
+	Dl             ApiDlConfig      `yaml:"dl"`
+	Gen            ApiGenConfig     `yaml:"gen"`
+	History        ApiHistoryConfig `yaml:"history"`
+	Library        ApiLibraryConfig `yaml:"library"`
+	LogLevel       string           `yaml:"log_level"`
+	Port           string           `yaml:"port"`
 }
 
 type ApiDlClientConfig struct {
 	ApiKey      string `yaml:"apiKey"`
+	ByHashUrl   string `yaml:"byHashUrl"`
 	DownloadUrl string `yaml:"downloadUrl"`
 	ModeInfoUrl string `yaml:"modeInfoUrl"`
+	ModelUrl    string `yaml:"modelUrl"`
+	SearchUrl   string `yaml:"searchUrl"`
 }
 
 type ApiDlConfig struct {
-	BaseDir       string            `yaml:"baseDir"`
-	Client        ApiDlClientConfig `yaml:"client"`
-	MaxConcurrent int               `yaml:"maxConcurrent"`
-	QueueSize     int               `yaml:"queueSize"`
+	BackfillMinutes  int               `yaml:"backfillMinutes"`
+	BaseDir          string            `yaml:"baseDir"`
+	Client           ApiDlClientConfig `yaml:"client"`
+	MaxConcurrent    int               `yaml:"maxConcurrent"`
+	MetadataTtlHours int               `yaml:"metadataTtlHours"`
+	Policy           ApiDlPolicyConfig `yaml:"policy"`
+	QuarantineDir    string            `yaml:"quarantineDir"`
+	QueueSize        int               `yaml:"queueSize"`
+	UpdateCheckHours int               `yaml:"updateCheckHours"`
+}
+
+type ApiDlPolicyConfig struct {
+	AdminToken        string `yaml:"adminToken"`
+	MaxSizeMb         int    `yaml:"maxSizeMb"`
+	RequireCleanScans bool   `yaml:"requireCleanScans"`
+	SafetensorsOnly   bool   `yaml:"safetensorsOnly"`
+}
+
+type ApiGenConfig struct {
+	MaxConcurrent int `yaml:"maxConcurrent"`
+	QueueSize     int `yaml:"queueSize"`
+}
+
+type ApiHistoryConfig struct {
+	DbPath     string `yaml:"dbPath"`
+	OutputsDir string `yaml:"outputsDir"`
+	ThumbsDir  string `yaml:"thumbsDir"`
+}
+
+type ApiLibraryConfig struct {
+	ControlnetsDir  string `yaml:"controlnetsDir"`
+	EmbeddingsDir   string `yaml:"embeddingsDir"`
+	IndexPath       string `yaml:"indexPath"`
+	LorasDir        string `yaml:"lorasDir"`
+	LycorisDir      string `yaml:"lycorisDir"`
+	ModelsDir       string `yaml:"modelsDir"`
+	RescanSeconds   int    `yaml:"rescanSeconds"`
+	UpscalersDir    string `yaml:"upscalersDir"`
+	VaesDir         string `yaml:"vaesDir"`
+	WatchDebounceMs int    `yaml:"watchDebounceMs"`
 }
 
 type RpcConfig struct {
-	Peer string `yaml:"peer"`
-	Port string `yaml:"port"`
+	HealthCheckSeconds int    `yaml:"healthCheckSeconds"`
+	Peer               string `yaml:"peer"`
+	Peers              string `yaml:"peers"`
+	Port               string `yaml:"port"`
 }
 
 type Config struct {
@@ -64,6 +108,33 @@ func (c Config) Validate() error {
 	if c.Api.Dl.MaxConcurrent > 10 {
 		return fmt.Errorf("api.dl.maxConcurrent must be <= 10")
 	}
+	if c.Api.Dl.MetadataTtlHours < 0 {
+		return fmt.Errorf("api.dl.metadataTtlHours must be >= 0")
+	}
+	if c.Api.Dl.MetadataTtlHours > 8760 {
+		return fmt.Errorf("api.dl.metadataTtlHours must be <= 8760")
+	}
+	if c.Api.Dl.BackfillMinutes < 0 {
+		return fmt.Errorf("api.dl.backfillMinutes must be >= 0")
+	}
+	if c.Api.Dl.BackfillMinutes > 10080 {
+		return fmt.Errorf("api.dl.backfillMinutes must be <= 10080")
+	}
+	if c.Api.Dl.UpdateCheckHours < 0 {
+		return fmt.Errorf("api.dl.updateCheckHours must be >= 0")
+	}
+	if c.Api.Dl.UpdateCheckHours > 720 {
+		return fmt.Errorf("api.dl.updateCheckHours must be <= 720")
+	}
+	if c.Api.Dl.QuarantineDir == "" {
+		return fmt.Errorf("api.dl.quarantineDir is required")
+	}
+	if c.Api.Dl.Policy.MaxSizeMb < 0 {
+		return fmt.Errorf("api.dl.policy.maxSizeMb must be >= 0")
+	}
+	if c.Api.Dl.Policy.MaxSizeMb > 1048576 {
+		return fmt.Errorf("api.dl.policy.maxSizeMb must be <= 1048576")
+	}
 	if c.Api.Dl.Client.DownloadUrl == "" {
 		return fmt.Errorf("api.dl.client.downloadUrl is required")
 	}
@@ -73,8 +144,65 @@ func (c Config) Validate() error {
 	if c.Api.Dl.Client.ApiKey == "" {
 		return fmt.Errorf("api.dl.client.apiKey is required")
 	}
+	if c.Api.Gen.QueueSize == 0 {
+		return fmt.Errorf("api.gen.queueSize is required")
+	}
+	if c.Api.Gen.QueueSize < 1 {
+		return fmt.Errorf("api.gen.queueSize must be >= 1")
+	}
+	if c.Api.Gen.QueueSize > 100 {
+		return fmt.Errorf("api.gen.queueSize must be <= 100")
+	}
+	if c.Api.Gen.MaxConcurrent == 0 {
+		return fmt.Errorf("api.gen.maxConcurrent is required")
+	}
+	if c.Api.Gen.MaxConcurrent < 1 {
+		return fmt.Errorf("api.gen.maxConcurrent must be >= 1")
+	}
+	if c.Api.Gen.MaxConcurrent > 10 {
+		return fmt.Errorf("api.gen.maxConcurrent must be <= 10")
+	}
+	if c.Api.History.DbPath == "" {
+		return fmt.Errorf("api.history.dbPath is required")
+	}
+	if c.Api.History.OutputsDir == "" {
+		return fmt.Errorf("api.history.outputsDir is required")
+	}
+	if c.Api.History.ThumbsDir == "" {
+		return fmt.Errorf("api.history.thumbsDir is required")
+	}
+	if c.Api.Library.ModelsDir == "" {
+		return fmt.Errorf("api.library.modelsDir is required")
+	}
+	if c.Api.Library.LorasDir == "" {
+		return fmt.Errorf("api.library.lorasDir is required")
+	}
+	if c.Api.Library.IndexPath == "" {
+		return fmt.Errorf("api.library.indexPath is required")
+	}
+	if c.Api.Library.RescanSeconds < 0 {
+		return fmt.Errorf("api.library.rescanSeconds must be >= 0")
+	}
+	if c.Api.Library.RescanSeconds > 86400 {
+		return fmt.Errorf("api.library.rescanSeconds must be <= 86400")
+	}
+	if c.Api.Library.WatchDebounceMs < 0 {
+		return fmt.Errorf("api.library.watchDebounceMs must be >= 0")
+	}
+	if c.Api.Library.WatchDebounceMs > 60000 {
+		return fmt.Errorf("api.library.watchDebounceMs must be <= 60000")
+	}
 	if c.Rpc.Port == "" {
 		return fmt.Errorf("rpc.port is required")
 	}
+	if c.Rpc.HealthCheckSeconds == 0 {
+		return fmt.Errorf("rpc.healthCheckSeconds is required")
+	}
+	if c.Rpc.HealthCheckSeconds < 1 {
+		return fmt.Errorf("rpc.healthCheckSeconds must be >= 1")
+	}
+	if c.Rpc.HealthCheckSeconds > 300 {
+		return fmt.Errorf("rpc.healthCheckSeconds must be <= 300")
+	}
 	return nil
 }
@@ -18,6 +18,9 @@ import (
 	"github.com/charmbracelet/log"
 )
 
+// ErrNotFound is returned when the model host has nothing for a lookup.
+var ErrNotFound = errors.New("not found on model host")
+
 type Hf struct {
 	api_key    string
 	httpClient *http.Client
@@ -25,6 +28,9 @@ type Hf struct {
 
 	downloadUrl  string
 	modelInfoUrl string
+	modelUrl     string
+	searchUrl    string
+	byHashUrl    string
 	logger       *log.Logger
 }
 
@@ -33,7 +39,10 @@ func NewHfClient(ctx context.Context, config config.ApiDlClientConfig) *Hf {
 	return &Hf{
 		api_key:      config.ApiKey,
 		modelInfoUrl: config.ModeInfoUrl,
+		modelUrl:     config.ModelUrl,
+		searchUrl:    config.SearchUrl,
 		downloadUrl:  config.DownloadUrl,
+		byHashUrl:    config.ByHashUrl,
 		ctx:          ctx,
 		logger:       log.With("component"),
 		httpClient: &http.Client{
@@ -75,6 +84,13 @@ func urlWithID(template, id string) string {
 	return template + "/" + id
 }
 
+// CanGetModelInfo reports whether a model URL template is configured.
+func (hf *Hf) CanGetModelInfo() bool {
+	return strings.TrimSpace(hf.modelUrl) != ""
+}
+
+// GetModelInfo returns the model with the given ID and its versions, newest
+// first. It returns ErrNotFound when the host has no such model.
 func (hf *Hf) GetModelInfo(id string) (ModelIdResponse, error) {
 
 	headers := make(map[string]string)
@@ -82,9 +98,16 @@ func (hf *Hf) GetModelInfo(id string) (ModelIdResponse, error) {
 	headers["Authorization"] = "Bearer " + hf.api_key
 	headers["Content-Type"] = "application/json"
 
-	endpoint := urlWithID(hf.modelInfoUrl, id)
+	endpoint := urlWithID(hf.modelUrl, id)
+	if endpoint == "" {
+		return ModelIdResponse{}, errors.New("missing model url template")
+	}
 	hf.logger.Debug("get model info", "id", id, "url", endpoint)
 	resp, err := transport.Get[ModelIdResponse](*hf.httpClient, hf.ctx, endpoint, headers)
+	var se *transport.StatusError
+	if errors.As(err, &se) && se.Code == http.StatusNotFound {
+		return ModelIdResponse{}, fmt.Errorf("%w: model %s", ErrNotFound, id)
+	}
 	if err != nil {
 		hf.logger.Error("get model info failed", "id", id, "err", err)
 		return ModelIdResponse{}, err
@@ -93,6 +116,8 @@ func (hf *Hf) GetModelInfo(id string) (ModelIdResponse, error) {
 	return resp, nil
 }
 
+// GetModelVersionInfo returns the model version with the given ID. It
+// returns ErrNotFound when the host has no such version.
 func (hf *Hf) GetModelVersionInfo(id string) (ModelVersionIdResponse, error) {
 
 	headers := make(map[string]string)
@@ -103,6 +128,10 @@ func (hf *Hf) GetModelVersionInfo(id string) (ModelVersionIdResponse, error) {
 	endpoint := urlWithID(hf.modelInfoUrl, id)
 	hf.logger.Debug("get model version info", "id", id, "url", endpoint)
 	resp, err := transport.Get[ModelVersionIdResponse](*hf.httpClient, hf.ctx, endpoint, headers)
+	var se *transport.StatusError
+	if errors.As(err, &se) && se.Code == http.StatusNotFound {
+		return ModelVersionIdResponse{}, fmt.Errorf("%w: model version %s", ErrNotFound, id)
+	}
 	if err != nil {
 		hf.logger.Error("get model version info failed", "id", id, "err", err)
 		return ModelVersionIdResponse{}, err
@@ -111,24 +140,90 @@ func (hf *Hf) GetModelVersionInfo(id string) (ModelVersionIdResponse, error) {
 	return resp, nil
 }
 
-func (hf *Hf) DownloadModelIntoFolder(modelVersionID, filePath string) error {
+// Download is a file DownloadModelIntoFolder put in place.
+type Download struct {
+	Path     string
+	SHA256   string // empty when the file was already there
+	Verified bool   // matched a hash the model host published
+}
+
+// CanLookupByHash reports whether a by-hash URL template is configured.
+func (hf *Hf) CanLookupByHash() bool {
+	return strings.TrimSpace(hf.byHashUrl) != ""
+}
+
+// GetModelVersionByHash finds the model version a file belongs to from its
+// SHA256 or AutoV2 hash. It returns ErrNotFound when the host doesn't know
+// the hash.
+func (hf *Hf) GetModelVersionByHash(hash string) (ModelVersionIdResponse, error) {
 
 	headers := make(map[string]string)
 
 	headers["Authorization"] = "Bearer " + hf.api_key
+	headers["Content-Type"] = "application/json"
+
+	endpoint := urlWithID(hf.byHashUrl, hash)
+	if endpoint == "" {
+		return ModelVersionIdResponse{}, errors.New("missing by-hash url template")
+	}
+	hf.logger.Debug("get model version by hash", "hash", hash, "url", endpoint)
+	resp, err := transport.Get[ModelVersionIdResponse](*hf.httpClient, hf.ctx, endpoint, headers)
+	var se *transport.StatusError
+	if errors.As(err, &se) && se.Code == http.StatusNotFound {
+		return ModelVersionIdResponse{}, fmt.Errorf("%w: %s", ErrNotFound, hash)
+	}
+	if err != nil {
+		hf.logger.Error("get model version by hash failed", "hash", hash, "err", err)
+		return ModelVersionIdResponse{}, err
+	}
 
+	return resp, nil
+}
+
+// DownloadModelIntoFolder downloads the model version into the folder at
+// filePath; the file name comes from the server. The content is hashed as it
+// streams and checked against want before the file is moved into place. On a
+// mismatch a *HashMismatchError is returned and the .part file is left for
+// the caller. A non-nil progress is told where the file goes and how the
+// transfer is going.
+func (hf *Hf) DownloadModelIntoFolder(modelVersionID, filePath string, want ModelVersionFileHashes, progress ProgressReporter) (Download, error) {
 	modelVersionID = strings.TrimSpace(modelVersionID)
 	if modelVersionID == "" {
-		return errors.New("missing model version id")
+		return Download{}, errors.New("missing model version id")
 	}
 	if strings.TrimSpace(hf.downloadUrl) == "" {
-		return errors.New("missing download url template")
+		return Download{}, errors.New("missing download url template")
 	}
 
 	downloadURL := urlWithID(hf.downloadUrl, modelVersionID)
 	if downloadURL == "" {
-		return errors.New("failed to build download url")
+		return Download{}, errors.New("failed to build download url")
+	}
+	return hf.download(downloadURL, modelVersionID, filePath, "", want, progress)
+}
+
+// DownloadFileIntoFolder downloads one file of a model version through its
+// own download URL, falling back to the version's when the host didn't send
+// one. Verification and progress work as in DownloadModelIntoFolder.
+func (hf *Hf) DownloadFileIntoFolder(modelVersionID string, file ModelVersionFile, filePath string, progress ProgressReporter) (Download, error) {
+	modelVersionID = strings.TrimSpace(modelVersionID)
+	if modelVersionID == "" {
+		return Download{}, errors.New("missing model version id")
+	}
+	downloadURL := strings.TrimSpace(file.DownloadUrl)
+	if downloadURL == "" {
+		return hf.DownloadModelIntoFolder(modelVersionID, filePath, file.Hashes, progress)
 	}
+	return hf.download(downloadURL, modelVersionID, filePath, file.Name, file.Hashes, progress)
+}
+
+// download fetches downloadURL into the folder at filePath, named after the
+// server's Content-Disposition, else name.
+func (hf *Hf) download(downloadURL, modelVersionID, filePath, name string, want ModelVersionFileHashes, progress ProgressReporter) (Download, error) {
+
+	headers := make(map[string]string)
+
+	headers["Authorization"] = "Bearer " + hf.api_key
 
 	downloadHost := ""
 	downloadPath := ""
@@ -141,11 +236,14 @@ func (hf *Hf) DownloadModelIntoFolder(modelVersionID, filePath string) error {
 	resp, err := transport.Download(*hf.httpClient, hf.ctx, downloadURL, headers)
 	if err != nil {
 		hf.logger.Error("download request failed", "host", downloadHost, "path", downloadPath, "dest", filePath, "err", err)
-		return err
+		return Download{}, err
 	}
 	defer resp.Body.Close()
 
 	filename := "model.safetensors"
+	if strings.TrimSpace(name) != "" {
+		filename = name
+	}
 	if cd := resp.Header.Get("Content-Disposition"); cd != "" {
 		if fn := utils.FileNameFromCd(cd); fn != "" {
 			filename = fn
@@ -159,36 +257,49 @@ func (hf *Hf) DownloadModelIntoFolder(modelVersionID, filePath string) error {
 
 	if fi, err := os.Stat(finalPath); err == nil && fi != nil && !fi.IsDir() && fi.Size() > 0 {
 		hf.logger.Info("download skipped; file exists", "file", finalPath)
-		return nil
+		return Download{Path: finalPath}, nil
 	}
 
 	out, err := os.Create(tmpPath)
 	if err != nil {
 		hf.logger.Error("download create temp file failed", "tmp", tmpPath, "err", err)
-		return err
+		return Download{}, err
 	}
 
-	_, copyErr := io.Copy(out, resp.Body)
+	var body io.Reader = resp.Body
+	if progress != nil {
+		progress.DownloadStarted(filename, finalPath, max(resp.ContentLength, 0))
+		body = newProgressReader(resp.Body, progress, resp.ContentLength)
+	}
+
+	v := newVerifier(want)
+	_, copyErr := io.Copy(v.writer(out), body)
 	closeErr := out.Close()
 
 	if copyErr != nil {
 		_ = os.Remove(tmpPath)
 		hf.logger.Error("download write failed", "tmp", tmpPath, "err", copyErr)
-		return copyErr
+		return Download{}, copyErr
 	}
 	if closeErr != nil {
 		_ = os.Remove(tmpPath)
 		hf.logger.Error("download close failed", "tmp", tmpPath, "err", closeErr)
-		return closeErr
+		return Download{}, closeErr
+	}
+
+	verified, err := v.verify(tmpPath)
+	if err != nil {
+		hf.logger.Error("download hash mismatch", "tmp", tmpPath, "err", err)
+		return Download{}, err
 	}
 
 	if err := os.Rename(tmpPath, finalPath); err != nil {
 		_ = os.Remove(tmpPath)
 		hf.logger.Error("download rename failed", "tmp", tmpPath, "final", finalPath, "err", err)
-		return err
+		return Download{}, err
 	}
-	hf.logger.Info("download complete", "file", finalPath)
-	return nil
+	hf.logger.Info("download complete", "file", finalPath, "verified", verified)
+	return Download{Path: finalPath, SHA256: v.SHA256(), Verified: verified}, nil
 }
 
 func SanitizeDownloadedFilename(filename, modelVersionID string) string {
@@ -0,0 +1,156 @@
+package huggingface
+
+import (
+	"be/config"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/zeebo/blake3"
+)
+
+func TestDownloadVerifiesHash(t *testing.T) {
+	const body = "model weights"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", `attachment; filename="ink.safetensors"`)
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+
+	hf := NewHfClient(context.Background(), config.ApiDlClientConfig{DownloadUrl: srv.URL + "/{id}"})
+	sum := sha256.Sum256([]byte(body))
+	good := hex.EncodeToString(sum[:])
+	crc := "C2AF5A5B"
+	str := func(s string) *string { return &s }
+
+	t.Run("match", func(t *testing.T) {
+		dir := t.TempDir()
+		dl, err := hf.DownloadModelIntoFolder("7", dir, ModelVersionFileHashes{SHA256: str(good)}, nil)
+		if err != nil {
+			t.Fatalf("download: %v", err)
+		}
+		if dl.Path != filepath.Join(dir, "7-ink.safetensors") || !dl.Verified || dl.SHA256 != good {
+			t.Fatalf("download = %+v", dl)
+		}
+	})
+
+	t.Run("blake3 only", func(t *testing.T) {
+		b3 := blake3.Sum256([]byte(body))
+		dl, err := hf.DownloadModelIntoFolder("7", t.TempDir(), ModelVersionFileHashes{BLAKE3: str(hex.EncodeToString(b3[:]))}, nil)
+		if err != nil || !dl.Verified {
+			t.Fatalf("download = %+v, %v; want it verified by blake3", dl, err)
+		}
+	})
+
+	t.Run("no published hash", func(t *testing.T) {
+		dl, err := hf.DownloadModelIntoFolder("7", t.TempDir(), ModelVersionFileHashes{}, nil)
+		if err != nil || dl.Verified || dl.SHA256 != good {
+			t.Fatalf("download = %+v, %v; want it unverified with its sha256", dl, err)
+		}
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := hf.DownloadModelIntoFolder("7", dir, ModelVersionFileHashes{CRC32: str(crc)}, nil)
+		var mismatch *HashMismatchError
+		if !errors.As(err, &mismatch) || mismatch.Algorithm != "crc32" {
+			t.Fatalf("err = %v, want a crc32 mismatch", err)
+		}
+		if _, err := os.Stat(mismatch.Path); err != nil {
+			t.Fatalf("rejected file not left for quarantine: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "7-ink.safetensors")); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("rejected file moved into place: %v", err)
+		}
+	})
+}
+
+func TestDownloadFileIntoFolder(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.RequestURI())
+		io.WriteString(w, "vae weights")
+	}))
+	defer srv.Close()
+
+	hf := NewHfClient(context.Background(), config.ApiDlClientConfig{DownloadUrl: srv.URL + "/versions/{id}"})
+	dir := t.TempDir()
+
+	// The file's own URL is used, and its name when the server sends none.
+	dl, err := hf.DownloadFileIntoFolder("7", ModelVersionFile{Name: "ink vae.safetensors", DownloadUrl: srv.URL + "/files/4?type=VAE"}, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dl.Path != filepath.Join(dir, "7-ink-vae.safetensors") {
+		t.Fatalf("path = %s", dl.Path)
+	}
+
+	// Without one it falls back to the version's download URL.
+	if _, err := hf.DownloadFileIntoFolder("7", ModelVersionFile{}, t.TempDir(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 || paths[0] != "/files/4?type=VAE" || paths[1] != "/versions/7" {
+		t.Fatalf("requests = %v", paths)
+	}
+}
+
+type progressRecorder struct {
+	filename, dest string
+	total          int64
+	reports        []Progress
+}
+
+func (r *progressRecorder) DownloadStarted(filename, dest string, total int64) {
+	r.filename, r.dest, r.total = filename, dest, total
+}
+
+func (r *progressRecorder) DownloadProgress(p Progress) {
+	r.reports = append(r.reports, p)
+}
+
+func TestDownloadReportsProgress(t *testing.T) {
+	body := strings.Repeat("w", 64*1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", `attachment; filename="ink.safetensors"`)
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		// Sent in quarters so the reader sees several chunks.
+		for i := 0; i < 4; i++ {
+			io.WriteString(w, body[i*len(body)/4:(i+1)*len(body)/4])
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer srv.Close()
+
+	hf := NewHfClient(context.Background(), config.ApiDlClientConfig{DownloadUrl: srv.URL + "/{id}"})
+	dir := t.TempDir()
+	rec := &progressRecorder{}
+	if _, err := hf.DownloadModelIntoFolder("7", dir, ModelVersionFileHashes{}, rec); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.filename != "7-ink.safetensors" || rec.dest != filepath.Join(dir, "7-ink.safetensors") || rec.total != int64(len(body)) {
+		t.Fatalf("started = %q %q %d", rec.filename, rec.dest, rec.total)
+	}
+	if len(rec.reports) == 0 {
+		t.Fatal("no progress reported")
+	}
+	var prev int64
+	for _, p := range rec.reports {
+		if p.Received <= prev || p.Total != int64(len(body)) {
+			t.Fatalf("reports = %+v", rec.reports)
+		}
+		prev = p.Received
+	}
+	if last := rec.reports[len(rec.reports)-1]; last.Received != int64(len(body)) || last.ETA != 0 || last.AvgRate <= 0 {
+		t.Fatalf("last report = %+v", last)
+	}
+}
@@ -0,0 +1,80 @@
+package huggingface
+
+import (
+	"io"
+	"time"
+)
+
+// Progress reports are sent at most every progressInterval, or sooner when
+// another progressStep of the file has arrived.
+const (
+	progressInterval = 500 * time.Millisecond
+	progressStep     = 0.01
+)
+
+// Progress is a snapshot of a running download.
+type Progress struct {
+	Received int64
+	Total    int64   // from Content-Length; 0 when the server didn't send it
+	Rate     float64 // bytes/s since the previous report
+	AvgRate  float64 // bytes/s since the download started
+	// ETA is the time left at the average rate; 0 when Total is unknown.
+	ETA time.Duration
+}
+
+// ProgressReporter is told when a download starts streaming and how far it
+// got. Both methods are called from the downloading goroutine and should
+// return quickly.
+type ProgressReporter interface {
+	DownloadStarted(filename, dest string, total int64)
+	DownloadProgress(p Progress)
+}
+
+// progressReader counts what is read through it and reports it, throttled.
+// The last report always covers the whole body.
+type progressReader struct {
+	r      io.Reader
+	report ProgressReporter
+	total  int64
+
+	received int64
+	start    time.Time
+	last     time.Time // of the previous report
+	lastN    int64     // received at the previous report
+}
+
+func newProgressReader(r io.Reader, report ProgressReporter, total int64) *progressReader {
+	if total < 0 {
+		total = 0
+	}
+	now := time.Now()
+	return &progressReader{r: r, report: report, total: total, start: now, last: now}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.received += int64(n)
+
+	now := time.Now()
+	due := now.Sub(p.last) >= progressInterval ||
+		(p.total > 0 && float64(p.received-p.lastN) >= progressStep*float64(p.total))
+	if (due || err == io.EOF) && p.received != p.lastN {
+		p.send(now)
+	}
+	return n, err
+}
+
+func (p *progressReader) send(now time.Time) {
+	pr := Progress{Received: p.received, Total: p.total}
+	if d := now.Sub(p.last).Seconds(); d > 0 {
+		pr.Rate = float64(p.received-p.lastN) / d
+	}
+	if d := now.Sub(p.start).Seconds(); d > 0 {
+		pr.AvgRate = float64(p.received) / d
+	}
+	if p.total > p.received && pr.AvgRate > 0 {
+		pr.ETA = time.Duration(float64(p.total-p.received) / pr.AvgRate * float64(time.Second))
+	}
+	p.last, p.lastN = now, p.received
+	p.report.DownloadProgress(pr)
+}
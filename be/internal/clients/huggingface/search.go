@@ -0,0 +1,204 @@
+package huggingface
+
+import (
+	"be/internal/clients/transport"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Model types the catalog search accepts.
+const (
+	TypeCheckpoint = "Checkpoint"
+	TypeLora       = "LORA"
+)
+
+// Search sort orders, as the model host spells them.
+var SearchSorts = map[string]string{
+	"rating":    "Highest Rated",
+	"downloads": "Most Downloaded",
+	"newest":    "Newest",
+}
+
+// MaxSearchLimit is the largest page the model host serves.
+const MaxSearchLimit = 100
+
+// Cursor is an opaque pagination cursor the host sends as a string or a
+// number.
+type Cursor string
+
+func (c *Cursor) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		*c = ""
+		return nil
+	}
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*c = Cursor(s)
+		return nil
+	}
+	*c = Cursor(b)
+	return nil
+}
+
+// SearchParams narrows a catalog search. Zero values are left out.
+type SearchParams struct {
+	Query     string
+	Type      string // TypeCheckpoint or TypeLora
+	BaseModel string
+	Sort      string // key of SearchSorts
+	NsfwLevel int    // browsing level bitmask: 1 PG, 2 PG-13, 4 R, 8 X, 16 XXX
+	Cursor    string
+	Limit     int
+}
+
+// CanSearch reports whether a search URL is configured.
+func (hf *Hf) CanSearch() bool {
+	return strings.TrimSpace(hf.searchUrl) != ""
+}
+
+// SearchModels runs the model host's model search and normalizes the page.
+func (hf *Hf) SearchModels(p SearchParams) (CatalogSearch, error) {
+
+	headers := make(map[string]string)
+
+	headers["Authorization"] = "Bearer " + hf.api_key
+	headers["Content-Type"] = "application/json"
+
+	endpoint, err := searchURL(hf.searchUrl, p)
+	if err != nil {
+		return CatalogSearch{}, err
+	}
+	hf.logger.Debug("search models", "url", endpoint)
+	resp, err := transport.Get[ModelSearchResponse](*hf.httpClient, hf.ctx, endpoint, headers)
+	if err != nil {
+		hf.logger.Error("search models failed", "err", err)
+		return CatalogSearch{}, err
+	}
+
+	return normalizeSearch(resp), nil
+}
+
+func searchURL(base string, p SearchParams) (string, error) {
+	base = strings.TrimSpace(base)
+	if base == "" {
+		return "", errors.New("missing search url")
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	set := func(key, value string) {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	set("query", p.Query)
+	set("types", p.Type)
+	set("baseModels", p.BaseModel)
+	set("sort", SearchSorts[p.Sort])
+	set("cursor", p.Cursor)
+	if p.NsfwLevel > 0 {
+		q.Set("browsingLevel", strconv.Itoa(p.NsfwLevel))
+		q.Set("nsfw", strconv.FormatBool(p.NsfwLevel > 1))
+	}
+	if p.Limit > 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func normalizeSearch(resp ModelSearchResponse) CatalogSearch {
+	out := CatalogSearch{
+		Items:      make([]CatalogModel, 0, len(resp.Items)),
+		NextCursor: string(resp.Metadata.NextCursor),
+	}
+	if out.NextCursor == "" && resp.Metadata.NextPage != "" {
+		// Some hosts only send the next page URL; its cursor is all we need.
+		if u, err := url.Parse(resp.Metadata.NextPage); err == nil {
+			out.NextCursor = u.Query().Get("cursor")
+		}
+	}
+	for _, m := range resp.Items {
+		model := CatalogModel{
+			ID:        m.Id,
+			Name:      m.Name,
+			Type:      m.Type,
+			Nsfw:      m.Nsfw,
+			Tags:      m.Tags,
+			Downloads: m.Stats.DownloadCount,
+			Versions:  make([]CatalogVersion, 0, len(m.ModelVersions)),
+		}
+		if m.NsfwLevel != nil {
+			model.NsfwLevel = *m.NsfwLevel
+		}
+		if m.Creator != nil {
+			model.Creator = m.Creator.Username
+		}
+		for _, v := range m.ModelVersions {
+			version := CatalogVersion{
+				ID:           v.Id,
+				Name:         v.Name,
+				BaseModel:    v.BaseModel,
+				TrainedWords: v.TrainedWords,
+			}
+			for _, img := range v.Images {
+				if img.Type == nil || *img.Type == "image" {
+					version.PreviewUrl = img.Url
+					break
+				}
+			}
+			for _, f := range v.Files {
+				if f.Primary && f.SizeKB != nil {
+					version.SizeKB = *f.SizeKB
+				}
+			}
+			model.Versions = append(model.Versions, version)
+		}
+		out.Items = append(out.Items, model)
+	}
+	return out
+}
+
+// CatalogFiles normalizes the files of a model version.
+func CatalogFiles(info ModelVersionIdResponse) CatalogVersionFiles {
+	out := CatalogVersionFiles{
+		ModelVersionID: info.Id,
+		Name:           info.Name,
+		BaseModel:      info.BaseModel,
+		Files:          make([]CatalogFile, 0, len(info.Files)),
+	}
+	str := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	for _, f := range info.Files {
+		file := CatalogFile{
+			ID:         f.Id,
+			Name:       f.Name,
+			Type:       f.Type,
+			Format:     str(f.Metadata.Format),
+			Fp:         str(f.Metadata.Fp),
+			Size:       str(f.Metadata.Size),
+			Primary:    f.Primary,
+			PickleScan: str(f.PickleScanResult),
+			VirusScan:  str(f.VirusScanResult),
+		}
+		if f.SizeKB != nil {
+			file.SizeKB = *f.SizeKB
+		}
+		out.Files = append(out.Files, file)
+	}
+	return out
+}
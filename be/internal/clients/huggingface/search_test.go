@@ -0,0 +1,48 @@
+package huggingface
+
+import (
+	"be/config"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSearchModels(t *testing.T) {
+	var query map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = map[string]string{}
+		for k := range r.URL.Query() {
+			query[k] = r.URL.Query().Get(k)
+		}
+		io.WriteString(w, `{"items":[{"id":5,"name":"Ink","type":"LORA","nsfw":false,"creator":{"username":"ana"},
+			"stats":{"downloadCount":42},"modelVersions":[{"id":13,"name":"v3","baseModel":"SDXL 1.0",
+			"files":[{"name":"ink.safetensors","primary":true,"sizeKB":1024}],
+			"images":[{"url":"https://img/v.mp4","type":"video"},{"url":"https://img/1.jpeg","type":"image"}]}]}],
+			"metadata":{"nextCursor":1234}}`)
+	}))
+	defer srv.Close()
+
+	hf := NewHfClient(context.Background(), config.ApiDlClientConfig{SearchUrl: srv.URL + "/models?token=x"})
+	res, err := hf.SearchModels(SearchParams{Query: "ink", Type: TypeLora, BaseModel: "SDXL 1.0", Sort: "newest", NsfwLevel: 1, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"token": "x", "query": "ink", "types": "LORA", "baseModels": "SDXL 1.0", "sort": "Newest", "browsingLevel": "1", "nsfw": "false", "limit": "10"}
+	for k, v := range want {
+		if query[k] != v {
+			t.Errorf("query %s = %q, want %q", k, query[k], v)
+		}
+	}
+	if res.NextCursor != "1234" || len(res.Items) != 1 {
+		t.Fatalf("result = %+v", res)
+	}
+	m := res.Items[0]
+	if m.Creator != "ana" || m.Downloads != 42 || len(m.Versions) != 1 {
+		t.Fatalf("model = %+v", m)
+	}
+	if v := m.Versions[0]; v.ID != 13 || v.SizeKB != 1024 || v.PreviewUrl != "https://img/1.jpeg" || v.Local {
+		t.Fatalf("version = %+v", v)
+	}
+}
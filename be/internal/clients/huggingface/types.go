@@ -3,22 +3,98 @@ package huggingface
 import "encoding/json"
 
 type ModelIdResponse struct {
-	Id   int64  `json:"id"`
-	Name string `json:"name"`
-	Type string `json:"type"`
+	Id        int64             `json:"id"`
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	Nsfw      bool              `json:"nsfw"`
+	NsfwLevel *int              `json:"nsfwLevel,omitempty"`
+	Tags      []string          `json:"tags,omitempty"`
+	Creator   *ModelCreator     `json:"creator,omitempty"`
+	Stats     ModelVersionStats `json:"stats"`
 
 	ModelVersions []ModelVersionSummary `json:"modelVersions"`
 }
 
+type ModelCreator struct {
+	Username string `json:"username"`
+}
+
+// ModelSearchResponse is a page of the model host's model search.
+type ModelSearchResponse struct {
+	Items    []ModelIdResponse   `json:"items"`
+	Metadata ModelSearchMetadata `json:"metadata"`
+}
+
+type ModelSearchMetadata struct {
+	NextCursor Cursor `json:"nextCursor"`
+	NextPage   string `json:"nextPage"`
+}
+
+// CatalogSearch is the normalized search result the API serves.
+type CatalogSearch struct {
+	Items      []CatalogModel `json:"items"`
+	NextCursor string         `json:"nextCursor,omitempty"` // empty on the last page
+}
+
+type CatalogModel struct {
+	ID        int64            `json:"id"`
+	Name      string           `json:"name"`
+	Type      string           `json:"type"` // Checkpoint or LORA
+	Creator   string           `json:"creator,omitempty"`
+	Nsfw      bool             `json:"nsfw"`
+	NsfwLevel int              `json:"nsfwLevel,omitempty"`
+	Tags      []string         `json:"tags,omitempty"`
+	Downloads int64            `json:"downloads"`
+	Versions  []CatalogVersion `json:"versions"` // newest first
+}
+
+type CatalogVersion struct {
+	ID           int64    `json:"id"`
+	Name         string   `json:"name"`
+	BaseModel    string   `json:"baseModel"`
+	TrainedWords []string `json:"trainedWords,omitempty"`
+	PreviewUrl   string   `json:"previewUrl,omitempty"`
+	SizeKB       float64  `json:"sizeKB,omitempty"` // primary file
+	// Local is set, with the file's path, when the version is already in
+	// the library.
+	Local     bool   `json:"local"`
+	LocalPath string `json:"localPath,omitempty"`
+}
+
 type ModelVersionSummary struct {
-	Id            int64              `json:"id"`
-	Index         *int               `json:"index,omitempty"`
-	Name          string             `json:"name"`
-	BaseModel     string             `json:"baseModel"`
-	BaseModelType *string            `json:"baseModelType,omitempty"`
-	DownloadUrl   string             `json:"downloadUrl"`
-	TrainedWords  []string           `json:"trainedWords"`
-	Files         []ModelVersionFile `json:"files,omitempty"`
+	Id            int64               `json:"id"`
+	Index         *int                `json:"index,omitempty"`
+	Name          string              `json:"name"`
+	BaseModel     string              `json:"baseModel"`
+	BaseModelType *string             `json:"baseModelType,omitempty"`
+	DownloadUrl   string              `json:"downloadUrl"`
+	TrainedWords  []string            `json:"trainedWords"`
+	NsfwLevel     *int                `json:"nsfwLevel,omitempty"`
+	Files         []ModelVersionFile  `json:"files,omitempty"`
+	Images        []ModelVersionImage `json:"images,omitempty"`
+}
+
+// CatalogVersionFiles lists the files of a model version to choose from.
+type CatalogVersionFiles struct {
+	ModelVersionID int64         `json:"modelVersionId"`
+	Name           string        `json:"name"`
+	BaseModel      string        `json:"baseModel"`
+	Files          []CatalogFile `json:"files"`
+}
+
+type CatalogFile struct {
+	ID         int64   `json:"id"`
+	Name       string  `json:"name"`
+	Type       string  `json:"type"` // Model, Pruned Model, VAE, Config, ...
+	SizeKB     float64 `json:"sizeKB,omitempty"`
+	Format     string  `json:"format,omitempty"` // SafeTensor, PickleTensor, ...
+	Fp         string  `json:"fp,omitempty"`     // fp16, fp32, bf16
+	Size       string  `json:"size,omitempty"`   // pruned or full
+	Primary    bool    `json:"primary"`
+	PickleScan string  `json:"pickleScan,omitempty"`
+	VirusScan  string  `json:"virusScan,omitempty"`
+	// Rejections lists why the download policy would refuse the file.
+	Rejections []string `json:"rejections,omitempty"`
 }
 
 type ModelVersionIdResponse struct {
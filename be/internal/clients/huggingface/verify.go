@@ -0,0 +1,81 @@
+package huggingface
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+
+	"github.com/zeebo/blake3"
+)
+
+// HashMismatchError reports a download whose content doesn't match the hash
+// the model host published for the file. The rejected file is left at Path
+// for the caller to quarantine.
+type HashMismatchError struct {
+	Algorithm string
+	Want      string
+	Got       string
+	Path      string
+}
+
+func (e *HashMismatchError) Error() string {
+	return fmt.Sprintf("%s mismatch: want %s, got %s", e.Algorithm, strings.ToLower(e.Want), e.Got)
+}
+
+// verifier hashes a download while it streams to disk. SHA256 is always
+// computed so the library doesn't have to read the file again; the expected
+// value is checked with the strongest hash the host published.
+type verifier struct {
+	sha256 hash.Hash
+	check  hash.Hash // nil when there is nothing to compare against, or it is sha256
+	algo   string
+	want   string
+}
+
+func newVerifier(want ModelVersionFileHashes) *verifier {
+	v := &verifier{sha256: sha256.New()}
+	switch {
+	case nonEmpty(want.SHA256):
+		v.algo, v.want = "sha256", *want.SHA256
+	case nonEmpty(want.BLAKE3):
+		v.algo, v.want, v.check = "blake3", *want.BLAKE3, blake3.New()
+	case nonEmpty(want.CRC32):
+		v.algo, v.want, v.check = "crc32", *want.CRC32, crc32.NewIEEE()
+	}
+	return v
+}
+
+func (v *verifier) writer(w io.Writer) io.Writer {
+	if v.check != nil {
+		return io.MultiWriter(w, v.sha256, v.check)
+	}
+	return io.MultiWriter(w, v.sha256)
+}
+
+// verify returns whether the download was checked against a published hash,
+// and a *HashMismatchError when that check failed.
+func (v *verifier) verify(path string) (bool, error) {
+	if v.algo == "" {
+		return false, nil
+	}
+	got := v.SHA256()
+	if v.check != nil {
+		got = hex.EncodeToString(v.check.Sum(nil))
+	}
+	if !strings.EqualFold(got, strings.TrimSpace(v.want)) {
+		return false, &HashMismatchError{Algorithm: v.algo, Want: v.want, Got: got, Path: path}
+	}
+	return true, nil
+}
+
+func (v *verifier) SHA256() string {
+	return hex.EncodeToString(v.sha256.Sum(nil))
+}
+
+func nonEmpty(s *string) bool {
+	return s != nil && strings.TrimSpace(*s) != ""
+}
@@ -9,6 +9,18 @@ import (
 	"strings"
 )
 
+// StatusError is returned by Get for replies outside 2xx.
+type StatusError struct {
+	URL    string
+	Status string
+	Code   int
+	Body   string // at most 8 KiB
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("http %s: %s: %s", e.URL, e.Status, e.Body)
+}
+
 func Get[r any](h http.Client, ctx context.Context, url string, headers map[string]string) (r, error) {
 
 	var response r
@@ -38,7 +50,7 @@ func Get[r any](h http.Client, ctx context.Context, url string, headers map[stri
 		if len(snippet) > 8<<10 {
 			snippet = snippet[:8<<10]
 		}
-		return response, fmt.Errorf("http %s: %s: %s", url, resp.Status, snippet)
+		return response, &StatusError{URL: url, Status: resp.Status, Code: resp.StatusCode, Body: snippet}
 	}
 
 	if err := json.Unmarshal(responseBytes, &response); err != nil {
@@ -0,0 +1,215 @@
+package dependencies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const pingTimeout = 5 * time.Second
+
+var ErrNoHealthyWorkers = errors.New("no healthy workers")
+
+// IsUnavailable reports whether err means the worker can't be reached right
+// now, as opposed to the worker rejecting the call.
+func IsUnavailable(err error) bool {
+	return errors.Is(err, ErrNoHealthyWorkers) || status.Code(err) == codes.Unavailable
+}
+
+// Worker is one python worker in the pool.
+type Worker struct {
+	*Rpc
+	Addr string
+
+	healthy atomic.Bool
+}
+
+// NewWorker wraps a client as a pool worker, starting out healthy.
+func NewWorker(addr string, rpc *Rpc) *Worker {
+	w := &Worker{Rpc: rpc, Addr: addr}
+	w.healthy.Store(true)
+	return w
+}
+
+// Healthy reports whether the worker passed its last health check and its
+// connection hasn't failed since.
+func (w *Worker) Healthy() bool {
+	if !w.healthy.Load() {
+		return false
+	}
+	return w.Rpc == nil || w.Reachable()
+}
+
+// Status describes the connection for /health.
+func (w *Worker) Status() string {
+	if w.Rpc == nil {
+		return "unknown"
+	}
+	return strings.ToLower(w.State().String())
+}
+
+// Pool holds a client per configured worker and health-checks them on an
+// interval, starting right away. Unhealthy workers stay in the pool and are
+// put back into rotation as soon as a check succeeds again, so the pool can
+// be created while every worker is still down.
+type Pool struct {
+	workers  []*Worker
+	interval time.Duration
+	wg       sync.WaitGroup
+	logger   *log.Logger
+}
+
+// ParsePeers splits a comma separated peer list. Entries without a port use
+// the default port. An empty list falls back to the single legacy peer.
+func ParsePeers(peers, fallback, port string) []string {
+	if strings.TrimSpace(peers) == "" {
+		peers = fallback
+	}
+	var addrs []string
+	for _, p := range strings.Split(peers, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(p); err != nil {
+			p = net.JoinHostPort(p, port)
+		}
+		addrs = append(addrs, p)
+	}
+	return addrs
+}
+
+func NewPool(addrs []string, interval time.Duration) (*Pool, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("error creating pool: no workers configured")
+	}
+
+	p := &Pool{
+		interval: interval,
+		logger:   log.With("component", "pool"),
+	}
+	for _, addr := range addrs {
+		rpc, err := NewRpc(addr)
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("error creating pool: %w", err)
+		}
+		p.workers = append(p.workers, NewWorker(addr, rpc))
+	}
+	p.logger.Info("pool ready", "workers", len(p.workers))
+	return p, nil
+}
+
+// Interval is the time between health checks, which is also how long a
+// caller should wait before retrying when no worker is available.
+func (p *Pool) Interval() time.Duration {
+	return p.interval
+}
+
+func (p *Pool) Workers() []*Worker {
+	return p.workers
+}
+
+// Primary returns the first healthy worker, for operations that only need
+// to talk to one of them.
+func (p *Pool) Primary() (*Worker, error) {
+	for _, w := range p.workers {
+		if w.Healthy() {
+			return w, nil
+		}
+	}
+	return nil, ErrNoHealthyWorkers
+}
+
+// Healthy returns the workers currently in rotation.
+func (p *Pool) Healthy() []*Worker {
+	var out []*Worker
+	for _, w := range p.workers {
+		if w.Healthy() {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// Broadcast calls fn on every healthy worker so they all end up in the same
+// state, and returns the first worker's result. It stops at the first
+// failure and names the worker in the error.
+func Broadcast[T any](p *Pool, fn func(*Worker) (T, error)) (T, error) {
+	var first T
+	workers := p.Healthy()
+	if len(workers) == 0 {
+		return first, ErrNoHealthyWorkers
+	}
+	for i, w := range workers {
+		res, err := fn(w)
+		if err != nil {
+			return first, fmt.Errorf("worker %s: %w", w.Addr, err)
+		}
+		if i == 0 {
+			first = res
+		}
+	}
+	return first, nil
+}
+
+func (p *Pool) Run(ctx context.Context) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.checkAll()
+		t := time.NewTicker(p.interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				p.checkAll()
+			}
+		}
+	}()
+}
+
+func (p *Pool) checkAll() {
+	var wg sync.WaitGroup
+	for _, w := range p.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.check(w)
+		}()
+	}
+	wg.Wait()
+}
+
+func (p *Pool) check(w *Worker) {
+	modelPath, err := w.Ping(pingTimeout)
+	if err != nil {
+		if w.healthy.Swap(false) {
+			p.logger.Warn("worker down", "worker", w.Addr, "err", err)
+		}
+		return
+	}
+	if !w.healthy.Swap(true) {
+		p.logger.Info("worker back", "worker", w.Addr, "modelPath", modelPath)
+	}
+}
+
+// Close stops the health checks (the context passed to Run must be
+// cancelled first) and closes every connection.
+func (p *Pool) Close() {
+	p.wg.Wait()
+	for _, w := range p.workers {
+		w.Close()
+	}
+}
@@ -2,12 +2,16 @@ package dependencies
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sync/atomic"
 	"time"
 
 	"be/proto"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/charmbracelet/log"
@@ -18,57 +22,138 @@ type Rpc struct {
 	cancel context.CancelFunc
 	conn   *grpc.ClientConn
 	peer   string
+	state  atomic.Int32 // connectivity.State
 	logger *log.Logger
 }
 
-func NewRpc(peer, port string) (*Rpc, error) {
-	addr := fmt.Sprint(peer, ":", port)
+// NewRpc creates a client for the worker at addr. It does not wait for the
+// worker: the connection is established in the background and re-established
+// whenever it drops, so the API can come up while the worker is still
+// starting or is down.
+func NewRpc(addr string) (*Rpc, error) {
 	logger := log.With("component", "rpc", "peer", addr)
-	logger.Info("rpc connecting")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 240*time.Second)
 
-	conn, err := grpc.DialContext(
-		ctx,
+	conn, err := grpc.NewClient(
 		addr,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
 	)
 	if err != nil {
-		cancel()
-		logger.Error("rpc connect failed", "err", err)
+		logger.Error("rpc client failed", "err", err)
 		return nil, fmt.Errorf("error creating newrpc: %w", err)
 	}
-	logger.Info("rpc connected")
 
-	return &Rpc{
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Rpc{
 		ctx:    &ctx,
 		conn:   conn,
 		cancel: cancel,
 		peer:   addr,
 		logger: logger,
-	}, nil
+	}
+	r.state.Store(int32(conn.GetState()))
+
+	logger.Info("rpc connecting")
+	conn.Connect()
+	go r.watchState(ctx)
+
+	return r, nil
 }
 
-func (r *Rpc) GenerateImage(positivePrompt, negativePrompt string) (*proto.GenerateImageResponse, error) {
+// watchState records connectivity changes and keeps the connection out of
+// idle so a worker coming back is noticed without waiting for a request.
+func (r *Rpc) watchState(ctx context.Context) {
+	state := r.conn.GetState()
+	for {
+		if !r.conn.WaitForStateChange(ctx, state) {
+			return
+		}
+		prev := state
+		state = r.conn.GetState()
+		r.state.Store(int32(state))
+
+		switch state {
+		case connectivity.Ready:
+			r.logger.Info("rpc connected", "from", prev.String())
+		case connectivity.TransientFailure:
+			r.logger.Warn("rpc unreachable", "from", prev.String())
+		case connectivity.Idle:
+			r.logger.Debug("rpc idle, reconnecting")
+			r.conn.Connect()
+		default:
+			r.logger.Debug("rpc state", "from", prev.String(), "to", state.String())
+		}
+	}
+}
+
+// State returns the last observed connectivity state.
+func (r *Rpc) State() connectivity.State {
+	return connectivity.State(r.state.Load())
+}
+
+// Reachable reports whether the connection is usable or may become usable
+// without a failed attempt first: anything but a transient failure or a
+// closed client.
+func (r *Rpc) Reachable() bool {
+	switch r.State() {
+	case connectivity.TransientFailure, connectivity.Shutdown:
+		return false
+	}
+	return true
+}
+
+func (r *Rpc) GenerateImage(req *proto.GenerateImageRequest) (*proto.GenerateImageResponse, error) {
 	start := time.Now()
-	r.logger.Debug("rpc GenerateImage", "positiveLen", len(positivePrompt), "negativeLen", len(negativePrompt))
+	r.logger.Debug("rpc GenerateImage", "positiveLen", len(req.PositivePrompt), "negativeLen", len(req.NegativePrompt), "seed", req.GetSeed(), "steps", req.Steps, "width", req.Width, "height", req.Height, "batch", req.BatchSize)
 	ctx, cancel := context.WithTimeout(context.Background(), 240*time.Second)
 	defer cancel()
 
 	client := proto.NewImageServiceClient(r.conn)
-	resp, err := client.GenerateImage(ctx, &proto.GenerateImageRequest{
-		PositivePrompt: positivePrompt,
-		NegativePrompt: negativePrompt,
-	})
+	resp, err := client.GenerateImage(ctx, req)
 	if err != nil {
 		r.logger.Error("rpc GenerateImage failed", "dur", time.Since(start).String(), "err", err)
 		return nil, err
 	}
-	r.logger.Info("rpc GenerateImage ok", "dur", time.Since(start).String(), "bytes", len(resp.Image))
+	r.logger.Info("rpc GenerateImage ok", "dur", time.Since(start).String(), "bytes", len(resp.Image), "images", len(resp.Images), "seed", resp.Seed)
 	return resp, nil
 }
 
+// GenerateImageStream runs a streaming generation, calling onProgress for each
+// progress event, and returns the final result.
+func (r *Rpc) GenerateImageStream(req *proto.GenerateImageRequest, onProgress func(*proto.GenerationProgress)) (*proto.GenerateImageResponse, error) {
+	start := time.Now()
+	r.logger.Debug("rpc GenerateImageStream", "positiveLen", len(req.PositivePrompt), "negativeLen", len(req.NegativePrompt), "seed", req.GetSeed(), "steps", req.Steps, "previewInterval", req.PreviewInterval)
+	ctx, cancel := context.WithTimeout(context.Background(), 240*time.Second)
+	defer cancel()
+
+	client := proto.NewImageServiceClient(r.conn)
+	stream, err := client.GenerateImageStream(ctx, req)
+	if err != nil {
+		r.logger.Error("rpc GenerateImageStream failed", "dur", time.Since(start).String(), "err", err)
+		return nil, err
+	}
+
+	for {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			err = errors.New("stream ended without a result")
+		}
+		if err != nil {
+			r.logger.Error("rpc GenerateImageStream failed", "dur", time.Since(start).String(), "err", err)
+			return nil, err
+		}
+
+		switch ev := event.Event.(type) {
+		case *proto.GenerateImageEvent_Progress:
+			if onProgress != nil {
+				onProgress(ev.Progress)
+			}
+		case *proto.GenerateImageEvent_Result:
+			r.logger.Info("rpc GenerateImageStream ok", "dur", time.Since(start).String(), "bytes", len(ev.Result.Image), "images", len(ev.Result.Images), "seed", ev.Result.Seed)
+			return ev.Result, nil
+		}
+	}
+}
+
 func (r *Rpc) SetModel(modelPath string) (*proto.SetModelResponse, error) {
 	start := time.Now()
 	r.logger.Info("rpc SetModel", "modelPath", modelPath)
@@ -119,6 +204,21 @@ func (r *Rpc) GetCurrentLoras() (*proto.GetCurrentLorasResponse, error) {
 	return resp, nil
 }
 
+// Ping is a cheap liveness probe used by the pool's health checks. It
+// returns the loaded model path and only logs at debug level.
+func (r *Rpc) Ping(timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	client := proto.NewImageServiceClient(r.conn)
+	resp, err := client.GetCurrentModel(ctx, &proto.GetCurrentModelRequest{})
+	if err != nil {
+		r.logger.Debug("rpc Ping failed", "err", err)
+		return "", err
+	}
+	return resp.ModelPath, nil
+}
+
 func (r *Rpc) ClearModel() (*proto.ClearModelResponse, error) {
 	start := time.Now()
 	r.logger.Info("rpc ClearModel")
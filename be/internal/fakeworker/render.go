@@ -0,0 +1,103 @@
+package fakeworker
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+
+	"be/proto"
+)
+
+// previewScale matches the python worker, whose previews are decoded
+// straight from the 1/8 resolution latents.
+const previewScale = 8
+
+type params struct {
+	prompt string
+	seed   int64
+	steps  int32
+	width  int32
+	height int32
+	batch  int32
+}
+
+func (p params) response() (*proto.GenerateImageResponse, error) {
+	images := make([][]byte, 0, p.batch)
+	for i := int32(0); i < p.batch; i++ {
+		img := Render(p.prompt, p.seed+int64(i), int(p.width), int(p.height))
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, fmt.Errorf("encode png: %w", err)
+		}
+		images = append(images, buf.Bytes())
+	}
+
+	return &proto.GenerateImageResponse{
+		Image:        images[0],
+		MimeType:     "image/png",
+		FilenameHint: fmt.Sprintf("sdxl-%d.png", p.seed),
+		Seed:         p.seed,
+		Images:       images,
+	}, nil
+}
+
+// preview renders the first image of the batch at latent resolution, faded
+// towards grey by how much of the sampling is left.
+func (p params) preview(step int32) ([]byte, error) {
+	img := Render(p.prompt, p.seed, max(1, int(p.width)/previewScale), max(1, int(p.height)/previewScale))
+	done := float64(step) / float64(p.steps)
+	for i := 0; i < len(img.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			img.Pix[i+c] = uint8(float64(img.Pix[i+c])*done + 127*(1-done))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 70}); err != nil {
+		return nil, fmt.Errorf("encode jpeg: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Render draws a diagonal gradient between two colours picked from the
+// prompt and seed, crossed by bands whose spacing also depends on them. The
+// same inputs always give the same pixels and a different prompt or seed
+// gives a visibly different image.
+func Render(prompt string, seed int64, width, height int) *image.NRGBA {
+	h := fnv.New64a()
+	h.Write([]byte(prompt))
+	binary.Write(h, binary.LittleEndian, seed)
+	sum := h.Sum64()
+
+	from := color.NRGBA{R: uint8(sum), G: uint8(sum >> 8), B: uint8(sum >> 16), A: 255}
+	to := color.NRGBA{R: uint8(sum >> 24), G: uint8(sum >> 32), B: uint8(sum >> 40), A: 255}
+	band := 16 + int(sum>>48)%48
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	span := max(1, width+height-2)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			t := float64(x+y) / float64(span)
+			c := color.NRGBA{
+				R: lerp(from.R, to.R, t),
+				G: lerp(from.G, to.G, t),
+				B: lerp(from.B, to.B, t),
+				A: 255,
+			}
+			if ((x-y+height)/band)%2 == 0 {
+				c.R, c.G, c.B = c.R/2+64, c.G/2+64, c.B/2+64
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func lerp(a, b uint8, t float64) uint8 {
+	return uint8(float64(a) + (float64(b)-float64(a))*t)
+}
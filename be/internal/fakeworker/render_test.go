@@ -0,0 +1,22 @@
+package fakeworker
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRenderDeterministic(t *testing.T) {
+	a := Render("a red fox", 42, 64, 48)
+	if b := a.Bounds(); b.Dx() != 64 || b.Dy() != 48 {
+		t.Fatalf("bounds = %v, want 64x48", b)
+	}
+	if !bytes.Equal(a.Pix, Render("a red fox", 42, 64, 48).Pix) {
+		t.Fatal("same prompt and seed rendered different pixels")
+	}
+	if bytes.Equal(a.Pix, Render("a red fox", 43, 64, 48).Pix) {
+		t.Fatal("a different seed rendered the same pixels")
+	}
+	if bytes.Equal(a.Pix, Render("a blue fox", 42, 64, 48).Pix) {
+		t.Fatal("a different prompt rendered the same pixels")
+	}
+}
@@ -0,0 +1,286 @@
+package fakeworker
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"be/proto"
+
+	"github.com/charmbracelet/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultSteps         = 30
+	defaultGuidanceScale = 7
+	defaultSize          = 1024
+)
+
+// Must stay in sync with the scheduler names the Go API accepts and the
+// python worker's SCHEDULERS table.
+var schedulers = map[string]bool{
+	"euler":               true,
+	"euler_a":             true,
+	"heun":                true,
+	"lms":                 true,
+	"ddim":                true,
+	"unipc":               true,
+	"dpmpp_2m":            true,
+	"dpmpp_2m_karras":     true,
+	"dpmpp_2m_sde":        true,
+	"dpmpp_2m_sde_karras": true,
+}
+
+// Server is a stand-in for the python worker. It keeps the model and LoRA
+// state in memory and answers with the same status codes the real worker
+// uses, but renders a pattern derived from the prompt and seed instead of
+// running a pipeline, so it needs no GPU.
+type Server struct {
+	proto.UnimplementedImageServiceServer
+
+	stepDelay time.Duration
+
+	mu        sync.Mutex
+	modelPath string
+	loras     []*proto.SetLora
+	guidance  float32 // of the last generation
+
+	grpc   *grpc.Server
+	logger *log.Logger
+}
+
+// New creates a fake worker. stepDelay is slept per sampling step so
+// progress events arrive at a pace resembling a real generation; zero
+// finishes immediately.
+func New(stepDelay time.Duration) *Server {
+	return &Server{
+		stepDelay: stepDelay,
+		logger:    log.With("component", "fakeworker"),
+	}
+}
+
+// Start listens on addr and serves in the background. It returns the
+// address actually bound, which differs from addr when it asks for port 0.
+func (s *Server) Start(addr string) (string, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", fmt.Errorf("error starting fake worker: %w", err)
+	}
+
+	s.grpc = grpc.NewServer()
+	proto.RegisterImageServiceServer(s.grpc, s)
+	go func() {
+		if err := s.grpc.Serve(lis); err != nil {
+			s.logger.Error("fake worker stopped", "err", err)
+		}
+	}()
+
+	s.logger.Info("fake worker listening", "addr", lis.Addr().String())
+	return lis.Addr().String(), nil
+}
+
+// Stop closes the listener and every open connection.
+func (s *Server) Stop() {
+	if s.grpc != nil {
+		s.grpc.Stop()
+	}
+}
+
+func (s *Server) GenerateImage(ctx context.Context, req *proto.GenerateImageRequest) (*proto.GenerateImageResponse, error) {
+	p, err := s.prepare(req)
+	if err != nil {
+		return nil, err
+	}
+	for step := int32(1); step <= p.steps; step++ {
+		if err := s.sleepStep(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return p.response()
+}
+
+func (s *Server) GenerateImageStream(req *proto.GenerateImageRequest, stream grpc.ServerStreamingServer[proto.GenerateImageEvent]) error {
+	p, err := s.prepare(req)
+	if err != nil {
+		return err
+	}
+
+	for step := int32(1); step <= p.steps; step++ {
+		if err := s.sleepStep(stream.Context()); err != nil {
+			return err
+		}
+		progress := &proto.GenerationProgress{Step: step, TotalSteps: p.steps}
+		if req.PreviewInterval > 0 && step%req.PreviewInterval == 0 && step < p.steps {
+			preview, err := p.preview(step)
+			if err != nil {
+				s.logger.Warn("render preview failed", "err", err)
+			} else {
+				progress.Preview = preview
+				progress.PreviewMimeType = "image/jpeg"
+			}
+		}
+		if err := stream.Send(&proto.GenerateImageEvent{
+			Event: &proto.GenerateImageEvent_Progress{Progress: progress},
+		}); err != nil {
+			return err
+		}
+	}
+
+	resp, err := p.response()
+	if err != nil {
+		return err
+	}
+	return stream.Send(&proto.GenerateImageEvent{
+		Event: &proto.GenerateImageEvent_Result{Result: resp},
+	})
+}
+
+// prepare applies the worker defaults and validates the request the way the
+// python worker does before it starts sampling.
+func (s *Server) prepare(req *proto.GenerateImageRequest) (params, error) {
+	s.mu.Lock()
+	modelPath := s.modelPath
+	s.mu.Unlock()
+	if modelPath == "" {
+		return params{}, status.Error(codes.FailedPrecondition, "Model must be set before generating images.")
+	}
+	if req.Scheduler != "" && !schedulers[req.Scheduler] {
+		return params{}, status.Errorf(codes.InvalidArgument, "Unknown scheduler: %s", req.Scheduler)
+	}
+
+	p := params{
+		prompt: req.PositivePrompt,
+		steps:  req.Steps,
+		width:  req.Width,
+		height: req.Height,
+		batch:  max(1, req.BatchSize),
+	}
+	if p.steps <= 0 {
+		p.steps = defaultSteps
+	}
+	if p.width <= 0 {
+		p.width = defaultSize
+	}
+	if p.height <= 0 {
+		p.height = defaultSize
+	}
+	if req.Seed != nil {
+		p.seed = *req.Seed
+	} else {
+		p.seed = rand.Int64N(1 << 32)
+	}
+
+	guidance := float32(defaultGuidanceScale)
+	if req.GuidanceScale != nil {
+		guidance = *req.GuidanceScale
+	}
+	s.mu.Lock()
+	s.guidance = guidance
+	s.mu.Unlock()
+	return p, nil
+}
+
+// GuidanceScale returns the guidance scale the last generation ran with,
+// after the worker default was applied.
+func (s *Server) GuidanceScale() float32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.guidance
+}
+
+func (s *Server) sleepStep(ctx context.Context) error {
+	if s.stepDelay <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(s.stepDelay)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (s *Server) SetModel(_ context.Context, req *proto.SetModelRequest) (*proto.SetModelResponse, error) {
+	info, err := os.Stat(req.ModelPath)
+	if err != nil || info.IsDir() {
+		return nil, status.Errorf(codes.NotFound, "Model not found: %s", req.ModelPath)
+	}
+
+	s.mu.Lock()
+	s.modelPath = req.ModelPath
+	s.loras = nil
+	s.mu.Unlock()
+
+	s.logger.Info("model set", "modelPath", req.ModelPath)
+	return &proto.SetModelResponse{ModelPath: req.ModelPath}, nil
+}
+
+func (s *Server) GetCurrentModel(context.Context, *proto.GetCurrentModelRequest) (*proto.GetCurrentModelResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &proto.GetCurrentModelResponse{ModelPath: s.modelPath}, nil
+}
+
+func (s *Server) ClearModel(context.Context, *proto.ClearModelRequest) (*proto.ClearModelResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := &proto.ClearModelResponse{ModelPath: s.modelPath, Loras: s.loras}
+	s.modelPath = ""
+	s.loras = nil
+	return resp, nil
+}
+
+// SetLora replaces the active LoRA stack. Like the python worker it skips
+// files that don't exist instead of failing, so callers compare the applied
+// stack with the one they asked for.
+func (s *Server) SetLora(_ context.Context, req *proto.SetLoraRequest) (*proto.SetLoraResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.modelPath == "" {
+		return nil, status.Error(codes.FailedPrecondition, "Model must be set before applying loras.")
+	}
+
+	applied := make([]*proto.SetLora, 0, len(req.Loras))
+	seen := map[string]bool{}
+	for _, l := range req.Loras {
+		info, err := os.Stat(l.Path)
+		if err != nil || info.IsDir() {
+			s.logger.Warn("lora not found", "path", l.Path)
+			continue
+		}
+		if l.Weight < 0.1 {
+			return nil, status.Error(codes.InvalidArgument, "LoRA weight must be >= 0.1")
+		}
+		if seen[l.Path] {
+			continue
+		}
+		seen[l.Path] = true
+		applied = append(applied, &proto.SetLora{Path: l.Path, Weight: l.Weight})
+	}
+
+	s.loras = applied
+	return &proto.SetLoraResponse{Loras: applied}, nil
+}
+
+func (s *Server) GetCurrentLoras(context.Context, *proto.GetCurrentLorasRequest) (*proto.GetCurrentLorasResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &proto.GetCurrentLorasResponse{Loras: s.loras}, nil
+}
+
+func (s *Server) ClearLoras(context.Context, *proto.ClearLorasRequest) (*proto.ClearLorasResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := s.loras
+	s.loras = nil
+	return &proto.ClearLorasResponse{Loras: removed}, nil
+}
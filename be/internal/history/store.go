@@ -0,0 +1,272 @@
+package history
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketRecords = []byte("records") // id => Record JSON
+	bucketByTime  = []byte("by_time") // createdAt (big-endian unix nanos) + id => id
+)
+
+var ErrNotFound = errors.New("history record not found")
+
+type Lora struct {
+	Path   string  `json:"path"`
+	Weight float32 `json:"weight"`
+}
+
+type Params struct {
+	Seed          int64   `json:"seed"`
+	Steps         int32   `json:"steps"`
+	GuidanceScale float32 `json:"guidanceScale"`
+	Width         int32   `json:"width"`
+	Height        int32   `json:"height"`
+	Scheduler     string  `json:"scheduler,omitempty"`
+	ClipSkip      int32   `json:"clipSkip,omitempty"`
+	BatchSize     int32   `json:"batchSize"`
+}
+
+type Record struct {
+	ID             string        `json:"id"`
+	CreatedAt      time.Time     `json:"createdAt"`
+	PositivePrompt string        `json:"positivePrompt"`
+	NegativePrompt string        `json:"negativePrompt"`
+	ModelPath      string        `json:"modelPath"`
+	Loras          []Lora        `json:"loras"`
+	Params         Params        `json:"params"`
+	Duration       time.Duration `json:"duration"`
+	MimeType       string        `json:"mimeType"`
+	// Images are paths relative to the outputs directory, in batch order.
+	Images []string `json:"images"`
+}
+
+// Filter narrows List results. Zero values match everything.
+type Filter struct {
+	Model  string // substring of the model path
+	Lora   string // substring of any applied LoRA path
+	From   time.Time
+	To     time.Time
+	Query  string // case-insensitive substring of either prompt
+	Offset int
+	Limit  int
+}
+
+type Store struct {
+	db         *bolt.DB
+	outputsDir string
+	logger     *log.Logger
+}
+
+func Open(dbPath, outputsDir string) (*Store, error) {
+	logger := log.With("component", "history", "db", dbPath)
+
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+		return nil, fmt.Errorf("error creating history dir: %w", err)
+	}
+	if err := os.MkdirAll(outputsDir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating outputs dir: %w", err)
+	}
+
+	db, err := bolt.Open(dbPath, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("error opening history db: %w", err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(bucketRecords); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(bucketByTime)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error initializing history db: %w", err)
+	}
+
+	logger.Info("history opened", "outputs", outputsDir)
+	return &Store{db: db, outputsDir: outputsDir, logger: logger}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// ImagePath resolves one of the record's images to an absolute path.
+func (s *Store) ImagePath(rec Record, index int) (string, error) {
+	if index < 0 || index >= len(rec.Images) {
+		return "", fmt.Errorf("image index %d out of range", index)
+	}
+	return filepath.Join(s.outputsDir, rec.Images[index]), nil
+}
+
+// Save writes the images into the outputs directory and persists the record.
+func (s *Store) Save(rec Record, images [][]byte, ext string) (Record, error) {
+	if rec.ID == "" {
+		return rec, errors.New("missing record id")
+	}
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = time.Now()
+	}
+
+	dayDir := rec.CreatedAt.UTC().Format("2006-01-02")
+	if err := os.MkdirAll(filepath.Join(s.outputsDir, dayDir), 0o755); err != nil {
+		return rec, err
+	}
+
+	rec.Images = make([]string, 0, len(images))
+	for i, img := range images {
+		rel := filepath.Join(dayDir, fmt.Sprintf("%s-%d%s", rec.ID, i, ext))
+		if err := os.WriteFile(filepath.Join(s.outputsDir, rel), img, 0o644); err != nil {
+			s.removeImages(rec.Images)
+			return rec, err
+		}
+		rec.Images = append(rec.Images, rel)
+	}
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		s.removeImages(rec.Images)
+		return rec, err
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(bucketRecords).Put([]byte(rec.ID), b); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketByTime).Put(timeKey(rec.CreatedAt, rec.ID), []byte(rec.ID))
+	})
+	if err != nil {
+		s.removeImages(rec.Images)
+		return rec, err
+	}
+
+	s.logger.Debug("history saved", "id", rec.ID, "images", len(rec.Images))
+	return rec, nil
+}
+
+func (s *Store) Get(id string) (Record, error) {
+	var rec Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketRecords).Get([]byte(id))
+		if b == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(b, &rec)
+	})
+	return rec, err
+}
+
+// List returns matching records newest first, along with the total number of
+// matches before pagination.
+func (s *Store) List(f Filter) ([]Record, int, error) {
+	query := strings.ToLower(strings.TrimSpace(f.Query))
+	records := []Record{}
+	total := 0
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		recs := tx.Bucket(bucketRecords)
+		c := tx.Bucket(bucketByTime).Cursor()
+		for k, id := c.Last(); k != nil; k, id = c.Prev() {
+			createdAt := time.Unix(0, int64(binary.BigEndian.Uint64(k[:8])))
+			if !f.To.IsZero() && createdAt.After(f.To) {
+				continue
+			}
+			if !f.From.IsZero() && createdAt.Before(f.From) {
+				break
+			}
+
+			b := recs.Get(id)
+			if b == nil {
+				continue
+			}
+			var rec Record
+			if err := json.Unmarshal(b, &rec); err != nil {
+				s.logger.Warn("history record unreadable", "id", string(id), "err", err)
+				continue
+			}
+			if !matches(rec, f, query) {
+				continue
+			}
+
+			total++
+			if total > f.Offset && (f.Limit <= 0 || len(records) < f.Limit) {
+				records = append(records, rec)
+			}
+		}
+		return nil
+	})
+	return records, total, err
+}
+
+// Delete removes the record and its image files.
+func (s *Store) Delete(id string) (Record, error) {
+	var rec Record
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		recs := tx.Bucket(bucketRecords)
+		b := recs.Get([]byte(id))
+		if b == nil {
+			return ErrNotFound
+		}
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return err
+		}
+		if err := recs.Delete([]byte(id)); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketByTime).Delete(timeKey(rec.CreatedAt, rec.ID))
+	})
+	if err != nil {
+		return rec, err
+	}
+
+	s.removeImages(rec.Images)
+	s.logger.Debug("history deleted", "id", id)
+	return rec, nil
+}
+
+func (s *Store) removeImages(images []string) {
+	for _, rel := range images {
+		if err := os.Remove(filepath.Join(s.outputsDir, rel)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			s.logger.Warn("history remove image failed", "file", rel, "err", err)
+		}
+	}
+}
+
+func matches(rec Record, f Filter, query string) bool {
+	if f.Model != "" && !strings.Contains(rec.ModelPath, f.Model) {
+		return false
+	}
+	if f.Lora != "" {
+		found := false
+		for _, l := range rec.Loras {
+			if strings.Contains(l.Path, f.Lora) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if query != "" &&
+		!strings.Contains(strings.ToLower(rec.PositivePrompt), query) &&
+		!strings.Contains(strings.ToLower(rec.NegativePrompt), query) {
+		return false
+	}
+	return true
+}
+
+func timeKey(t time.Time, id string) []byte {
+	k := make([]byte, 8, 8+len(id))
+	binary.BigEndian.PutUint64(k, uint64(t.UnixNano()))
+	return append(k, id...)
+}
@@ -0,0 +1,82 @@
+package history
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(filepath.Join(dir, "history.db"), filepath.Join(dir, "outputs"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer s.Close()
+
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	seed := []Record{
+		{ID: "a", CreatedAt: base, PositivePrompt: "A red fox", ModelPath: "/models/sdxl.safetensors"},
+		{ID: "b", CreatedAt: base.Add(time.Hour), PositivePrompt: "blue sky", ModelPath: "/models/pony.safetensors", Loras: []Lora{{Path: "/loras/1-ink.safetensors", Weight: 0.8}}},
+		{ID: "c", CreatedAt: base.Add(48 * time.Hour), PositivePrompt: "castle", NegativePrompt: "fox", ModelPath: "/models/sdxl.safetensors"},
+	}
+	for _, rec := range seed {
+		if _, err := s.Save(rec, [][]byte{[]byte("png")}, ".png"); err != nil {
+			t.Fatalf("save %s: %v", rec.ID, err)
+		}
+	}
+
+	ids := func(recs []Record) string {
+		out := ""
+		for _, r := range recs {
+			out += r.ID
+		}
+		return out
+	}
+
+	cases := map[string]struct {
+		filter Filter
+		want   string
+		total  int
+	}{
+		"all_newest_first": {Filter{}, "cba", 3},
+		"model":            {Filter{Model: "sdxl"}, "ca", 2},
+		"lora":             {Filter{Lora: "ink"}, "b", 1},
+		"text":             {Filter{Query: "FOX"}, "ca", 2},
+		"date_range":       {Filter{From: base.Add(30 * time.Minute), To: base.Add(24 * time.Hour)}, "b", 1},
+		"paged":            {Filter{Offset: 1, Limit: 1}, "b", 3},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, total, err := s.List(tc.filter)
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			if ids(got) != tc.want || total != tc.total {
+				t.Fatalf("got %q (total %d), want %q (total %d)", ids(got), total, tc.want, tc.total)
+			}
+		})
+	}
+
+	t.Run("delete_removes_images", func(t *testing.T) {
+		rec, err := s.Get("a")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		path, err := s.ImagePath(rec, 0)
+		if err != nil {
+			t.Fatalf("image path: %v", err)
+		}
+		if _, err := s.Delete("a"); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("image still present: %v", err)
+		}
+		if _, err := s.Get("a"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // decoder, for sources that are already WebP
+)
+
+type Format string
+
+const (
+	FormatPNG  Format = "png"
+	FormatJPEG Format = "jpeg"
+	FormatWebP Format = "webp"
+)
+
+const (
+	DefaultQuality = 85
+	MinThumbWidth  = 16
+	MaxThumbWidth  = 1024
+)
+
+// ParseFormat accepts png, jpeg (or jpg) and webp, case-insensitively.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "png":
+		return FormatPNG, nil
+	case "jpeg", "jpg":
+		return FormatJPEG, nil
+	case "webp":
+		return FormatWebP, nil
+	}
+	return "", fmt.Errorf("unsupported image format %q (want png, jpeg or webp)", s)
+}
+
+// FormatOf maps a MIME type back to a Format.
+func FormatOf(mimeType string) (Format, bool) {
+	switch mimeType {
+	case "image/png":
+		return FormatPNG, true
+	case "image/jpeg":
+		return FormatJPEG, true
+	case "image/webp":
+		return FormatWebP, true
+	}
+	return "", false
+}
+
+func (f Format) MimeType() string {
+	return "image/" + string(f)
+}
+
+func (f Format) Ext() string {
+	if f == FormatJPEG {
+		return ".jpg"
+	}
+	return "." + string(f)
+}
+
+// Convert re-encodes src into the target format. quality only applies to
+// JPEG; PNG and WebP are lossless. Converting drops PNG text chunks, so the
+// embedded generation parameters only survive in PNG output.
+func Convert(src []byte, to Format, quality int) ([]byte, error) {
+	if to == FormatPNG && IsPNG(src) {
+		return src, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	return encode(img, to, quality)
+}
+
+// Thumbnail scales src down to width pixels, keeping the aspect ratio, and
+// encodes it in the target format. Images already narrower than width are
+// only re-encoded.
+func Thumbnail(src []byte, width int, to Format, quality int) ([]byte, error) {
+	if width < MinThumbWidth || width > MaxThumbWidth {
+		return nil, fmt.Errorf("thumbnail width must be between %d and %d", MinThumbWidth, MaxThumbWidth)
+	}
+	img, _, err := image.Decode(bytes.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+
+	b := img.Bounds()
+	if b.Dx() > width {
+		height := max(1, b.Dy()*width/b.Dx())
+		dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+		img = dst
+	}
+	return encode(img, to, quality)
+}
+
+func encode(img image.Image, to Format, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch to {
+	case FormatPNG:
+		err = (&png.Encoder{CompressionLevel: png.BestSpeed}).Encode(&buf, img)
+	case FormatJPEG:
+		if quality < 1 || quality > 100 {
+			quality = DefaultQuality
+		}
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	case FormatWebP:
+		err = nativewebp.Encode(&buf, img, nil)
+	default:
+		err = fmt.Errorf("unsupported image format %q", to)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,157 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// gradientPNG is detailed enough for JPEG quality to make a difference.
+func gradientPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 7), G: uint8(y * 5), B: uint8(x ^ y), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]Format{"png": FormatPNG, " JPG ": FormatJPEG, "jpeg": FormatJPEG, "WebP": FormatWebP} {
+		if got, err := ParseFormat(in); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", in, got, err, want)
+		}
+		if got, ok := FormatOf(want.MimeType()); !ok || got != want {
+			t.Errorf("FormatOf(%q) = %q, %v", want.MimeType(), got, ok)
+		}
+	}
+	if _, err := ParseFormat("gif"); err == nil {
+		t.Error("ParseFormat(gif) succeeded")
+	}
+	if FormatJPEG.Ext() != ".jpg" || FormatWebP.Ext() != ".webp" {
+		t.Errorf("ext = %s, %s", FormatJPEG.Ext(), FormatWebP.Ext())
+	}
+}
+
+func TestConvert(t *testing.T) {
+	src := gradientPNG(t, 40, 30)
+	want, _ := png.Decode(bytes.NewReader(src))
+
+	for _, tc := range []struct {
+		to       Format
+		decode   func([]byte) (image.Image, error)
+		lossless bool
+	}{
+		{FormatPNG, func(b []byte) (image.Image, error) { return png.Decode(bytes.NewReader(b)) }, true},
+		{FormatJPEG, func(b []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(b)) }, false},
+		{FormatWebP, func(b []byte) (image.Image, error) { return webp.Decode(bytes.NewReader(b)) }, true},
+	} {
+		t.Run(string(tc.to), func(t *testing.T) {
+			out, err := Convert(src, tc.to, DefaultQuality)
+			if err != nil {
+				t.Fatalf("convert: %v", err)
+			}
+			got, err := tc.decode(out)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got.Bounds() != want.Bounds() {
+				t.Fatalf("bounds = %v, want %v", got.Bounds(), want.Bounds())
+			}
+			if !tc.lossless {
+				return
+			}
+			for y := 0; y < 30; y++ {
+				for x := 0; x < 40; x++ {
+					if a, b := color.NRGBAModel.Convert(got.At(x, y)), color.NRGBAModel.Convert(want.At(x, y)); a != b {
+						t.Fatalf("pixel %d,%d = %v, want %v", x, y, a, b)
+					}
+				}
+			}
+		})
+	}
+
+	withText, err := SetText(src, ParametersKey, "a red fox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err := Convert(withText, FormatPNG, 0); err != nil || !bytes.Equal(out, withText) {
+		t.Errorf("png to png re-encoded the image (%v)", err)
+	}
+	if _, err := Convert([]byte("not an image"), FormatJPEG, DefaultQuality); err == nil {
+		t.Error("converted garbage")
+	}
+	if _, err := Convert(src, "gif", DefaultQuality); err == nil {
+		t.Error("converted to an unknown format")
+	}
+}
+
+func TestConvertQuality(t *testing.T) {
+	src := gradientPNG(t, 64, 64)
+	size := func(quality int) int {
+		t.Helper()
+		out, err := Convert(src, FormatJPEG, quality)
+		if err != nil {
+			t.Fatalf("convert at %d: %v", quality, err)
+		}
+		return len(out)
+	}
+
+	if low, high := size(1), size(100); low >= high {
+		t.Errorf("quality 1 = %d bytes, quality 100 = %d; want the low quality smaller", low, high)
+	}
+	// Out of range values fall back to the default rather than failing.
+	def := size(DefaultQuality)
+	for _, q := range []int{0, -5, 101} {
+		if got := size(q); got != def {
+			t.Errorf("quality %d = %d bytes, want the default's %d", q, got, def)
+		}
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	src := gradientPNG(t, 200, 100)
+
+	for _, tc := range []struct {
+		width      int
+		wantW      int
+		wantH      int
+		shouldFail bool
+	}{
+		{width: 64, wantW: 64, wantH: 32},
+		{width: 512, wantW: 200, wantH: 100}, // narrower than asked: not upscaled
+		{width: MinThumbWidth - 1, shouldFail: true},
+		{width: MaxThumbWidth + 1, shouldFail: true},
+	} {
+		for _, to := range []Format{FormatPNG, FormatJPEG, FormatWebP} {
+			out, err := Thumbnail(src, tc.width, to, DefaultQuality)
+			if tc.shouldFail {
+				if err == nil {
+					t.Errorf("thumbnail %d as %s succeeded", tc.width, to)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("thumbnail %d as %s: %v", tc.width, to, err)
+			}
+			cfg, format, err := image.DecodeConfig(bytes.NewReader(out))
+			if err != nil || format != string(to) {
+				t.Fatalf("thumbnail %d as %s decoded as %q: %v", tc.width, to, format, err)
+			}
+			if cfg.Width != tc.wantW || cfg.Height != tc.wantH {
+				t.Errorf("thumbnail %d as %s = %dx%d, want %dx%d", tc.width, to, cfg.Width, cfg.Height, tc.wantW, tc.wantH)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package imaging
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParametersKey is the PNG text keyword AUTOMATIC1111 uses for its
+// generation parameters.
+const ParametersKey = "parameters"
+
+type Lora struct {
+	Name   string
+	Weight float32
+}
+
+// Parameters describes a generation in AUTOMATIC1111's infotext format.
+// Empty or zero optional fields are left out.
+type Parameters struct {
+	Prompt         string
+	NegativePrompt string
+	Loras          []Lora
+	Steps          int32
+	Sampler        string
+	CFGScale       float32
+	Seed           int64
+	Width          int32
+	Height         int32
+	ModelHash      string
+	Model          string
+	// ClipSkip uses A1111's numbering, where 1 means no layers skipped.
+	ClipSkip int32
+}
+
+// String renders the infotext: the prompt (with <lora:name:weight> tags),
+// an optional "Negative prompt:" line and a line of comma separated settings.
+func (p Parameters) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(p.Prompt)
+	for _, l := range p.Loras {
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		fmt.Fprintf(&sb, "<lora:%s:%s>", l.Name, formatFloat(l.Weight))
+	}
+	if p.NegativePrompt != "" {
+		sb.WriteString("\nNegative prompt: ")
+		sb.WriteString(p.NegativePrompt)
+	}
+
+	settings := []string{"Steps: " + strconv.Itoa(int(p.Steps))}
+	if p.Sampler != "" {
+		settings = append(settings, "Sampler: "+quote(p.Sampler))
+	}
+	settings = append(settings,
+		"CFG scale: "+formatFloat(p.CFGScale),
+		"Seed: "+strconv.FormatInt(p.Seed, 10),
+		fmt.Sprintf("Size: %dx%d", p.Width, p.Height),
+	)
+	if p.ModelHash != "" {
+		settings = append(settings, "Model hash: "+p.ModelHash)
+	}
+	if p.Model != "" {
+		settings = append(settings, "Model: "+quote(p.Model))
+	}
+	if p.ClipSkip > 1 {
+		settings = append(settings, "Clip skip: "+strconv.Itoa(int(p.ClipSkip)))
+	}
+
+	sb.WriteByte('\n')
+	sb.WriteString(strings.Join(settings, ", "))
+	return sb.String()
+}
+
+func formatFloat(v float32) string {
+	return strconv.FormatFloat(float64(v), 'f', -1, 32)
+}
+
+// quote wraps values that would break the "key: value, ..." line the same
+// way A1111 does, as a JSON string.
+func quote(v string) string {
+	if !strings.ContainsAny(v, ",:\n\"") {
+		return v
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
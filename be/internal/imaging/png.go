@@ -0,0 +1,160 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"unicode/utf8"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+var ErrNotPNG = errors.New("not a png image")
+
+// IsPNG reports whether b starts with the PNG signature.
+func IsPNG(b []byte) bool {
+	return bytes.HasPrefix(b, pngSignature)
+}
+
+// SetText stores text under keyword, replacing any existing text chunk with
+// the same keyword. Pixel data is copied through untouched. Text that fits in
+// Latin-1 goes into a tEXt chunk, anything else into an uncompressed iTXt
+// chunk, which is what most readers (and A1111 itself) expect.
+func SetText(img []byte, keyword, text string) ([]byte, error) {
+	if len(keyword) == 0 || len(keyword) > 79 {
+		return nil, fmt.Errorf("invalid png text keyword %q", keyword)
+	}
+
+	var chunk []byte
+	if latin1, ok := toLatin1(text); ok {
+		data := append(append([]byte(keyword), 0), latin1...)
+		chunk = encodeChunk("tEXt", data)
+	} else {
+		// keyword, null, compression flag, compression method, empty
+		// language tag, empty translated keyword, UTF-8 text
+		data := append([]byte(keyword), 0, 0, 0, 0, 0)
+		chunk = encodeChunk("iTXt", append(data, text...))
+	}
+
+	out := make([]byte, 0, len(img)+len(chunk))
+	out = append(out, pngSignature...)
+	err := walkChunks(img, func(typ string, data, raw []byte) {
+		if isTextChunk(typ) && textKeyword(data) == keyword {
+			return
+		}
+		out = append(out, raw...)
+		if typ == "IHDR" {
+			out = append(out, chunk...)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StripText removes every textual and EXIF chunk, leaving only what is
+// needed to render the image.
+func StripText(img []byte) ([]byte, error) {
+	out := make([]byte, 0, len(img))
+	out = append(out, pngSignature...)
+	err := walkChunks(img, func(typ string, data, raw []byte) {
+		if isTextChunk(typ) || typ == "eXIf" {
+			return
+		}
+		out = append(out, raw...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Text returns the value of the tEXt or uncompressed iTXt chunk stored under
+// keyword.
+func Text(img []byte, keyword string) (string, bool) {
+	var value string
+	found := false
+	_ = walkChunks(img, func(typ string, data, raw []byte) {
+		if found || !isTextChunk(typ) || textKeyword(data) != keyword {
+			return
+		}
+		rest := data[len(keyword)+1:]
+		switch typ {
+		case "tEXt":
+			runes := make([]rune, len(rest))
+			for i, b := range rest {
+				runes[i] = rune(b)
+			}
+			value, found = string(runes), true
+		case "iTXt":
+			// skip compression flag/method, language tag and translated keyword
+			if len(rest) < 2 || rest[0] != 0 {
+				return
+			}
+			parts := bytes.SplitN(rest[2:], []byte{0}, 3)
+			if len(parts) == 3 {
+				value, found = string(parts[2]), true
+			}
+		}
+	})
+	return value, found
+}
+
+// walkChunks calls fn for every chunk with its data and its raw encoding
+// (length, type, data and CRC). It stops after IEND.
+func walkChunks(img []byte, fn func(typ string, data, raw []byte)) error {
+	if !IsPNG(img) {
+		return ErrNotPNG
+	}
+	b := img[len(pngSignature):]
+	for len(b) > 0 {
+		if len(b) < 12 {
+			return errors.New("truncated png chunk")
+		}
+		n := binary.BigEndian.Uint32(b[:4])
+		if uint64(n)+12 > uint64(len(b)) {
+			return errors.New("truncated png chunk")
+		}
+		typ := string(b[4:8])
+		raw := b[:12+n]
+		fn(typ, raw[8:8+n], raw)
+		b = b[12+n:]
+		if typ == "IEND" {
+			return nil
+		}
+	}
+	return errors.New("png missing IEND")
+}
+
+func encodeChunk(typ string, data []byte) []byte {
+	out := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(out[:4], uint32(len(data)))
+	copy(out[4:8], typ)
+	out = append(out, data...)
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(out[4:]))
+}
+
+func isTextChunk(typ string) bool {
+	return typ == "tEXt" || typ == "zTXt" || typ == "iTXt"
+}
+
+func textKeyword(data []byte) string {
+	if i := bytes.IndexByte(data, 0); i > 0 {
+		return string(data[:i])
+	}
+	return ""
+}
+
+func toLatin1(s string) ([]byte, bool) {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xff || r == utf8.RuneError {
+			return nil, false
+		}
+		out = append(out, byte(r))
+	}
+	return out, true
+}
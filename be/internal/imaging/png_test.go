@@ -0,0 +1,82 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestSetText(t *testing.T) {
+	src := testPNG(t)
+
+	for name, text := range map[string]string{
+		"latin1": "a red fox, café\nSteps: 30",
+		"utf8":   "狐 in the snow",
+	} {
+		t.Run(name, func(t *testing.T) {
+			out, err := SetText(src, ParametersKey, "stale")
+			if err != nil {
+				t.Fatalf("set: %v", err)
+			}
+			if out, err = SetText(out, ParametersKey, text); err != nil {
+				t.Fatalf("replace: %v", err)
+			}
+
+			got, ok := Text(out, ParametersKey)
+			if !ok || got != text {
+				t.Fatalf("got %q (found %v), want %q", got, ok, text)
+			}
+			if _, err := png.Decode(bytes.NewReader(out)); err != nil {
+				t.Fatalf("annotated png no longer decodes: %v", err)
+			}
+
+			stripped, err := StripText(out)
+			if err != nil {
+				t.Fatalf("strip: %v", err)
+			}
+			if !bytes.Equal(stripped, src) {
+				t.Fatalf("stripping should restore the original bytes")
+			}
+		})
+	}
+
+	if _, err := SetText([]byte("GIF89a"), ParametersKey, "x"); err != ErrNotPNG {
+		t.Fatalf("expected ErrNotPNG, got %v", err)
+	}
+}
+
+func TestParametersString(t *testing.T) {
+	p := Parameters{
+		Prompt:         "a red fox",
+		NegativePrompt: "blurry",
+		Loras:          []Lora{{Name: "ink", Weight: 0.8}},
+		Steps:          30,
+		Sampler:        "DPM++ 2M Karras",
+		CFGScale:       7.5,
+		Seed:           1234,
+		Width:          1024,
+		Height:         768,
+		ModelHash:      "31e35c80fc",
+		Model:          "sd_xl_base_1.0",
+		ClipSkip:       2,
+	}
+	want := "a red fox <lora:ink:0.8>\n" +
+		"Negative prompt: blurry\n" +
+		"Steps: 30, Sampler: DPM++ 2M Karras, CFG scale: 7.5, Seed: 1234, Size: 1024x768, Model hash: 31e35c80fc, Model: sd_xl_base_1.0, Clip skip: 2"
+	if got := p.String(); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
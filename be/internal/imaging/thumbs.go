@@ -0,0 +1,65 @@
+package imaging
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ThumbCache stores rendered thumbnails on disk under caller-chosen keys.
+// Keys are used as file names, so they must not contain path separators.
+type ThumbCache struct {
+	dir string
+}
+
+func NewThumbCache(dir string) (*ThumbCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &ThumbCache{dir: dir}, nil
+}
+
+func (c *ThumbCache) Get(key string) ([]byte, bool) {
+	b, err := os.ReadFile(filepath.Join(c.dir, key))
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// Put writes through a temp file so concurrent readers never see a partial
+// thumbnail.
+func (c *ThumbCache) Put(key string, data []byte) error {
+	if strings.ContainsAny(key, `/\`) {
+		return errors.New("invalid thumbnail key")
+	}
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(c.dir, key))
+}
+
+// RemovePrefix drops every thumbnail whose key starts with prefix.
+func (c *ThumbCache) RemovePrefix(prefix string) error {
+	matches, err := filepath.Glob(filepath.Join(c.dir, prefix+"*"))
+	if err != nil {
+		return err
+	}
+	for _, m := range matches {
+		if err := os.Remove(m); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
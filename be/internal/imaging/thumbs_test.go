@@ -0,0 +1,53 @@
+package imaging
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestThumbCache(t *testing.T) {
+	c, err := NewThumbCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := c.Get("abc-0-w320-q85.jpg"); ok {
+		t.Fatal("hit on an empty cache")
+	}
+	if err := c.Put("abc-0-w320-q85.jpg", []byte("thumb")); err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := c.Get("abc-0-w320-q85.jpg"); !ok || !bytes.Equal(b, []byte("thumb")) {
+		t.Fatalf("get = %q, %v; want the stored thumbnail", b, ok)
+	}
+	if _, ok := c.Get("abc-0-w320-q85.webp"); ok {
+		t.Fatal("hit for a key that was never stored")
+	}
+	if err := c.Put("../escape", []byte("x")); err == nil {
+		t.Fatal("stored a key with a path separator")
+	}
+
+	if err := c.Put("abc-1-w320-q85.jpg", []byte("thumb")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put("def-0-w320-q85.jpg", []byte("other")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RemovePrefix("abc-"); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]bool{"abc-0-w320-q85.jpg": false, "abc-1-w320-q85.jpg": false, "def-0-w320-q85.jpg": true} {
+		if _, ok := c.Get(key); ok != want {
+			t.Errorf("after removing abc-: %s cached = %v, want %v", key, ok, want)
+		}
+	}
+
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("cache dir holds %d files, want no leftover temp files", len(entries))
+	}
+}
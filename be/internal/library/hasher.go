@@ -0,0 +1,169 @@
+package library
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Run scans once, then rescans every interval (0 disables rescans) and
+// hashes new files in the background until ctx is cancelled.
+func (l *Library) Run(ctx context.Context, interval time.Duration) {
+	l.wg.Add(2)
+	go func() {
+		defer l.wg.Done()
+		l.hashLoop(ctx)
+	}()
+	go func() {
+		defer l.wg.Done()
+		if err := l.Scan(); err != nil {
+			l.logger.Error("library scan failed", "err", err)
+		}
+		if interval <= 0 {
+			return
+		}
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if err := l.Scan(); err != nil {
+					l.logger.Error("library scan failed", "err", err)
+				}
+			}
+		}
+	}()
+}
+
+func (l *Library) queueHash(path string) {
+	l.hashMu.Lock()
+	if _, ok := l.hashQueued[path]; !ok {
+		l.hashQueued[path] = struct{}{}
+		l.hashPending = append(l.hashPending, path)
+	}
+	l.hashMu.Unlock()
+
+	select {
+	case l.hashWake <- struct{}{}:
+	default:
+	}
+}
+
+func (l *Library) nextHash() (string, bool) {
+	l.hashMu.Lock()
+	defer l.hashMu.Unlock()
+	if len(l.hashPending) == 0 {
+		return "", false
+	}
+	path := l.hashPending[0]
+	l.hashPending = l.hashPending[1:]
+	delete(l.hashQueued, path)
+	return path, true
+}
+
+// hashLoop hashes one file at a time; checkpoints are several GB and
+// hashing them in parallel would only fight over the disk.
+func (l *Library) hashLoop(ctx context.Context) {
+	for {
+		path, ok := l.nextHash()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-l.hashWake:
+				continue
+			}
+		}
+		if err := l.hash(ctx, path); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			l.logger.Warn("hash failed", "path", path, "err", err)
+		}
+	}
+}
+
+// Hash returns the entry for path, hashing the file first if the background
+// hasher hasn't got to it yet.
+func (l *Library) Hash(ctx context.Context, path string) (Entry, error) {
+	path = filepath.Clean(path)
+	if err := l.hash(ctx, path); err != nil {
+		return Entry{}, err
+	}
+	return l.Get(path)
+}
+
+func (l *Library) hash(ctx context.Context, path string) error {
+	l.mu.RLock()
+	e, ok := l.entries[path]
+	l.mu.RUnlock()
+	if !ok || e.SHA256 != "" {
+		return nil
+	}
+
+	start := time.Now()
+	sum, err := hashFile(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	cur, ok := l.entries[path]
+	if !ok || cur.Size != e.Size || !cur.ModTime.Equal(e.ModTime) {
+		// Changed or removed while hashing; the next scan queues it again.
+		l.mu.Unlock()
+		return nil
+	}
+	cur.SHA256 = sum
+	l.entries[path] = cur
+	l.mu.Unlock()
+
+	v, err := json.Marshal(cur)
+	if err != nil {
+		return err
+	}
+	if err := l.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketEntries).Put([]byte(path), v)
+	}); err != nil {
+		return err
+	}
+	l.notify([]Change{{ChangeChanged, cur}})
+	l.logger.Debug("hashed", "path", path, "bytes", cur.Size, "dur", time.Since(start).String())
+	return nil
+}
+
+// hashFile returns the file's SHA256 as lowercase hex, giving up as soon as
+// ctx is cancelled.
+func hashFile(ctx context.Context, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	buf := make([]byte, 1<<20)
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		n, err := f.Read(buf)
+		h.Write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,552 @@
+package library
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucketEntries = []byte("entries") // path => Entry JSON
+
+var (
+	ErrNotFound    = errors.New("library entry not found")
+	ErrOutsideRoot = errors.New("path is outside the library root")
+)
+
+// Kind is which library root a file lives under.
+type Kind string
+
+const (
+	KindCheckpoint Kind = "checkpoint"
+	KindLora       Kind = "lora"
+	KindEmbedding  Kind = "embedding" // textual inversions
+	KindVAE        Kind = "vae"
+	KindControlNet Kind = "controlnet"
+	KindUpscaler   Kind = "upscaler"
+	KindLycoris    Kind = "lycoris" // LoCon and DoRA
+)
+
+// Kinds lists every kind in the order the API reports them.
+var Kinds = []Kind{KindCheckpoint, KindLora, KindEmbedding, KindVAE, KindControlNet, KindUpscaler, KindLycoris}
+
+// Extensions are the file types the library indexes, by kind. Checkpoints
+// and LoRAs are loaded by the worker and have to be safetensors; the other
+// kinds are still often published pickled, and are listed when an admin
+// downloads them anyway.
+var Extensions = map[Kind][]string{
+	KindCheckpoint: {".safetensors"},
+	KindLora:       {".safetensors"},
+	KindLycoris:    {".safetensors"},
+	KindEmbedding:  {".safetensors", ".pt", ".bin"},
+	KindVAE:        {".safetensors", ".pt", ".bin"},
+	KindControlNet: {".safetensors", ".pth", ".bin"},
+	KindUpscaler:   {".safetensors", ".pth", ".pt"},
+}
+
+type Entry struct {
+	Path string `json:"path"`
+	Kind Kind   `json:"kind"`
+	Name string `json:"name"`
+	// BaseModel is the first folder below the root, which is where the
+	// downloader files models (e.g. "SDXL-1.0"). Empty for files at the root.
+	BaseModel string    `json:"baseModel,omitempty"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"modTime"`
+	// SHA256 is filled in by the background hasher and kept for as long as
+	// size and modification time are unchanged.
+	SHA256 string `json:"sha256,omitempty"`
+	// Verified is set when the file matched the model host's published hash
+	// as it was downloaded. Like SHA256 it is dropped when the file changes.
+	Verified bool `json:"verified,omitempty"`
+}
+
+// equal compares entries field by field. ModTime is compared with Equal:
+// times read back from the index carry a different Location than the ones
+// os.Stat returns, so == would see every entry as changed after a restart.
+func (e Entry) equal(o Entry) bool {
+	return e.Path == o.Path &&
+		e.Kind == o.Kind &&
+		e.Name == o.Name &&
+		e.BaseModel == o.BaseModel &&
+		e.Size == o.Size &&
+		e.ModTime.Equal(o.ModTime) &&
+		e.SHA256 == o.SHA256 &&
+		e.Verified == o.Verified
+}
+
+// ChangeType says how an entry changed.
+type ChangeType string
+
+const (
+	ChangeAdded   ChangeType = "added"
+	ChangeRemoved ChangeType = "removed"
+	ChangeChanged ChangeType = "changed" // new size, modification time or hash
+)
+
+// Change is reported to the OnChange callback. Removed changes carry the
+// entry as it was last indexed.
+type Change struct {
+	Type  ChangeType
+	Entry Entry
+}
+
+// Sort orders for Filter.Sort.
+const (
+	SortName      = "name"
+	SortSize      = "size"
+	SortModified  = "modified"
+	SortBaseModel = "baseModel"
+)
+
+// Filter narrows List results. Zero values match everything.
+type Filter struct {
+	Kind      Kind
+	BaseModel string // base model folder, case-insensitive
+	Query     string // case-insensitive substring of the name or path
+	Sort      string // one of the Sort constants, name by default
+	Desc      bool
+	Offset    int
+	Limit     int // 0 returns everything after Offset
+}
+
+// Library indexes the model roots. The index lives in memory and is
+// persisted in bbolt so hashes survive restarts; Scan brings it in line with
+// the disk.
+type Library struct {
+	roots  map[Kind]string
+	db     *bolt.DB
+	logger *log.Logger
+
+	scanMu  sync.Mutex
+	mu      sync.RWMutex
+	entries map[string]Entry
+	scanned time.Time
+
+	onChange func([]Change)
+
+	hashMu      sync.Mutex
+	hashPending []string
+	hashQueued  map[string]struct{}
+	hashWake    chan struct{}
+	wg          sync.WaitGroup
+}
+
+func Open(dbPath string, roots map[Kind]string) (*Library, error) {
+	logger := log.With("component", "library", "db", dbPath)
+
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+		return nil, fmt.Errorf("error creating library dir: %w", err)
+	}
+	db, err := bolt.Open(dbPath, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("error opening library db: %w", err)
+	}
+
+	l := &Library{
+		roots:      map[Kind]string{},
+		db:         db,
+		logger:     logger,
+		entries:    map[string]Entry{},
+		hashQueued: map[string]struct{}{},
+		hashWake:   make(chan struct{}, 1),
+	}
+	for kind, root := range roots {
+		if root = strings.TrimSpace(root); root != "" {
+			l.roots[kind] = filepath.Clean(root)
+		}
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(bucketEntries)
+		if err != nil {
+			return err
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var e Entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				logger.Warn("library entry unreadable", "path", string(k), "err", err)
+				return nil
+			}
+			l.entries[e.Path] = e
+			return nil
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error initializing library db: %w", err)
+	}
+
+	logger.Info("library opened", "entries", len(l.entries), "roots", l.roots)
+	return l, nil
+}
+
+// Close stops the background work (the context passed to Run must be
+// cancelled first) and closes the index.
+func (l *Library) Close() error {
+	l.wg.Wait()
+	return l.db.Close()
+}
+
+// OnChange registers fn to be called with every batch of index changes, from
+// scans and from the hasher. It is called without any lock held and must not
+// block for long; set it before Run.
+func (l *Library) OnChange(fn func([]Change)) {
+	l.mu.Lock()
+	l.onChange = fn
+	l.mu.Unlock()
+}
+
+func (l *Library) notify(changes []Change) {
+	l.mu.RLock()
+	fn := l.onChange
+	l.mu.RUnlock()
+	if fn != nil && len(changes) > 0 {
+		fn(changes)
+	}
+}
+
+// Root returns the configured root for kind, or "" when there is none.
+func (l *Library) Root(kind Kind) string {
+	return l.roots[kind]
+}
+
+// Resolve cleans path and checks that it lies under the root for kind, so
+// endpoints that take a path can't be pointed at arbitrary files.
+func (l *Library) Resolve(kind Kind, path string) (string, error) {
+	root := l.roots[kind]
+	path = strings.TrimSpace(path)
+	if root == "" || path == "" {
+		return "", ErrOutsideRoot
+	}
+	path = filepath.Clean(path)
+	rel, err := filepath.Rel(root, path)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", ErrOutsideRoot
+	}
+	return path, nil
+}
+
+// Scanned returns when the last scan finished.
+func (l *Library) Scanned() time.Time {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.scanned
+}
+
+// Scan walks every root and updates the index: new files are added, changed
+// files lose their hash and files that are gone are dropped. Files without a
+// hash are queued for the background hasher.
+func (l *Library) Scan() error {
+	l.scanMu.Lock()
+	defer l.scanMu.Unlock()
+
+	start := time.Now()
+	found := map[string]Entry{}
+	for _, kind := range l.kinds() {
+		root := l.roots[kind]
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == root {
+					return err
+				}
+				l.logger.Warn("walkdir error", "path", path, "err", err)
+				return nil
+			}
+			if d.IsDir() || !indexed(kind, d.Name()) {
+				return nil
+			}
+			// A root nested inside this one owns its own files.
+			if owner, _, _ := l.rootOf(path); owner != kind {
+				return nil
+			}
+			fi, err := d.Info()
+			if err != nil {
+				l.logger.Warn("stat failed", "path", path, "err", err)
+				return nil
+			}
+			found[path] = newEntry(kind, root, path, fi)
+			return nil
+		})
+		if errors.Is(err, fs.ErrNotExist) {
+			l.logger.Warn("library root missing", "kind", kind, "root", root)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("scan %s: %w", root, err)
+		}
+	}
+
+	l.mu.Lock()
+	var changed []Entry
+	var removed []string
+	var changes []Change
+	for path, e := range found {
+		old, ok := l.entries[path]
+		if ok && old.Size == e.Size && old.ModTime.Equal(e.ModTime) {
+			e.SHA256 = old.SHA256
+			e.Verified = old.Verified
+		}
+		switch {
+		case !ok:
+			changes = append(changes, Change{ChangeAdded, e})
+		case !old.equal(e):
+			changes = append(changes, Change{ChangeChanged, e})
+		}
+		if !ok || !old.equal(e) {
+			changed = append(changed, e)
+		}
+		found[path] = e
+	}
+	for path, old := range l.entries {
+		if _, ok := found[path]; !ok {
+			removed = append(removed, path)
+			changes = append(changes, Change{ChangeRemoved, old})
+		}
+	}
+	l.entries = found
+	l.scanned = time.Now()
+	l.mu.Unlock()
+
+	if err := l.persist(changed, removed); err != nil {
+		return err
+	}
+	l.notify(changes)
+	for _, e := range found {
+		if e.SHA256 == "" {
+			l.queueHash(e.Path)
+		}
+	}
+
+	l.logger.Info("library scanned", "entries", len(found), "changed", len(changed), "removed", len(removed), "dur", time.Since(start).String())
+	return nil
+}
+
+// MarkVerified indexes a file that was checked against its published hash
+// while it downloaded, so it shows up with its hash and the verified flag
+// right away rather than after the next scan and a second read of the file.
+func (l *Library) MarkVerified(path, sha256 string) error {
+	path = filepath.Clean(path)
+	kind, root, ok := l.rootOf(path)
+	if !ok {
+		return ErrOutsideRoot
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	e := newEntry(kind, root, path, fi)
+	e.SHA256 = sha256
+	e.Verified = true
+
+	l.mu.Lock()
+	old, had := l.entries[path]
+	l.entries[path] = e
+	l.mu.Unlock()
+	if had && old.equal(e) {
+		return nil
+	}
+
+	if err := l.persist([]Entry{e}, nil); err != nil {
+		return err
+	}
+	change := Change{ChangeAdded, e}
+	if had {
+		change.Type = ChangeChanged
+	}
+	l.notify([]Change{change})
+	return nil
+}
+
+// rootOf finds the root path lies under. When roots are nested the deepest
+// one wins, and equal roots go to the kind listed first in Kinds.
+func (l *Library) rootOf(path string) (Kind, string, bool) {
+	var best Kind
+	for _, kind := range l.kinds() {
+		if _, err := l.Resolve(kind, path); err == nil && len(l.roots[kind]) > len(l.roots[best]) {
+			best = kind
+		}
+	}
+	if best == "" {
+		return "", "", false
+	}
+	return best, l.roots[best], true
+}
+
+// kinds returns the kinds that have a root, in Kinds order, so walks don't
+// depend on map order.
+func (l *Library) kinds() []Kind {
+	var out []Kind
+	for _, kind := range Kinds {
+		if _, ok := l.roots[kind]; ok {
+			out = append(out, kind)
+		}
+	}
+	return out
+}
+
+func (l *Library) persist(changed []Entry, removed []string) error {
+	if len(changed) == 0 && len(removed) == 0 {
+		return nil
+	}
+	return l.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketEntries)
+		for _, e := range changed {
+			v, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(e.Path), v); err != nil {
+				return err
+			}
+		}
+		for _, path := range removed {
+			if err := b.Delete([]byte(path)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (l *Library) Get(path string) (Entry, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	e, ok := l.entries[filepath.Clean(path)]
+	if !ok {
+		return Entry{}, ErrNotFound
+	}
+	return e, nil
+}
+
+// List returns matching entries in the requested order, along with the total
+// number of matches before pagination.
+func (l *Library) List(f Filter) ([]Entry, int) {
+	query := strings.ToLower(strings.TrimSpace(f.Query))
+
+	l.mu.RLock()
+	matched := make([]Entry, 0, len(l.entries))
+	for _, e := range l.entries {
+		if matches(e, f, query) {
+			matched = append(matched, e)
+		}
+	}
+	l.mu.RUnlock()
+
+	slices.SortFunc(matched, func(a, b Entry) int {
+		c := compare(a, b, f.Sort)
+		if c == 0 {
+			c = strings.Compare(a.Path, b.Path)
+		}
+		if f.Desc {
+			return -c
+		}
+		return c
+	})
+
+	total := len(matched)
+	if f.Offset >= total {
+		return []Entry{}, total
+	}
+	matched = matched[f.Offset:]
+	if f.Limit > 0 && len(matched) > f.Limit {
+		matched = matched[:f.Limit]
+	}
+	return matched, total
+}
+
+// ValidSort reports whether s is a supported sort order.
+func ValidSort(s string) bool {
+	switch s {
+	case "", SortName, SortSize, SortModified, SortBaseModel:
+		return true
+	}
+	return false
+}
+
+func compare(a, b Entry, sort string) int {
+	switch sort {
+	case SortSize:
+		return cmp.Compare(a.Size, b.Size)
+	case SortModified:
+		return a.ModTime.Compare(b.ModTime)
+	case SortBaseModel:
+		if c := strings.Compare(strings.ToLower(a.BaseModel), strings.ToLower(b.BaseModel)); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+}
+
+func matches(e Entry, f Filter, query string) bool {
+	if f.Kind != "" && e.Kind != f.Kind {
+		return false
+	}
+	if f.BaseModel != "" && !strings.EqualFold(e.BaseModel, f.BaseModel) {
+		return false
+	}
+	if query != "" &&
+		!strings.Contains(strings.ToLower(e.Name), query) &&
+		!strings.Contains(strings.ToLower(e.Path), query) {
+		return false
+	}
+	return true
+}
+
+func indexed(kind Kind, name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return slices.Contains(Extensions[kind], ext)
+}
+
+func newEntry(kind Kind, root, path string, fi fs.FileInfo) Entry {
+	e := Entry{
+		Path:    path,
+		Kind:    kind,
+		Name:    DisplayName(path),
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+	}
+	if rel, err := filepath.Rel(root, path); err == nil {
+		if dir, _, ok := strings.Cut(filepath.ToSlash(rel), "/"); ok {
+			e.BaseModel = dir
+		}
+	}
+	return e
+}
+
+// DisplayName turns a file name into something readable: the extension and
+// the model version prefix the downloader adds ("123-") are dropped and
+// dashes and underscores become spaces.
+func DisplayName(path string) string {
+	stem := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if prefix, rest, ok := strings.Cut(stem, "-"); ok && rest != "" && isDigits(prefix) {
+		stem = rest
+	}
+	stem = strings.NewReplacer("-", " ", "_", " ").Replace(stem)
+	if name := strings.Join(strings.Fields(stem), " "); name != "" {
+		return name
+	}
+	return filepath.Base(path)
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
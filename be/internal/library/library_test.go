@@ -0,0 +1,241 @@
+package library
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLibrary(t *testing.T) {
+	dir := t.TempDir()
+	models := filepath.Join(dir, "models")
+	loras := filepath.Join(dir, "loras")
+	writeFile(t, filepath.Join(models, "SDXL-1.0", "10-juggernaut-xl.safetensors"), "checkpoint")
+	writeFile(t, filepath.Join(models, "sd15.safetensors"), "ckpt")
+	writeFile(t, filepath.Join(loras, "SDXL-1.0", "123-ink_sketch.safetensors"), "ink")
+	writeFile(t, filepath.Join(loras, "Pony", "456-pastel.safetensors"), "pastel lora")
+	writeFile(t, filepath.Join(loras, "Pony", "notes.txt"), "ignored")
+
+	dbPath := filepath.Join(dir, "library.db")
+	roots := map[Kind]string{KindCheckpoint: models, KindLora: loras}
+	l, err := Open(dbPath, roots)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := l.Scan(); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	names := func(entries []Entry) string {
+		var out []string
+		for _, e := range entries {
+			out = append(out, e.Name)
+		}
+		return strings.Join(out, ",")
+	}
+
+	cases := map[string]struct {
+		filter Filter
+		want   string
+		total  int
+	}{
+		"all by name":      {Filter{}, "ink sketch,juggernaut xl,pastel,sd15", 4},
+		"checkpoints":      {Filter{Kind: KindCheckpoint}, "juggernaut xl,sd15", 2},
+		"base model":       {Filter{BaseModel: "sdxl-1.0"}, "ink sketch,juggernaut xl", 2},
+		"query":            {Filter{Query: "PAST"}, "pastel", 1},
+		"size desc":        {Filter{Kind: KindLora, Sort: SortSize, Desc: true}, "pastel,ink sketch", 2},
+		"base model order": {Filter{Kind: KindLora, Sort: SortBaseModel}, "pastel,ink sketch", 2},
+		"paged":            {Filter{Offset: 1, Limit: 2}, "juggernaut xl,pastel", 4},
+		"past the end":     {Filter{Offset: 9}, "", 4},
+	}
+	for name, tc := range cases {
+		got, total := l.List(tc.filter)
+		if names(got) != tc.want || total != tc.total {
+			t.Errorf("%s: got %q (total %d), want %q (total %d)", name, names(got), total, tc.want, tc.total)
+		}
+	}
+
+	lora := filepath.Join(loras, "SDXL-1.0", "123-ink_sketch.safetensors")
+	e, err := l.Get(lora)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if e.Kind != KindLora || e.BaseModel != "SDXL-1.0" || e.Size != 3 || e.SHA256 != "" {
+		t.Fatalf("entry = %+v", e)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	l.Run(ctx, 0)
+	want := sha256.Sum256([]byte("ink"))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if e, _ := l.Get(lora); e.SHA256 == hex.EncodeToString(want[:]) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("lora was never hashed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Hashes survive a reopen, and files that are gone drop out on the next
+	// scan.
+	if err := os.Remove(filepath.Join(models, "sd15.safetensors")); err != nil {
+		t.Fatal(err)
+	}
+	l, err = Open(dbPath, roots)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer l.Close()
+	var changes []Change
+	l.OnChange(func(c []Change) { changes = append(changes, c...) })
+	if err := l.Scan(); err != nil {
+		t.Fatalf("rescan: %v", err)
+	}
+	// Entries read back from the index are unchanged files, not changes.
+	if len(changes) != 1 || changes[0].Type != ChangeRemoved {
+		t.Fatalf("changes after reopen = %+v, want only the removal", changes)
+	}
+	if e, _ := l.Get(lora); e.SHA256 == "" {
+		t.Fatal("hash lost across reopen")
+	}
+	if _, err := l.Get(filepath.Join(models, "sd15.safetensors")); err != ErrNotFound {
+		t.Fatalf("removed file still indexed: %v", err)
+	}
+}
+
+func TestNestedRoots(t *testing.T) {
+	dir := t.TempDir()
+	models := filepath.Join(dir, "models")
+	loras := filepath.Join(models, "loras")
+	writeFile(t, filepath.Join(models, "SDXL-1.0", "10-juggernaut-xl.safetensors"), "checkpoint")
+	writeFile(t, filepath.Join(loras, "Pony", "456-pastel.safetensors"), "pastel lora")
+
+	l, err := Open(filepath.Join(dir, "library.db"), map[Kind]string{KindCheckpoint: models, KindLora: loras})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer l.Close()
+
+	// The checkpoint root contains the LoRA root; every scan has to file the
+	// LoRA under the deeper root however the roots are iterated.
+	lora := filepath.Join(loras, "Pony", "456-pastel.safetensors")
+	for i := 0; i < 20; i++ {
+		if err := l.Scan(); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		if _, total := l.List(Filter{}); total != 2 {
+			t.Fatalf("scan %d indexed %d entries, want 2", i, total)
+		}
+		e, err := l.Get(lora)
+		if err != nil || e.Kind != KindLora || e.BaseModel != "Pony" {
+			t.Fatalf("scan %d: lora = %+v, %v; want a Pony LoRA", i, e, err)
+		}
+	}
+
+	if kind, root, ok := l.rootOf(lora); !ok || kind != KindLora || root != loras {
+		t.Errorf("rootOf(lora) = %s, %s, %v", kind, root, ok)
+	}
+	if kind, _, ok := l.rootOf(filepath.Join(models, "x.safetensors")); !ok || kind != KindCheckpoint {
+		t.Errorf("rootOf(checkpoint) = %s, %v", kind, ok)
+	}
+	if _, _, ok := l.rootOf(filepath.Join(dir, "elsewhere.safetensors")); ok {
+		t.Error("rootOf matched a path outside every root")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	loras := filepath.Join(dir, "loras")
+	writeFile(t, filepath.Join(loras, "old.safetensors"), "old")
+
+	l, err := Open(filepath.Join(dir, "library.db"), map[Kind]string{KindLora: loras})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan Change, 16)
+	l.OnChange(func(changes []Change) {
+		for _, c := range changes {
+			if c.Type != ChangeChanged {
+				events <- c
+			}
+		}
+	})
+	if err := l.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if c := <-events; c.Type != ChangeAdded || c.Entry.Name != "old" {
+		t.Fatalf("initial scan: %+v", c)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		l.Close()
+	}()
+	if err := l.Watch(ctx, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	next := func() Change {
+		t.Helper()
+		select {
+		case c := <-events:
+			return c
+		case <-time.After(5 * time.Second):
+			t.Fatal("no library event")
+			return Change{}
+		}
+	}
+
+	// A download finishing: the .part file is ignored, the rename is not.
+	// The new folder is watched as soon as it is created.
+	part := filepath.Join(loras, "SDXL 1.0", "new.safetensors.part")
+	writeFile(t, part, "new")
+	if err := os.Rename(part, strings.TrimSuffix(part, ".part")); err != nil {
+		t.Fatal(err)
+	}
+	if c := next(); c.Type != ChangeAdded || c.Entry.BaseModel != "SDXL 1.0" {
+		t.Fatalf("after download: %+v", c)
+	}
+
+	if err := os.Remove(filepath.Join(loras, "old.safetensors")); err != nil {
+		t.Fatal(err)
+	}
+	if c := next(); c.Type != ChangeRemoved || c.Entry.Name != "old" {
+		t.Fatalf("after remove: %+v", c)
+	}
+}
+
+func TestDisplayName(t *testing.T) {
+	for in, want := range map[string]string{
+		"/m/123-ink_sketch.safetensors": "ink sketch",
+		"/m/sdxl.safetensors":           "sdxl",
+		"/m/v2-model.safetensors":       "v2 model",
+		"/m/123-.safetensors":           "123",
+	} {
+		if got := DisplayName(in); got != want {
+			t.Errorf("DisplayName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch rescans the roots shortly after files are added, removed or
+// rewritten, instead of waiting for the next periodic scan. Events are
+// debounced: a rescan runs once the roots have been quiet for debounce, so a
+// multi-GB copy or a burst of moves costs one scan. fsnotify isn't
+// recursive, so every folder below a root is watched and folders created
+// later are picked up as they appear.
+func (l *Library) Watch(ctx context.Context, debounce time.Duration) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("error creating library watcher: %w", err)
+	}
+
+	dirs := map[string]struct{}{}
+	for _, kind := range l.kinds() {
+		root := l.roots[kind]
+		if err := l.watchTree(w, dirs, root); err != nil {
+			l.logger.Warn("library root not watched", "kind", kind, "root", root, "err", err)
+		}
+	}
+	l.logger.Info("library watching", "dirs", len(dirs), "debounce", debounce.String())
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		defer w.Close()
+		l.watchLoop(ctx, w, dirs, debounce)
+	}()
+	return nil
+}
+
+func (l *Library) watchLoop(ctx context.Context, w *fsnotify.Watcher, dirs map[string]struct{}, debounce time.Duration) {
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if !l.relevant(w, dirs, ev) {
+				continue
+			}
+			l.logger.Debug("library event", "op", ev.Op.String(), "path", ev.Name)
+			timer.Reset(debounce)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			l.logger.Warn("library watcher error", "err", err)
+		case <-timer.C:
+			if err := l.Scan(); err != nil {
+				l.logger.Error("library scan failed", "err", err)
+			}
+		}
+	}
+}
+
+// relevant reports whether ev may change the index. Downloads in progress
+// (.part files) and other non-model files are ignored; new folders are
+// watched and count as a change since files may have been moved in with
+// them.
+func (l *Library) relevant(w *fsnotify.Watcher, dirs map[string]struct{}, ev fsnotify.Event) bool {
+	if ev.Op == fsnotify.Chmod {
+		return false
+	}
+	if _, ok := dirs[ev.Name]; ok && ev.Has(fsnotify.Remove|fsnotify.Rename) {
+		delete(dirs, ev.Name)
+		_ = w.Remove(ev.Name)
+		return true
+	}
+	if ev.Has(fsnotify.Create) {
+		if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+			if err := l.watchTree(w, dirs, ev.Name); err != nil {
+				l.logger.Warn("watch failed", "path", ev.Name, "err", err)
+			}
+			return true
+		}
+	}
+	kind, _, ok := l.rootOf(ev.Name)
+	return ok && indexed(kind, ev.Name)
+}
+
+func (l *Library) watchTree(w *fsnotify.Watcher, dirs map[string]struct{}, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if _, ok := dirs[path]; ok {
+			return nil
+		}
+		if err := w.Add(path); err != nil {
+			l.logger.Warn("watch failed", "path", path, "err", err)
+			return nil
+		}
+		dirs[path] = struct{}{}
+		return nil
+	})
+}
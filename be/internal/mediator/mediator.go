@@ -3,6 +3,10 @@ package mediator
 import (
 	"be/config"
 	"be/internal/dependencies"
+	"be/internal/history"
+	"be/internal/imaging"
+	"be/internal/library"
+	"be/internal/modelhash"
 	"be/internal/services"
 	"context"
 	"fmt"
@@ -15,45 +19,101 @@ import (
 )
 
 type App struct {
-	api *services.Api
-	rpc *dependencies.Rpc
+	api     *services.Api
+	workers *dependencies.Pool
 
 	hub *services.Hub
 	dl  *services.DownloaderService
+	gen *services.GenerationService
 
-	ctx    context.Context
-	cancel context.CancelFunc
+	history *history.Store
+	library *library.Library
+
+	rescan   time.Duration
+	debounce time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
 	// settings
 	Config *config.Config
 }
 
 func NewApp(config config.Config) (*App, error) {
-	log.Info("app init", "component", "mediator", "env", config.Env, "apiPort", config.Api.Port, "rpcPeer", config.Rpc.Peer, "rpcPort", config.Rpc.Port)
+	log.Info("app init", "component", "mediator", "env", config.Env, "apiPort", config.Api.Port, "rpcPeer", config.Rpc.Peer, "rpcPeers", config.Rpc.Peers, "rpcPort", config.Rpc.Port)
+
+	peers := dependencies.ParsePeers(config.Rpc.Peers, config.Rpc.Peer, config.Rpc.Port)
+	workers, err := dependencies.NewPool(peers, time.Duration(config.Rpc.HealthCheckSeconds)*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("error creating newapp: %w", err)
+	}
+
+	hist, err := history.Open(config.Api.History.DbPath, config.Api.History.OutputsDir)
+	if err != nil {
+		workers.Close()
+		return nil, fmt.Errorf("error creating newapp: %w", err)
+	}
+
+	thumbs, err := imaging.NewThumbCache(config.Api.History.ThumbsDir)
+	if err != nil {
+		hist.Close()
+		workers.Close()
+		return nil, fmt.Errorf("error creating newapp: %w", err)
+	}
 
-	rpc, err := dependencies.NewRpc(config.Rpc.Peer, config.Rpc.Port)
+	lib, err := library.Open(config.Api.Library.IndexPath, map[library.Kind]string{
+		library.KindCheckpoint: config.Api.Library.ModelsDir,
+		library.KindLora:       config.Api.Library.LorasDir,
+		library.KindEmbedding:  config.Api.Library.EmbeddingsDir,
+		library.KindVAE:        config.Api.Library.VaesDir,
+		library.KindControlNet: config.Api.Library.ControlnetsDir,
+		library.KindUpscaler:   config.Api.Library.UpscalersDir,
+		library.KindLycoris:    config.Api.Library.LycorisDir,
+	})
 	if err != nil {
+		hist.Close()
+		workers.Close()
 		return nil, fmt.Errorf("error creating newapp: %w", err)
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	hub := services.NewHub()
-	dl := services.NewDownloaderService(hub, config.Api.Dl, ctx)
-	api := services.NewApi(rpc, config.Api, hub, dl)
+	hashes := modelhash.NewCache()
+	dl := services.NewDownloaderService(hub, lib, config.Api.Dl, ctx)
+	gen := services.NewGenerationService(hub, workers, hist, hashes, config.Api.Gen, ctx)
+	gen.CheckLoras(services.NewLoraCompat(lib, dl))
+	api := services.NewApi(workers, config.Api, hub, dl, gen, hist, thumbs, lib)
+	events := services.LibraryEvents(hub)
+	lib.OnChange(func(changes []library.Change) {
+		dl.LibraryChanged(changes)
+		events(changes)
+	})
 
 	return &App{
-		api:    api,
-		rpc:    rpc,
-		hub:    hub,
-		dl:     dl,
-		ctx:    ctx,
-		cancel: cancel,
+		api:      api,
+		workers:  workers,
+		hub:      hub,
+		dl:       dl,
+		gen:      gen,
+		history:  hist,
+		library:  lib,
+		rescan:   time.Duration(config.Api.Library.RescanSeconds) * time.Second,
+		debounce: time.Duration(config.Api.Library.WatchDebounceMs) * time.Millisecond,
+		ctx:      ctx,
+		cancel:   cancel,
 	}, nil
 }
 
 func (a *App) Run() error {
 	log.Info("app run", "component", "mediator")
+	a.workers.Run(a.ctx)
 	a.dl.Run()
+	a.gen.Run()
+	a.library.Run(a.ctx, a.rescan)
+	if a.debounce > 0 {
+		if err := a.library.Watch(a.ctx, a.debounce); err != nil {
+			log.Error("library watch failed; relying on rescans", "component", "mediator", "err", err)
+		}
+	}
 
 	errCh := make(chan error, 1)
 	go func() {
@@ -85,6 +145,8 @@ func (a *App) Shutdown() {
 	a.cancel()
 	log.Info("downloader shutdown", "component", "mediator")
 	a.dl.Shutdown()
+	log.Info("generator shutdown", "component", "mediator")
+	a.gen.Shutdown()
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	log.Info("api shutdown", "component", "mediator")
@@ -93,7 +155,15 @@ func (a *App) Shutdown() {
 
 	log.Info("hub shutdown", "component", "mediator")
 	a.hub.Shutdown()
-	log.Info("rpc close", "component", "mediator")
-	a.rpc.Close()
+	log.Info("workers close", "component", "mediator")
+	a.workers.Close()
+	log.Info("history close", "component", "mediator")
+	if err := a.history.Close(); err != nil {
+		log.Error("history close failed", "component", "mediator", "err", err)
+	}
+	log.Info("library close", "component", "mediator")
+	if err := a.library.Close(); err != nil {
+		log.Error("library close failed", "component", "mediator", "err", err)
+	}
 	log.Info("shutdown complete", "component", "mediator")
 }
@@ -0,0 +1,274 @@
+// Package metadata keeps the model host's description of every downloaded
+// file in a JSON sidecar next to it, so trigger words, base model and
+// description are available without a network round trip per request.
+package metadata
+
+import (
+	"be/internal/clients/huggingface"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// SidecarExt is appended to the model file's name to name its sidecar:
+// 123-ink.safetensors => 123-ink.safetensors.json. Keeping the extension
+// stops files that share a stem (ink.safetensors, ink.pt) from sharing a
+// sidecar.
+const SidecarExt = ".json"
+
+// retryDelay keeps a failing lookup (an unknown version, the host being
+// down) from being retried on every request.
+const retryDelay = 15 * time.Minute
+
+// Fetcher looks up a model version on the model host.
+type Fetcher interface {
+	GetModelVersionInfo(id string) (huggingface.ModelVersionIdResponse, error)
+}
+
+type cached struct {
+	info    huggingface.ModelVersionIdResponse
+	fetched time.Time // sidecar modification time
+}
+
+// Store serves sidecars from memory. Lookups never wait for the network:
+// sidecars older than the TTL, and files downloaded before sidecars existed,
+// are (re)fetched in the background and show up on a later lookup.
+type Store struct {
+	fetcher Fetcher
+	ttl     time.Duration
+	logger  *log.Logger
+
+	mu    sync.RWMutex
+	cache map[string]cached // model path => metadata
+
+	pendingMu sync.Mutex
+	pending   []string
+	queued    map[string]struct{}
+	failed    map[string]time.Time
+	wake      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewStore returns a store that refreshes sidecars older than ttl through
+// fetcher. A ttl of 0 never refreshes existing sidecars.
+func NewStore(fetcher Fetcher, ttl time.Duration) *Store {
+	return &Store{
+		fetcher: fetcher,
+		ttl:     ttl,
+		logger:  log.With("component", "metadata"),
+		cache:   map[string]cached{},
+		queued:  map[string]struct{}{},
+		failed:  map[string]time.Time{},
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// SidecarPath returns where the metadata for the model file at path lives.
+func SidecarPath(path string) string {
+	return path + SidecarExt
+}
+
+// VersionID returns the model version ID the downloader prefixes file names
+// with ("123-ink.safetensors"), if there is one.
+func VersionID(path string) (int64, bool) {
+	prefix, _, ok := strings.Cut(filepath.Base(path), "-")
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// Run refreshes queued sidecars one at a time until ctx is cancelled.
+func (s *Store) Run(ctx context.Context) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			path, ok := s.next()
+			if !ok {
+				select {
+				case <-ctx.Done():
+					return
+				case <-s.wake:
+					continue
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			err := s.refresh(path)
+			s.pendingMu.Lock()
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				s.failed[path] = time.Now()
+			} else {
+				delete(s.failed, path)
+			}
+			s.pendingMu.Unlock()
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				s.logger.Warn("metadata refresh failed", "path", path, "err", err)
+			}
+		}
+	}()
+}
+
+// Wait blocks until the refresh loop has stopped; cancel the context passed
+// to Run first.
+func (s *Store) Wait() {
+	s.wg.Wait()
+}
+
+// Lookup returns the metadata for the model file at path.
+func (s *Store) Lookup(path string) (huggingface.ModelVersionIdResponse, bool) {
+	path = filepath.Clean(path)
+
+	s.mu.RLock()
+	c, ok := s.cache[path]
+	s.mu.RUnlock()
+
+	if !ok {
+		var err error
+		c, err = readSidecar(SidecarPath(path))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			if _, known := VersionID(path); known {
+				s.queue(path)
+			}
+			return huggingface.ModelVersionIdResponse{}, false
+		case err != nil:
+			s.logger.Warn("sidecar unreadable", "path", path, "err", err)
+			return huggingface.ModelVersionIdResponse{}, false
+		}
+		s.mu.Lock()
+		s.cache[path] = c
+		s.mu.Unlock()
+	}
+
+	if s.ttl > 0 && time.Since(c.fetched) > s.ttl {
+		s.queue(path)
+	}
+	return c.info, true
+}
+
+// Forget drops what the store remembers about the model file at path, for
+// files that left the library. The sidecar itself is left alone.
+func (s *Store) Forget(path string) {
+	path = filepath.Clean(path)
+
+	s.mu.Lock()
+	delete(s.cache, path)
+	s.mu.Unlock()
+
+	s.pendingMu.Lock()
+	delete(s.failed, path)
+	s.pendingMu.Unlock()
+}
+
+// Write stores info as the metadata for the model file at path.
+func (s *Store) Write(path string, info huggingface.ModelVersionIdResponse) error {
+	path = filepath.Clean(path)
+	b, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding sidecar: %w", err)
+	}
+
+	sidecar := SidecarPath(path)
+	tmp := sidecar + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("error writing sidecar: %w", err)
+	}
+	if err := os.Rename(tmp, sidecar); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("error writing sidecar: %w", err)
+	}
+
+	s.mu.Lock()
+	s.cache[path] = cached{info: info, fetched: time.Now()}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Store) refresh(path string) error {
+	id, ok := VersionID(path)
+	if !ok {
+		// Not named by the downloader; refresh by the version the sidecar
+		// says it is, if it says.
+		s.mu.RLock()
+		id = s.cache[path].info.Id
+		s.mu.RUnlock()
+		if id <= 0 {
+			return nil
+		}
+	}
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	info, err := s.fetcher.GetModelVersionInfo(strconv.FormatInt(id, 10))
+	if err != nil {
+		return err
+	}
+	if err := s.Write(path, info); err != nil {
+		return err
+	}
+	s.logger.Debug("metadata refreshed", "path", path, "modelVersionId", id)
+	return nil
+}
+
+func (s *Store) queue(path string) {
+	s.pendingMu.Lock()
+	if t, ok := s.failed[path]; ok && time.Since(t) < retryDelay {
+		s.pendingMu.Unlock()
+		return
+	}
+	if _, ok := s.queued[path]; !ok {
+		s.queued[path] = struct{}{}
+		s.pending = append(s.pending, path)
+	}
+	s.pendingMu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Store) next() (string, bool) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	if len(s.pending) == 0 {
+		return "", false
+	}
+	path := s.pending[0]
+	s.pending = s.pending[1:]
+	delete(s.queued, path)
+	return path, true
+}
+
+func readSidecar(path string) (cached, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return cached{}, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cached{}, err
+	}
+	var info huggingface.ModelVersionIdResponse
+	if err := json.Unmarshal(b, &info); err != nil {
+		return cached{}, err
+	}
+	return cached{info: info, fetched: fi.ModTime()}, nil
+}
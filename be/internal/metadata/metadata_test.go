@@ -0,0 +1,150 @@
+package metadata
+
+import (
+	"be/internal/clients/huggingface"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeFetcher struct {
+	calls atomic.Int32
+	words []string
+}
+
+func (f *fakeFetcher) GetModelVersionInfo(id string) (huggingface.ModelVersionIdResponse, error) {
+	f.calls.Add(1)
+	if id != "123" {
+		return huggingface.ModelVersionIdResponse{}, errors.New("unknown version")
+	}
+	return huggingface.ModelVersionIdResponse{Id: 123, BaseModel: "SDXL 1.0", TrainedWords: f.words}, nil
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	lora := filepath.Join(dir, "123-ink.safetensors")
+	if err := os.WriteFile(lora, []byte("weights"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fetcher := &fakeFetcher{words: []string{"ink"}}
+	s := NewStore(fetcher, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		s.Wait()
+	}()
+	s.Run(ctx)
+
+	// No sidecar yet: nothing now, fetched in the background.
+	if _, ok := s.Lookup(lora); ok {
+		t.Fatal("metadata without a sidecar")
+	}
+	waitFor(t, func() bool {
+		info, ok := s.Lookup(lora)
+		return ok && info.BaseModel == "SDXL 1.0"
+	})
+	if _, err := os.Stat(filepath.Join(dir, "123-ink.safetensors.json")); err != nil {
+		t.Fatalf("sidecar: %v", err)
+	}
+
+	// A fresh sidecar is served without asking the host again; a stale one
+	// is served while it is refreshed.
+	calls := fetcher.calls.Load()
+	s.Lookup(lora)
+	if fetcher.calls.Load() != calls {
+		t.Fatal("fresh sidecar refetched")
+	}
+	fetcher.words = []string{"ink", "sketch"}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(SidecarPath(lora), old, old); err != nil {
+		t.Fatal(err)
+	}
+	s = NewStore(fetcher, time.Hour)
+	s.Run(ctx)
+	if info, ok := s.Lookup(lora); !ok || len(info.TrainedWords) != 1 {
+		t.Fatalf("stale lookup = %+v, %v; want the old sidecar", info, ok)
+	}
+	waitFor(t, func() bool {
+		info, _ := s.Lookup(lora)
+		return len(info.TrainedWords) == 2
+	})
+
+	// Unknown versions are not retried on every lookup.
+	other := filepath.Join(dir, "456-other.safetensors")
+	if err := os.WriteFile(other, []byte("weights"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s.Lookup(other)
+	waitFor(t, func() bool {
+		s.pendingMu.Lock()
+		defer s.pendingMu.Unlock()
+		_, failed := s.failed[other]
+		return failed
+	})
+	calls = fetcher.calls.Load()
+	s.Lookup(other)
+	time.Sleep(50 * time.Millisecond)
+	if fetcher.calls.Load() != calls {
+		t.Fatal("failed lookup retried immediately")
+	}
+}
+
+func TestSidecarPerFile(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(&fakeFetcher{}, 0)
+
+	// Files that only differ by extension keep separate sidecars.
+	lora := filepath.Join(dir, "ink.safetensors")
+	embedding := filepath.Join(dir, "ink.pt")
+	if err := s.Write(lora, huggingface.ModelVersionIdResponse{Id: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(embedding, huggingface.ModelVersionIdResponse{Id: 2}); err != nil {
+		t.Fatal(err)
+	}
+	s = NewStore(&fakeFetcher{}, 0)
+	for path, want := range map[string]int64{lora: 1, embedding: 2} {
+		if info, ok := s.Lookup(path); !ok || info.Id != want {
+			t.Errorf("Lookup(%s) = %d, %v; want %d", filepath.Base(path), info.Id, ok, want)
+		}
+	}
+
+	// Forget drops the cached entry for a removed file.
+	s.Forget(lora)
+	s.mu.RLock()
+	_, cached := s.cache[lora]
+	n := len(s.cache)
+	s.mu.RUnlock()
+	if cached || n != 1 {
+		t.Errorf("after Forget: lora cached = %v, %d entries; want only the embedding", cached, n)
+	}
+}
+
+func TestVersionID(t *testing.T) {
+	for path, want := range map[string]int64{
+		"/loras/123-ink.safetensors":   123,
+		"/loras/ink.safetensors":       0,
+		"/loras/v2-model.safetensors":  0,
+		"/loras/-12-model.safetensors": 0,
+	} {
+		if got, _ := VersionID(path); got != want {
+			t.Errorf("VersionID(%q) = %d, want %d", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package modelhash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// Cache keeps SHA256 sums of model files keyed by path. An entry is only
+// trusted while the file's size and modification time are unchanged, so a
+// replaced file gets hashed again.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]entry
+	pending map[string]struct{}
+	logger  *log.Logger
+}
+
+type entry struct {
+	size    int64
+	modTime time.Time
+	sum     string
+}
+
+func NewCache() *Cache {
+	return &Cache{
+		entries: map[string]entry{},
+		pending: map[string]struct{}{},
+		logger:  log.With("component", "modelhash"),
+	}
+}
+
+// Lookup returns the cached sum without blocking. Checkpoints are several
+// GB, so on a miss the file is hashed in the background and Lookup reports
+// false until that finishes.
+func (c *Cache) Lookup(path string) (string, bool) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[path]; ok && e.size == fi.Size() && e.modTime.Equal(fi.ModTime()) {
+		return e.sum, true
+	}
+	if _, ok := c.pending[path]; !ok {
+		c.pending[path] = struct{}{}
+		go func() {
+			if _, err := c.Sum(path); err != nil {
+				c.logger.Warn("hash failed", "path", path, "err", err)
+			}
+		}()
+	}
+	return "", false
+}
+
+// Sum returns the file's SHA256 as lowercase hex, hashing it if needed.
+func (c *Cache) Sum(path string) (string, error) {
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, path)
+		c.mu.Unlock()
+	}()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	e, ok := c.entries[path]
+	c.mu.Unlock()
+	if ok && e.size == fi.Size() && e.modTime.Equal(fi.ModTime()) {
+		return e.sum, nil
+	}
+
+	start := time.Now()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	c.mu.Lock()
+	c.entries[path] = entry{size: fi.Size(), modTime: fi.ModTime(), sum: sum}
+	c.mu.Unlock()
+	c.logger.Debug("hashed", "path", path, "bytes", fi.Size(), "dur", time.Since(start).String())
+	return sum, nil
+}
+
+// Short returns the first 10 hex characters of a SHA256 sum, the "AutoV2"
+// form A1111 writes as "Model hash" and Civitai indexes.
+func Short(sum string) string {
+	if len(sum) < 10 {
+		return sum
+	}
+	return sum[:10]
+}
@@ -0,0 +1,342 @@
+package safetensors
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Arch is the model family a file belongs to. Files of different families
+// can't be combined.
+type Arch string
+
+const (
+	ArchUnknown Arch = ""
+	ArchSD1     Arch = "sd1"
+	ArchSD2     Arch = "sd2"
+	ArchSDXL    Arch = "sdxl"
+	ArchSD3     Arch = "sd3"
+	ArchFlux    Arch = "flux"
+)
+
+// Type is what the file is used for.
+type Type string
+
+const (
+	TypeUnknown    Type = ""
+	TypeCheckpoint Type = "checkpoint"
+	TypeLora       Type = "lora"
+	TypeVAE        Type = "vae"
+	TypeEmbedding  Type = "embedding"
+	TypeControlNet Type = "controlnet"
+)
+
+// maxTags caps how many training tags Info reports.
+const maxTags = 100
+
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// Training holds the kohya sd-scripts fields (ss_*) LoRA trainers write into
+// the metadata.
+type Training struct {
+	BaseModel        string // ss_sd_model_name: the checkpoint it was trained on
+	BaseModelVersion string // ss_base_model_version, e.g. sdxl_base_v1-0
+	NetworkModule    string
+	NetworkDim       int
+	NetworkAlpha     float64
+	OutputName       string
+	Resolution       string
+	Epochs           int
+	Steps            int
+	Comment          string
+	// Tags are the captions' tags across all datasets, most frequent first.
+	Tags []TagCount
+}
+
+type Info struct {
+	Type      Type
+	Arch      Arch
+	Precision string // most common tensor dtype
+	Tensors   int
+	Header    int64
+	Metadata  map[string]string
+	Training  *Training
+}
+
+// InspectFile reads the header of the file at path and describes it.
+func InspectFile(path string) (Info, error) {
+	h, err := ReadFile(path)
+	if err != nil {
+		return Info{}, err
+	}
+	return Inspect(h), nil
+}
+
+// Inspect infers type and architecture from tensor names and shapes, falling
+// back to what the metadata claims when the tensors are inconclusive.
+func Inspect(h Header) Info {
+	info := Info{
+		Precision: precision(h),
+		Tensors:   len(h.Tensors),
+		Header:    h.Size,
+		Metadata:  h.Metadata,
+		Training:  training(h.Metadata),
+	}
+	info.Type, info.Arch = fromTensors(h)
+
+	metaType, metaArch := fromMetadata(h.Metadata)
+	if info.Type == TypeUnknown {
+		info.Type = metaType
+	}
+	if info.Arch == ArchUnknown {
+		info.Arch = metaArch
+	}
+	return info
+}
+
+func fromTensors(h Header) (Type, Arch) {
+	var (
+		lora, diffusion, vae, control bool
+		sdxl, flux, sd3               bool
+	)
+	for name := range h.Tensors {
+		switch {
+		case isLoraKey(name):
+			lora = true
+		case strings.HasPrefix(name, "model.diffusion_model."):
+			diffusion = true
+		case strings.HasPrefix(name, "encoder.conv_in."), strings.HasPrefix(name, "decoder.conv_in."):
+			vae = true
+		case strings.Contains(name, "input_hint_block."), strings.HasPrefix(name, "controlnet_cond_embedding."):
+			control = true
+		}
+		switch {
+		case strings.HasPrefix(name, "conditioner.embedders.1."), strings.HasPrefix(name, "lora_te2_"), strings.HasPrefix(name, "lora_te1_"):
+			sdxl = true
+		case strings.Contains(name, "double_blocks"), strings.Contains(name, "single_transformer_blocks"):
+			flux = true
+		case strings.Contains(name, "joint_blocks"):
+			sd3 = true
+		}
+	}
+
+	typ := TypeUnknown
+	switch {
+	case lora:
+		typ = TypeLora
+	case control:
+		typ = TypeControlNet
+	case diffusion || flux || sd3:
+		typ = TypeCheckpoint
+	case vae:
+		return TypeVAE, ArchUnknown // SD1, SD2 and SDXL VAEs share a layout
+	}
+	if typ == TypeUnknown {
+		if t, ok := h.Tensors["emb_params"]; ok && len(t.Shape) == 2 {
+			return TypeEmbedding, archForContext(t.Shape[1])
+		}
+		if _, ok := h.Tensors["clip_g"]; ok {
+			return TypeEmbedding, ArchSDXL
+		}
+		if len(h.Tensors) == 0 {
+			return TypeUnknown, ArchUnknown
+		}
+	}
+
+	switch {
+	case flux:
+		return typ, ArchFlux
+	case sd3:
+		return typ, ArchSD3
+	case sdxl:
+		return typ, ArchSDXL
+	}
+	return typ, archForContext(crossAttentionDim(h))
+}
+
+func isLoraKey(name string) bool {
+	for _, marker := range []string{".lora_down.", ".lora_up.", ".lora_A.", ".lora_B.", ".lora.down.", ".lora.up.", ".hada_w1_", ".lokr_w1"} {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return strings.HasPrefix(name, "lora_unet_") || strings.HasPrefix(name, "lora_te_")
+}
+
+// crossAttentionDim returns the text encoder width the UNet attends to: the
+// input side of any attn2 key projection. It is 768 for SD1, 1024 for SD2
+// and 2048 for SDXL, in checkpoints and LoRAs alike.
+func crossAttentionDim(h Header) int64 {
+	for name, t := range h.Tensors {
+		if len(t.Shape) != 2 {
+			continue
+		}
+		flat := strings.ReplaceAll(name, ".", "_")
+		if !strings.Contains(flat, "attn2_to_k") {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(name, ".to_k.weight"),
+			strings.Contains(name, "lora_down"), strings.Contains(name, "lora_A"), strings.Contains(name, "lora.down"):
+			return t.Shape[1]
+		}
+	}
+	return 0
+}
+
+func archForContext(dim int64) Arch {
+	switch dim {
+	case 768:
+		return ArchSD1
+	case 1024:
+		return ArchSD2
+	case 2048:
+		return ArchSDXL
+	}
+	return ArchUnknown
+}
+
+// fromMetadata reads the modelspec architecture ("stable-diffusion-xl-v1-base/lora")
+// or kohya's ss_base_model_version ("sdxl_base_v1-0").
+func fromMetadata(meta map[string]string) (Type, Arch) {
+	typ := TypeUnknown
+	if spec := strings.ToLower(meta["modelspec.architecture"]); spec != "" {
+		base, kind, _ := strings.Cut(spec, "/")
+		switch kind {
+		case "lora":
+			typ = TypeLora
+		case "textual-inversion":
+			typ = TypeEmbedding
+		case "":
+			typ = TypeCheckpoint
+		}
+		if arch := ArchFromName(base); arch != ArchUnknown {
+			return typ, arch
+		}
+	}
+	if _, ok := meta["ss_network_module"]; ok {
+		typ = TypeLora
+	}
+	if v := meta["ss_base_model_version"]; v != "" {
+		return typ, ArchFromName(v)
+	}
+	if meta["ss_v2"] == "True" {
+		return typ, ArchSD2
+	}
+	return typ, ArchUnknown
+}
+
+// ArchFromName maps a base model name to its family. It understands the
+// modelspec and kohya spellings ("stable-diffusion-xl-v1-base", "sd_v1") as
+// well as the model hub's ("SD 1.5", "SDXL 1.0", "Pony", "Flux.1 D").
+func ArchFromName(name string) Arch {
+	compact := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '_', '.':
+			return -1
+		}
+		return r
+	}, strings.ToLower(name))
+	has := func(subs ...string) bool {
+		for _, s := range subs {
+			if strings.Contains(compact, s) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case has("flux"):
+		return ArchFlux
+	case has("xl", "pony", "illustrious", "noobai"):
+		return ArchSDXL
+	case has("sd3", "v3", "diffusion3"):
+		return ArchSD3
+	case has("sd2", "v2", "diffusion2"):
+		return ArchSD2
+	case has("sd1", "v1", "diffusion1"):
+		return ArchSD1
+	}
+	return ArchUnknown
+}
+
+func precision(h Header) string {
+	counts := map[string]int{}
+	for _, t := range h.Tensors {
+		counts[t.Dtype]++
+	}
+	best, n := "", 0
+	for dtype, c := range counts {
+		if c > n || (c == n && dtype < best) {
+			best, n = dtype, c
+		}
+	}
+	return best
+}
+
+func training(meta map[string]string) *Training {
+	found := false
+	for k := range meta {
+		if strings.HasPrefix(k, "ss_") {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	t := &Training{
+		BaseModel:        meta["ss_sd_model_name"],
+		BaseModelVersion: meta["ss_base_model_version"],
+		NetworkModule:    meta["ss_network_module"],
+		OutputName:       meta["ss_output_name"],
+		Resolution:       meta["ss_resolution"],
+		Comment:          meta["ss_training_comment"],
+		Tags:             tagFrequency(meta["ss_tag_frequency"]),
+	}
+	t.NetworkDim, _ = strconv.Atoi(meta["ss_network_dim"])
+	t.NetworkAlpha, _ = strconv.ParseFloat(meta["ss_network_alpha"], 64)
+	t.Epochs, _ = strconv.Atoi(meta["ss_num_epochs"])
+	t.Steps, _ = strconv.Atoi(meta["ss_max_train_steps"])
+	return t
+}
+
+// tagFrequency merges kohya's per-dataset tag counts
+// ({"10_dataset": {"tag": 12}}) into one list.
+func tagFrequency(raw string) []TagCount {
+	if raw == "" {
+		return nil
+	}
+	var datasets map[string]map[string]int
+	if err := json.Unmarshal([]byte(raw), &datasets); err != nil {
+		return nil
+	}
+	merged := map[string]int{}
+	for _, tags := range datasets {
+		for tag, n := range tags {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				merged[tag] += n
+			}
+		}
+	}
+	out := make([]TagCount, 0, len(merged))
+	for tag, n := range merged {
+		out = append(out, TagCount{Tag: tag, Count: n})
+	}
+	slices.SortFunc(out, func(a, b TagCount) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Tag, b.Tag)
+	})
+	if len(out) > maxTags {
+		out = out[:maxTags]
+	}
+	return out
+}
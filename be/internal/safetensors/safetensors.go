@@ -0,0 +1,83 @@
+// Package safetensors reads the JSON header of .safetensors files without
+// touching the tensor data, which is enough to tell what a file is.
+package safetensors
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// MaxHeaderSize is the largest header the format allows.
+const MaxHeaderSize = 100 << 20
+
+var ErrInvalidHeader = errors.New("invalid safetensors header")
+
+type Tensor struct {
+	Dtype       string   `json:"dtype"`
+	Shape       []int64  `json:"shape"`
+	DataOffsets [2]int64 `json:"data_offsets"`
+}
+
+// Header is the parsed file header: the free-form string metadata and the
+// layout of every tensor.
+type Header struct {
+	Size     int64 // header length in bytes, excluding the length prefix
+	Metadata map[string]string
+	Tensors  map[string]Tensor
+}
+
+// ReadFile reads the header of the file at path.
+func ReadFile(path string) (Header, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Header{}, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read reads a header from the start of r: a little-endian uint64 length
+// followed by that many bytes of JSON.
+func Read(r io.Reader) (Header, error) {
+	var n uint64
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return Header{}, fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+	if n < 2 || n > MaxHeaderSize {
+		return Header{}, fmt.Errorf("%w: header length %d", ErrInvalidHeader, n)
+	}
+
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return Header{}, fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &raw); err != nil {
+		return Header{}, fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+
+	h := Header{
+		Size:     int64(n),
+		Metadata: map[string]string{},
+		Tensors:  make(map[string]Tensor, len(raw)),
+	}
+	for name, v := range raw {
+		if name == "__metadata__" {
+			if err := json.Unmarshal(v, &h.Metadata); err != nil {
+				return Header{}, fmt.Errorf("%w: __metadata__: %v", ErrInvalidHeader, err)
+			}
+			continue
+		}
+		var t Tensor
+		if err := json.Unmarshal(v, &t); err != nil {
+			return Header{}, fmt.Errorf("%w: tensor %s: %v", ErrInvalidHeader, name, err)
+		}
+		h.Tensors[name] = t
+	}
+	return h, nil
+}
@@ -0,0 +1,199 @@
+package safetensors
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// encode builds a file with the given tensors (name => shape) and metadata.
+func encode(t *testing.T, tensors map[string][]int64, meta map[string]string) []byte {
+	t.Helper()
+	header := map[string]any{}
+	if meta != nil {
+		header["__metadata__"] = meta
+	}
+	for name, shape := range tensors {
+		header[name] = map[string]any{"dtype": "F16", "shape": shape, "data_offsets": []int64{0, 0}}
+	}
+	js, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint64(len(js)))
+	buf.Write(js)
+	buf.WriteString("tensor bytes that must not be read")
+	return buf.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	h, err := Read(bytes.NewReader(encode(t, map[string][]int64{"a": {2, 3}}, map[string]string{"format": "pt"})))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if h.Metadata["format"] != "pt" || len(h.Tensors) != 1 || h.Tensors["a"].Shape[1] != 3 || h.Tensors["a"].Dtype != "F16" {
+		t.Fatalf("header = %+v", h)
+	}
+
+	for name, b := range map[string][]byte{
+		"short":     {1, 2},
+		"too long":  binary.LittleEndian.AppendUint64(nil, MaxHeaderSize+1),
+		"truncated": append(binary.LittleEndian.AppendUint64(nil, 100), "{}"...),
+		"not json":  append(binary.LittleEndian.AppendUint64(nil, 3), "abc"...),
+	} {
+		if _, err := Read(bytes.NewReader(b)); !errors.Is(err, ErrInvalidHeader) {
+			t.Errorf("%s: err = %v, want ErrInvalidHeader", name, err)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	cases := map[string]struct {
+		tensors map[string][]int64
+		meta    map[string]string
+		typ     Type
+		arch    Arch
+	}{
+		"sdxl checkpoint": {
+			tensors: map[string][]int64{
+				"model.diffusion_model.input_blocks.4.1.transformer_blocks.0.attn2.to_k.weight": {640, 2048},
+				"conditioner.embedders.1.model.ln_final.weight":                                 {1280},
+			},
+			typ: TypeCheckpoint, arch: ArchSDXL,
+		},
+		"sd15 checkpoint": {
+			tensors: map[string][]int64{
+				"model.diffusion_model.input_blocks.1.1.transformer_blocks.0.attn2.to_k.weight": {320, 768},
+				"cond_stage_model.transformer.text_model.final_layer_norm.weight":               {768},
+			},
+			typ: TypeCheckpoint, arch: ArchSD1,
+		},
+		"sd2 checkpoint": {
+			tensors: map[string][]int64{
+				"model.diffusion_model.input_blocks.1.1.transformer_blocks.0.attn2.to_k.weight": {320, 1024},
+			},
+			typ: TypeCheckpoint, arch: ArchSD2,
+		},
+		"sd15 lora": {
+			tensors: map[string][]int64{
+				"lora_unet_down_blocks_0_attentions_0_transformer_blocks_0_attn2_to_k.lora_down.weight": {16, 768},
+				"lora_te_text_model_encoder_layers_0_mlp_fc1.lora_up.weight":                            {3072, 16},
+			},
+			typ: TypeLora, arch: ArchSD1,
+		},
+		"sdxl lora": {
+			tensors: map[string][]int64{
+				"lora_unet_input_blocks_4_1_transformer_blocks_0_attn2_to_k.lora_down.weight": {32, 2048},
+				"lora_te2_text_model_encoder_layers_0_mlp_fc1.lora_up.weight":                 {5120, 32},
+			},
+			typ: TypeLora, arch: ArchSDXL,
+		},
+		"diffusers sdxl lora": {
+			tensors: map[string][]int64{
+				"unet.down_blocks.1.attentions.0.transformer_blocks.0.attn2.to_k.lora_A.weight": {8, 2048},
+			},
+			typ: TypeLora, arch: ArchSDXL,
+		},
+		"flux lora": {
+			tensors: map[string][]int64{
+				"lora_unet_double_blocks_0_img_attn_qkv.lora_down.weight": {16, 3072},
+			},
+			typ: TypeLora, arch: ArchFlux,
+		},
+		"vae": {
+			tensors: map[string][]int64{
+				"encoder.conv_in.weight": {128, 3, 3, 3},
+				"decoder.conv_in.weight": {512, 4, 3, 3},
+			},
+			typ: TypeVAE, arch: ArchUnknown,
+		},
+		"sd15 embedding": {
+			tensors: map[string][]int64{"emb_params": {4, 768}},
+			typ:     TypeEmbedding, arch: ArchSD1,
+		},
+		"sdxl embedding": {
+			tensors: map[string][]int64{"clip_l": {4, 768}, "clip_g": {4, 1280}},
+			typ:     TypeEmbedding, arch: ArchSDXL,
+		},
+		"controlnet": {
+			tensors: map[string][]int64{
+				"control_model.input_hint_block.0.weight":                               {16, 3, 3, 3},
+				"control_model.input_blocks.1.1.transformer_blocks.0.attn2.to_k.weight": {320, 768},
+			},
+			typ: TypeControlNet, arch: ArchSD1,
+		},
+		"metadata only": {
+			tensors: map[string][]int64{"lora_unet_mid_block_attentions_0_proj_in.alpha": {}},
+			meta:    map[string]string{"modelspec.architecture": "stable-diffusion-xl-v1-base/lora"},
+			typ:     TypeLora, arch: ArchSDXL,
+		},
+		"kohya version": {
+			tensors: map[string][]int64{"lora_unet_mid_block_attentions_0_proj_in.alpha": {}},
+			meta:    map[string]string{"ss_base_model_version": "sd_v1", "ss_network_module": "networks.lora"},
+			typ:     TypeLora, arch: ArchSD1,
+		},
+	}
+	for name, tc := range cases {
+		h, err := Read(bytes.NewReader(encode(t, tc.tensors, tc.meta)))
+		if err != nil {
+			t.Fatalf("%s: read: %v", name, err)
+		}
+		info := Inspect(h)
+		if info.Type != tc.typ || info.Arch != tc.arch {
+			t.Errorf("%s: got %q/%q, want %q/%q", name, info.Type, info.Arch, tc.typ, tc.arch)
+		}
+	}
+}
+
+func TestInspectTraining(t *testing.T) {
+	meta := map[string]string{
+		"ss_sd_model_name":      "sd_xl_base_1.0.safetensors",
+		"ss_base_model_version": "sdxl_base_v1-0",
+		"ss_network_module":     "networks.lora",
+		"ss_network_dim":        "32",
+		"ss_network_alpha":      "16.0",
+		"ss_num_epochs":         "10",
+		"ss_tag_frequency":      `{"10_ink": {"ink": 12, "sketch": 4}, "5_extra": {"sketch": 9, " ": 3}}`,
+	}
+	h, err := Read(bytes.NewReader(encode(t, map[string][]int64{"lora_te1_x.lora_down.weight": {32, 768}}, meta)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := Inspect(h).Training
+	if tr == nil {
+		t.Fatal("no training info")
+	}
+	if tr.BaseModel != "sd_xl_base_1.0.safetensors" || tr.NetworkDim != 32 || tr.NetworkAlpha != 16 || tr.Epochs != 10 {
+		t.Fatalf("training = %+v", tr)
+	}
+	if len(tr.Tags) != 2 || tr.Tags[0] != (TagCount{"sketch", 13}) || tr.Tags[1] != (TagCount{"ink", 12}) {
+		t.Fatalf("tags = %+v", tr.Tags)
+	}
+
+	if Inspect(Header{Metadata: map[string]string{"format": "pt"}}).Training != nil {
+		t.Fatal("training info without ss_ fields")
+	}
+}
+
+func TestArchFromName(t *testing.T) {
+	for name, want := range map[string]Arch{
+		"SD 1.5":                      ArchSD1,
+		"sd_v1":                       ArchSD1,
+		"SD 2.1 768":                  ArchSD2,
+		"SDXL 1.0":                    ArchSDXL,
+		"SDXL-1.0":                    ArchSDXL,
+		"stable-diffusion-xl-v1-base": ArchSDXL,
+		"Pony":                        ArchSDXL,
+		"Illustrious":                 ArchSDXL,
+		"SD 3.5 Large":                ArchSD3,
+		"Flux.1 D":                    ArchFlux,
+		"Other":                       ArchUnknown,
+	} {
+		if got := ArchFromName(name); got != want {
+			t.Errorf("ArchFromName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -3,6 +3,9 @@ package services
 import (
 	"be/config"
 	"be/internal/dependencies"
+	"be/internal/history"
+	"be/internal/imaging"
+	"be/internal/library"
 	"context"
 	"fmt"
 
@@ -13,28 +16,39 @@ import (
 
 type Api struct {
 	server         *fiber.App
-	rpc            *dependencies.Rpc
+	workers        *dependencies.Pool
 	port           string
 	allowedOrigins string
 	hub            *Hub
 	dl             *DownloaderService
+	gen            *GenerationService
+	history        *history.Store
+	thumbs         *imaging.ThumbCache
+	library        *library.Library
+	compat         *LoraCompat
 	logger         *log.Logger
 }
 
-func NewApi(rpc *dependencies.Rpc, config config.ApiConfig, hub *Hub, dl *DownloaderService) *Api {
+func NewApi(workers *dependencies.Pool, config config.ApiConfig, hub *Hub, dl *DownloaderService, gen *GenerationService, history *history.Store, thumbs *imaging.ThumbCache, library *library.Library) *Api {
 	if config.AllowedOrigins == "" {
 		config.AllowedOrigins = "*"
 	}
 
-	return &Api{
-		server:         fiber.New(),
-		rpc:            rpc,
+	a := &Api{
+		workers:        workers,
 		port:           config.Port,
 		allowedOrigins: config.AllowedOrigins,
 		hub:            hub,
 		dl:             dl,
+		gen:            gen,
+		history:        history,
+		thumbs:         thumbs,
+		library:        library,
+		compat:         NewLoraCompat(library, dl),
 		logger:         log.With("component", "api"),
 	}
+	a.server = fiber.New(fiber.Config{ErrorHandler: a.errorHandler})
+	return a
 }
 
 func (a *Api) Start() error {
@@ -46,8 +60,9 @@ func (a *Api) Start() error {
 	a.server.Use(cors.New(cors.Config{
 		AllowOrigins:     a.allowedOrigins,
 		AllowCredentials: allowCredentials,
-		AllowMethods:     "GET,POST,OPTIONS",
-		AllowHeaders:     "Content-Type,Authorization,Accept,Origin",
+		AllowMethods:     "GET,POST,DELETE,OPTIONS",
+		AllowHeaders:     "Content-Type,Authorization,Accept,Origin," + HeaderAdminToken,
+		ExposeHeaders:    "X-Request-Id,X-Seed,X-History-Id",
 	}))
 
 	a.addRoutes()
@@ -76,16 +91,35 @@ func (a *Api) Shutdown(ctx context.Context) error {
 
 func (a *Api) addRoutes() {
 	a.server.Add("GET", "/health", a.Health())
-	a.server.Add("POST", "/generateimage", a.GenerateImage())
+	a.server.Add("POST", "/generateimage", a.RequireWorker(), a.GenerateImage())
+	a.server.Add("POST", "/generations", a.RequireWorker(), a.EnqueueGeneration())
+	a.server.Add("GET", "/generations/:id", a.GetGeneration())
+	a.server.Add("GET", "/history", a.ListHistory())
+	a.server.Add("GET", "/history/:id", a.GetHistory())
+	a.server.Add("GET", "/history/:id/image", a.GetHistoryImage())
+	a.server.Add("DELETE", "/history/:id", a.DeleteHistory())
+	a.server.Add("GET", "/images/:id/thumb", a.Thumbnail())
 	a.server.Add("GET", "/models", a.ListModels())
+	a.server.Add("GET", "/models/info", a.ModelInfo())
 	a.server.Add("GET", "/loras", a.ListLoras())
-	a.server.Add("POST", "/setmodel", a.SetModel())
-	a.server.Add("POST", "/setloras", a.SetLoras())
-	a.server.Add("GET", "/currentmodel", a.CurrentModel())
-	a.server.Add("GET", "/currentloras", a.CurrentLoras())
-	a.server.Add("POST", "/clearmodel", a.ClearModel())
-	a.server.Add("POST", "/clearloras", a.ClearLoras())
+	a.server.Add("GET", "/loras/info", a.LoraInfo())
+	a.server.Add("GET", "/embeddings", a.ListKind(library.KindEmbedding))
+	a.server.Add("GET", "/vaes", a.ListKind(library.KindVAE))
+	a.server.Add("GET", "/controlnets", a.ListKind(library.KindControlNet))
+	a.server.Add("GET", "/upscalers", a.ListKind(library.KindUpscaler))
+	a.server.Add("GET", "/lycoris", a.ListKind(library.KindLycoris))
+	a.server.Add("GET", "/library", a.ListLibrary())
+	a.server.Add("GET", "/library/updates", a.LibraryUpdates())
+	a.server.Add("POST", "/library/upgrade", a.UpgradeModel())
+	a.server.Add("POST", "/setmodel", a.RequireWorker(), a.SetModel())
+	a.server.Add("POST", "/setloras", a.RequireWorker(), a.SetLoras())
+	a.server.Add("GET", "/currentmodel", a.RequireWorker(), a.CurrentModel())
+	a.server.Add("GET", "/currentloras", a.RequireWorker(), a.CurrentLoras())
+	a.server.Add("POST", "/clearmodel", a.RequireWorker(), a.ClearModel())
+	a.server.Add("POST", "/clearloras", a.RequireWorker(), a.ClearLoras())
 	a.server.Add("POST", "/download", a.DownloadModel())
+	a.server.Add("GET", "/catalog/search", a.CatalogSearch())
+	a.server.Add("GET", "/catalog/versions/:id/files", a.CatalogVersionFiles())
 
 	// websocket connection
 	a.server.Use("/ws", a.WsUpgrade())
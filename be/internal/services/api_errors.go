@@ -0,0 +1,228 @@
+package services
+
+import (
+	"be/internal/clients/huggingface"
+	"be/internal/dependencies"
+	"be/internal/history"
+	"be/internal/library"
+	"be/internal/safetensors"
+	"be/types"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+
+	"github.com/gofiber/fiber/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorEnvelopeVersion is bumped whenever the shape of types.ErrorResponse
+// changes in a way clients have to know about.
+const errorEnvelopeVersion = 1
+
+// statusClientClosedRequest is the nginx convention for a request the client
+// gave up on; net/http has no name for it.
+const statusClientClosedRequest = 499
+
+// Error codes are part of the API contract: clients branch on them, so the
+// values never change once published.
+const (
+	CodeCanceled           = "canceled"
+	CodeInvalidArgument    = "invalid_argument"
+	CodeDeadlineExceeded   = "deadline_exceeded"
+	CodeNotFound           = "not_found"
+	CodeAlreadyExists      = "already_exists"
+	CodePermissionDenied   = "permission_denied"
+	CodeResourceExhausted  = "resource_exhausted"
+	CodeFailedPrecondition = "failed_precondition"
+	CodeAborted            = "aborted"
+	CodeOutOfRange         = "out_of_range"
+	CodeUnimplemented      = "unimplemented"
+	CodeInternal           = "internal"
+	CodeUnavailable        = "unavailable"
+	CodeUnauthenticated    = "unauthenticated"
+)
+
+// errorClass is how a failure is reported over HTTP.
+type errorClass struct {
+	status    int
+	code      string
+	retryable bool
+}
+
+var (
+	classCanceled           = errorClass{statusClientClosedRequest, CodeCanceled, true}
+	classInvalidArgument    = errorClass{fiber.StatusBadRequest, CodeInvalidArgument, false}
+	classDeadlineExceeded   = errorClass{fiber.StatusGatewayTimeout, CodeDeadlineExceeded, true}
+	classNotFound           = errorClass{fiber.StatusNotFound, CodeNotFound, false}
+	classAlreadyExists      = errorClass{fiber.StatusConflict, CodeAlreadyExists, false}
+	classPermissionDenied   = errorClass{fiber.StatusForbidden, CodePermissionDenied, false}
+	classResourceExhausted  = errorClass{fiber.StatusTooManyRequests, CodeResourceExhausted, true}
+	classFailedPrecondition = errorClass{fiber.StatusBadRequest, CodeFailedPrecondition, false}
+	classAborted            = errorClass{fiber.StatusConflict, CodeAborted, true}
+	classOutOfRange         = errorClass{fiber.StatusBadRequest, CodeOutOfRange, false}
+	classUnimplemented      = errorClass{fiber.StatusNotImplemented, CodeUnimplemented, false}
+	classInternal           = errorClass{fiber.StatusInternalServerError, CodeInternal, false}
+	classUnavailable        = errorClass{fiber.StatusServiceUnavailable, CodeUnavailable, true}
+	classUnauthenticated    = errorClass{fiber.StatusUnauthorized, CodeUnauthenticated, false}
+)
+
+var rpcErrorClasses = map[codes.Code]errorClass{
+	codes.Canceled:           classCanceled,
+	codes.Unknown:            classInternal,
+	codes.InvalidArgument:    classInvalidArgument,
+	codes.DeadlineExceeded:   classDeadlineExceeded,
+	codes.NotFound:           classNotFound,
+	codes.AlreadyExists:      classAlreadyExists,
+	codes.PermissionDenied:   classPermissionDenied,
+	codes.ResourceExhausted:  classResourceExhausted,
+	codes.FailedPrecondition: classFailedPrecondition,
+	codes.Aborted:            classAborted,
+	codes.OutOfRange:         classOutOfRange,
+	codes.Unimplemented:      classUnimplemented,
+	codes.Internal:           classInternal,
+	codes.Unavailable:        classUnavailable,
+	codes.DataLoss:           classInternal,
+	codes.Unauthenticated:    classUnauthenticated,
+}
+
+// apiError carries the class for a failure the API detected itself, such as
+// a malformed body, so it is reported like the equivalent worker error.
+type apiError struct {
+	class errorClass
+	err   error
+}
+
+func (e apiError) Error() string { return e.err.Error() }
+func (e apiError) Unwrap() error { return e.err }
+
+func invalidArgument(err error) error  { return apiError{classInvalidArgument, err} }
+func notFound(err error) error         { return apiError{classNotFound, err} }
+func internalError(err error) error    { return apiError{classInternal, err} }
+func permissionDenied(err error) error { return apiError{classPermissionDenied, err} }
+func unavailable(err error) error      { return apiError{classUnavailable, err} }
+
+// detailedError is implemented by errors that report more than a message;
+// the details end up in the envelope as is.
+type detailedError interface {
+	error
+	ErrorDetails() any
+}
+
+// classifyError maps err to its HTTP class and returns the text to report.
+// For worker errors that is the status description alone, without the
+// "rpc error: code = ..." prefix.
+func classifyError(err error) (errorClass, string) {
+	var ae apiError
+	if errors.As(err, &ae) {
+		return ae.class, ae.err.Error()
+	}
+
+	var se interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &se) {
+		st := se.GRPCStatus()
+		class, ok := rpcErrorClasses[st.Code()]
+		if !ok {
+			class = classInternal
+		}
+		return class, st.Message()
+	}
+
+	var perr InvalidParamsError
+	var lerr IncompatibleLorasError
+	switch {
+	case errors.As(err, &lerr):
+		return classFailedPrecondition, err.Error()
+	case errors.As(err, &perr),
+		errors.Is(err, library.ErrOutsideRoot),
+		errors.Is(err, safetensors.ErrInvalidHeader):
+		return classInvalidArgument, err.Error()
+	case errors.Is(err, dependencies.ErrNoHealthyWorkers),
+		errors.Is(err, ErrGenerationShuttingDown),
+		errors.Is(err, ErrDownloaderShuttingDown),
+		errors.Is(err, ErrNoModelLookups),
+		errors.Is(err, ErrCatalogSearchDisabled):
+		return classUnavailable, err.Error()
+	case errors.Is(err, ErrGenerationQueueFull), errors.Is(err, ErrDownloadQueueFull):
+		return classResourceExhausted, err.Error()
+	case errors.Is(err, ErrGenerationNotFound),
+		errors.Is(err, history.ErrNotFound),
+		errors.Is(err, library.ErrNotFound),
+		errors.Is(err, ErrNoUpdate),
+		errors.Is(err, ErrNoMatchingVersion),
+		errors.Is(err, huggingface.ErrNotFound),
+		errors.Is(err, fs.ErrNotExist):
+		return classNotFound, err.Error()
+	case errors.Is(err, context.DeadlineExceeded):
+		return classDeadlineExceeded, err.Error()
+	case errors.Is(err, context.Canceled):
+		return classCanceled, err.Error()
+	}
+	return classInternal, err.Error()
+}
+
+// fail sends the error envelope for err. Retryable 503 and 429 replies carry
+// a Retry-After of one health check interval, by which time a worker that
+// came back is in rotation again.
+func (a *Api) fail(ctx *fiber.Ctx, err error, message string) error {
+	class, detail := classifyError(err)
+	if class.status == fiber.StatusServiceUnavailable || class.status == fiber.StatusTooManyRequests {
+		ctx.Set(fiber.HeaderRetryAfter, fmt.Sprint(a.retryAfter()))
+	}
+	resp := types.ErrorResponse{
+		Version:   errorEnvelopeVersion,
+		Code:      class.code,
+		Error:     detail,
+		Message:   message,
+		RequestID: ReqID(ctx),
+		Retryable: class.retryable,
+	}
+	var de detailedError
+	if errors.As(err, &de) {
+		resp.Details = de.ErrorDetails()
+	}
+	return ctx.Status(class.status).JSON(resp)
+}
+
+func (a *Api) retryAfter() int {
+	if a.workers == nil {
+		return 10
+	}
+	return int(math.Ceil(a.workers.Interval().Seconds()))
+}
+
+// errorHandler renders errors returned from handlers and fiber's own
+// failures (unknown routes, bad methods) in the same envelope.
+func (a *Api) errorHandler(ctx *fiber.Ctx, err error) error {
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		class := classForStatus(fe.Code)
+		return a.fail(ctx, apiError{class, err}, fe.Message)
+	}
+	return a.fail(ctx, err, "request failed")
+}
+
+// classForStatus picks the class for a bare HTTP status, keeping the status
+// itself even when no class uses it.
+func classForStatus(code int) errorClass {
+	switch code {
+	case fiber.StatusNotFound:
+		return classNotFound
+	case fiber.StatusUnauthorized:
+		return classUnauthenticated
+	case fiber.StatusForbidden:
+		return classPermissionDenied
+	case fiber.StatusTooManyRequests:
+		return classResourceExhausted
+	case fiber.StatusServiceUnavailable:
+		return classUnavailable
+	case fiber.StatusGatewayTimeout, fiber.StatusRequestTimeout:
+		return errorClass{code, CodeDeadlineExceeded, true}
+	}
+	if code >= fiber.StatusInternalServerError {
+		return errorClass{code, CodeInternal, false}
+	}
+	return errorClass{code, CodeInvalidArgument, false}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"be/internal/clients/huggingface"
+	"be/internal/clients/transport"
+	"be/internal/dependencies"
+	"be/internal/history"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+		retry  bool
+		detail string
+	}{
+		{"unavailable", status.Error(codes.Unavailable, "connection refused"), http.StatusServiceUnavailable, CodeUnavailable, true, "connection refused"},
+		{"deadline", status.Error(codes.DeadlineExceeded, "too slow"), http.StatusGatewayTimeout, CodeDeadlineExceeded, true, "too slow"},
+		{"not found", status.Error(codes.NotFound, "Model not found: x"), http.StatusNotFound, CodeNotFound, false, "Model not found: x"},
+		{"invalid", status.Error(codes.InvalidArgument, "bad scheduler"), http.StatusBadRequest, CodeInvalidArgument, false, "bad scheduler"},
+		{"exhausted", status.Error(codes.ResourceExhausted, "out of memory"), http.StatusTooManyRequests, CodeResourceExhausted, true, "out of memory"},
+		{"wrapped rpc", fmt.Errorf("worker a: %w", status.Error(codes.FailedPrecondition, "no model")), http.StatusBadRequest, CodeFailedPrecondition, false, "no model"},
+		{"no workers", dependencies.ErrNoHealthyWorkers, http.StatusServiceUnavailable, CodeUnavailable, true, dependencies.ErrNoHealthyWorkers.Error()},
+		{"queue full", ErrGenerationQueueFull, http.StatusTooManyRequests, CodeResourceExhausted, true, ErrGenerationQueueFull.Error()},
+		{"history", history.ErrNotFound, http.StatusNotFound, CodeNotFound, false, history.ErrNotFound.Error()},
+		{"params", InvalidParamsError{"steps", "too many"}, http.StatusBadRequest, CodeInvalidArgument, false, "steps: too many"},
+		{"context", context.DeadlineExceeded, http.StatusGatewayTimeout, CodeDeadlineExceeded, true, context.DeadlineExceeded.Error()},
+		{"api", invalidArgument(errors.New("clientId is required")), http.StatusBadRequest, CodeInvalidArgument, false, "clientId is required"},
+		{"plain", errors.New("boom"), http.StatusInternalServerError, CodeInternal, false, "boom"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			class, detail := classifyError(tc.err)
+			if class.status != tc.status || class.code != tc.code || class.retryable != tc.retry || detail != tc.detail {
+				t.Fatalf("classifyError(%v) = %+v %q, want %d %s retryable=%v %q", tc.err, class, detail, tc.status, tc.code, tc.retry, tc.detail)
+			}
+		})
+	}
+}
+
+func TestHostError(t *testing.T) {
+	reply := func(code int) error {
+		return fmt.Errorf("search: %w", &transport.StatusError{URL: "http://host/search", Status: http.StatusText(code), Code: code})
+	}
+	cases := []struct {
+		name string
+		err  error
+		code string
+	}{
+		{"not found", fmt.Errorf("%w: model version 9", huggingface.ErrNotFound), CodeNotFound},
+		{"no search url", ErrCatalogSearchDisabled, CodeUnavailable},
+		{"transport", errors.New("dial tcp: connection refused"), CodeUnavailable},
+		{"bad gateway", reply(http.StatusBadGateway), CodeUnavailable},
+		{"bad request", reply(http.StatusBadRequest), CodeInvalidArgument},
+		{"404", reply(http.StatusNotFound), CodeNotFound},
+		{"rate limited", reply(http.StatusTooManyRequests), CodeResourceExhausted},
+		{"bad key", reply(http.StatusUnauthorized), CodePermissionDenied},
+	}
+	for _, tc := range cases {
+		if class, _ := classifyError(hostError(tc.err)); class.code != tc.code {
+			t.Errorf("%s: code = %s, want %s", tc.name, class.code, tc.code)
+		}
+	}
+}
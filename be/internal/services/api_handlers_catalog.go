@@ -0,0 +1,114 @@
+package services
+
+import (
+	"be/internal/clients/huggingface"
+	"be/internal/clients/transport"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const defaultSearchLimit = 20
+
+// CatalogSearch proxies the model host's model search, so a version can be
+// found and downloaded without leaving the app.
+func (a *Api) CatalogSearch() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		logger := HttpLogger("CatalogSearch", ctx)
+		if a.dl == nil {
+			logger.Error("downloader not configured")
+			return a.fail(ctx, unavailable(errors.New("downloader not configured")), "service unavailable")
+		}
+
+		params, err := searchParams(ctx)
+		if err != nil {
+			logger.Warn("invalid search", "err", err)
+			return a.fail(ctx, invalidArgument(err), "invalid search")
+		}
+		res, err := a.dl.Search(params)
+		if err != nil {
+			logger.Warn("catalog search failed", "err", err)
+			return a.fail(ctx, hostError(err), "catalog search failed")
+		}
+		logger.Debug("catalog search", "query", params.Query, "returned", len(res.Items), "nextCursor", res.NextCursor)
+		return ctx.Status(fiber.StatusOK).JSON(res)
+	}
+}
+
+// CatalogVersionFiles lists the files of a model version, so a download can
+// pick them by ID.
+func (a *Api) CatalogVersionFiles() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		logger := HttpLogger("CatalogVersionFiles", ctx)
+		if a.dl == nil {
+			logger.Error("downloader not configured")
+			return a.fail(ctx, unavailable(errors.New("downloader not configured")), "service unavailable")
+		}
+
+		id, err := ctx.ParamsInt("id")
+		if err != nil || id <= 0 {
+			logger.Warn("invalid model version id", "id", ctx.Params("id"))
+			return a.fail(ctx, invalidArgument(errors.New("id must be a model version id")), "invalid id")
+		}
+		files, err := a.dl.VersionFiles(int64(id))
+		if err != nil {
+			logger.Warn("list version files failed", "modelVersionId", id, "err", err)
+			return a.fail(ctx, hostError(err), "failed to list files")
+		}
+		logger.Debug("list version files", "modelVersionId", id, "files", len(files.Files))
+		return ctx.Status(fiber.StatusOK).JSON(files)
+	}
+}
+
+// hostError classifies a failed model host call. Not found and the host's
+// other 4xx replies keep their meaning; transport errors and 5xx replies are
+// unavailable, since a retry may succeed.
+func hostError(err error) error {
+	var se *transport.StatusError
+	switch {
+	case errors.Is(err, huggingface.ErrNotFound), errors.Is(err, ErrCatalogSearchDisabled):
+		return err
+	case !errors.As(err, &se) || se.Code >= http.StatusInternalServerError:
+		return unavailable(err)
+	case se.Code == http.StatusNotFound:
+		return notFound(err)
+	case se.Code == http.StatusTooManyRequests:
+		return apiError{classResourceExhausted, err}
+	case se.Code == http.StatusUnauthorized, se.Code == http.StatusForbidden:
+		return permissionDenied(err)
+	}
+	return invalidArgument(err)
+}
+
+func searchParams(ctx *fiber.Ctx) (huggingface.SearchParams, error) {
+	p := huggingface.SearchParams{
+		Query:     strings.TrimSpace(ctx.Query("q")),
+		BaseModel: strings.TrimSpace(ctx.Query("baseModel")),
+		Cursor:    strings.TrimSpace(ctx.Query("cursor")),
+		Sort:      strings.TrimSpace(ctx.Query("sort")),
+		Limit:     ctx.QueryInt("limit", defaultSearchLimit),
+		NsfwLevel: ctx.QueryInt("nsfwLevel", 0),
+	}
+	switch t := strings.TrimSpace(ctx.Query("type")); {
+	case t == "":
+	case strings.EqualFold(t, huggingface.TypeCheckpoint):
+		p.Type = huggingface.TypeCheckpoint
+	case strings.EqualFold(t, huggingface.TypeLora):
+		p.Type = huggingface.TypeLora
+	default:
+		return p, fmt.Errorf("type must be %s or %s", huggingface.TypeCheckpoint, huggingface.TypeLora)
+	}
+	if _, ok := huggingface.SearchSorts[p.Sort]; p.Sort != "" && !ok {
+		return p, errors.New("sort must be rating, downloads or newest")
+	}
+	if p.NsfwLevel < 0 || p.NsfwLevel > 31 {
+		return p, errors.New("nsfwLevel must be between 0 and 31")
+	}
+	if p.Limit < 1 || p.Limit > huggingface.MaxSearchLimit {
+		return p, fmt.Errorf("limit must be between 1 and %d", huggingface.MaxSearchLimit)
+	}
+	return p, nil
+}
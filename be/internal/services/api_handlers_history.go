@@ -0,0 +1,219 @@
+package services
+
+import (
+	"be/internal/history"
+	"be/types"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+func (a *Api) ListHistory() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		logger := HttpLogger("ListHistory", ctx)
+		if a.history == nil {
+			logger.Error("history not configured")
+			return a.fail(ctx, internalError(errors.New("history not configured")), "service unavailable")
+		}
+
+		filter, err := historyFilter(ctx)
+		if err != nil {
+			logger.Warn("invalid history query", "err", err)
+			return a.fail(ctx, invalidArgument(err), "invalid query")
+		}
+
+		records, total, err := a.history.List(filter)
+		if err != nil {
+			logger.Error("list history failed", "err", err)
+			return a.fail(ctx, internalError(err), "failed to list history")
+		}
+
+		items := make([]types.HistoryEntry, 0, len(records))
+		for _, rec := range records {
+			items = append(items, historyEntry(rec))
+		}
+
+		logger.Debug("list history", "total", total, "returned", len(items))
+		return ctx.Status(fiber.StatusOK).JSON(types.HistoryListResponse{
+			Items:  items,
+			Total:  total,
+			Offset: filter.Offset,
+			Limit:  filter.Limit,
+		})
+	}
+}
+
+func (a *Api) GetHistory() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		logger := HttpLogger("GetHistory", ctx)
+		if a.history == nil {
+			logger.Error("history not configured")
+			return a.fail(ctx, internalError(errors.New("history not configured")), "service unavailable")
+		}
+
+		id := strings.TrimSpace(ctx.Params("id"))
+		rec, err := a.history.Get(id)
+		if err != nil {
+			return a.historyLookupError(ctx, logger, id, err)
+		}
+		return ctx.Status(fiber.StatusOK).JSON(historyEntry(rec))
+	}
+}
+
+func (a *Api) GetHistoryImage() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		logger := HttpLogger("GetHistoryImage", ctx)
+		if a.history == nil {
+			logger.Error("history not configured")
+			return a.fail(ctx, internalError(errors.New("history not configured")), "service unavailable")
+		}
+
+		out, err := parseImageOutput(ctx, "")
+		if err != nil {
+			logger.Warn("invalid output params", "err", err)
+			return a.fail(ctx, invalidArgument(err), "invalid query")
+		}
+
+		id := strings.TrimSpace(ctx.Params("id"))
+		rec, err := a.history.Get(id)
+		if err != nil {
+			return a.historyLookupError(ctx, logger, id, err)
+		}
+
+		index := ctx.QueryInt("index", 0)
+		path, err := a.history.ImagePath(rec, index)
+		if err != nil {
+			logger.Warn("history image index out of range", "id", id, "index", index, "images", len(rec.Images))
+			return a.fail(ctx, notFound(err), "unknown history image")
+		}
+
+		img, err := os.ReadFile(path)
+		if err != nil {
+			logger.Error("history image read failed", "id", id, "index", index, "err", err)
+			return a.fail(ctx, internalError(err), "failed to read history image")
+		}
+
+		logger.Debug("history image fetched", "id", id, "index", index, "bytes", len(img))
+		ctx.Set("X-Seed", strconv.FormatInt(rec.Params.Seed+int64(index), 10))
+		if err := out.send(ctx, img, rec.MimeType, filepath.Base(path)); err != nil {
+			logger.Error("render image failed", "id", id, "err", err)
+			return a.fail(ctx, internalError(err), "failed to render image")
+		}
+		return nil
+	}
+}
+
+func (a *Api) DeleteHistory() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		logger := HttpLogger("DeleteHistory", ctx)
+		if a.history == nil {
+			logger.Error("history not configured")
+			return a.fail(ctx, internalError(errors.New("history not configured")), "service unavailable")
+		}
+
+		id := strings.TrimSpace(ctx.Params("id"))
+		if _, err := a.history.Delete(id); err != nil {
+			return a.historyLookupError(ctx, logger, id, err)
+		}
+		if a.thumbs != nil {
+			if err := a.thumbs.RemovePrefix(id + "-"); err != nil {
+				logger.Warn("thumbnail cleanup failed", "id", id, "err", err)
+			}
+		}
+
+		logger.Info("history deleted", "id", id)
+		return ctx.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+func (a *Api) historyLookupError(ctx *fiber.Ctx, logger *log.Logger, id string, err error) error {
+	if errors.Is(err, history.ErrNotFound) {
+		logger.Warn("history lookup failed", "id", id, "err", err)
+		return a.fail(ctx, err, "unknown history entry")
+	}
+	logger.Warn("history read failed", "id", id, "err", err)
+	return a.fail(ctx, internalError(err), "failed to read history")
+}
+
+// historyFilter parses the list query. Dates accept RFC 3339 timestamps or
+// plain YYYY-MM-DD days; a plain "to" day includes the whole day.
+func historyFilter(ctx *fiber.Ctx) (history.Filter, error) {
+	f := history.Filter{
+		Model:  strings.TrimSpace(ctx.Query("model")),
+		Lora:   strings.TrimSpace(ctx.Query("lora")),
+		Query:  strings.TrimSpace(ctx.Query("q")),
+		Offset: ctx.QueryInt("offset", 0),
+		Limit:  ctx.QueryInt("limit", defaultHistoryLimit),
+	}
+	if f.Offset < 0 {
+		return f, errors.New("offset must be >= 0")
+	}
+	if f.Limit < 1 || f.Limit > maxHistoryLimit {
+		return f, fmt.Errorf("limit must be between 1 and %d", maxHistoryLimit)
+	}
+
+	var err error
+	if f.From, err = parseHistoryDate(ctx.Query("from"), false); err != nil {
+		return f, fmt.Errorf("from: %w", err)
+	}
+	if f.To, err = parseHistoryDate(ctx.Query("to"), true); err != nil {
+		return f, fmt.Errorf("to: %w", err)
+	}
+	return f, nil
+}
+
+func parseHistoryDate(v string, endOfDay bool) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return time.Time{}, errors.New("expected RFC 3339 or YYYY-MM-DD")
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+func historyEntry(rec history.Record) types.HistoryEntry {
+	loras := make([]types.HistoryLora, 0, len(rec.Loras))
+	for _, l := range rec.Loras {
+		loras = append(loras, types.HistoryLora{Path: l.Path, Weight: l.Weight})
+	}
+	return types.HistoryEntry{
+		ID:             rec.ID,
+		CreatedAt:      rec.CreatedAt,
+		PositivePrompt: rec.PositivePrompt,
+		NegativePrompt: rec.NegativePrompt,
+		ModelPath:      rec.ModelPath,
+		Loras:          loras,
+		Seed:           rec.Params.Seed,
+		Steps:          rec.Params.Steps,
+		GuidanceScale:  rec.Params.GuidanceScale,
+		Width:          rec.Params.Width,
+		Height:         rec.Params.Height,
+		Scheduler:      rec.Params.Scheduler,
+		ClipSkip:       rec.Params.ClipSkip,
+		BatchSize:      rec.Params.BatchSize,
+		DurationMs:     rec.Duration.Milliseconds(),
+		MimeType:       rec.MimeType,
+		ImageCount:     len(rec.Images),
+	}
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"be/internal/library"
+	"be/internal/safetensors"
+	"be/types"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const maxLibraryLimit = 1000
+
+func (a *Api) ListModels() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		logger := HttpLogger("ListModels", ctx)
+		page, err := a.libraryPage(ctx, library.KindCheckpoint)
+		if err != nil {
+			logger.Warn("list models failed", "err", err)
+			return a.fail(ctx, err, "failed to list models")
+		}
+		logger.Debug("list models", "total", page.Total, "returned", len(page.Items))
+		return ctx.Status(fiber.StatusOK).JSON(types.ListModelsResponse{
+			ModelPaths:          libraryPaths(page.Items),
+			LibraryListResponse: page,
+		})
+	}
+}
+
+func (a *Api) ListLoras() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		logger := HttpLogger("ListLoras", ctx)
+		page, err := a.libraryPage(ctx, library.KindLora)
+		if err != nil {
+			logger.Warn("list loras failed", "err", err)
+			return a.fail(ctx, err, "failed to list loras")
+		}
+		logger.Debug("list loras", "total", page.Total, "returned", len(page.Items))
+		return ctx.Status(fiber.StatusOK).JSON(types.ListLorasResponse{
+			LoraPaths:           libraryPaths(page.Items),
+			LibraryListResponse: page,
+		})
+	}
+}
+
+// ListKind lists one of the roots that have no endpoint of their own
+// (embeddings, VAEs, ControlNets, upscalers and LyCORIS), in the /library
+// shape.
+func (a *Api) ListKind(kind library.Kind) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		logger := HttpLogger("ListKind", ctx)
+		page, err := a.libraryPage(ctx, kind)
+		if err != nil {
+			logger.Warn("list library failed", "kind", kind, "err", err)
+			return a.fail(ctx, err, fmt.Sprintf("failed to list %s files", kind))
+		}
+		logger.Debug("list library", "kind", kind, "total", page.Total, "returned", len(page.Items))
+		return ctx.Status(fiber.StatusOK).JSON(page)
+	}
+}
+
+// ListLibrary lists every root at once; ?type= narrows it to one kind.
+func (a *Api) ListLibrary() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		logger := HttpLogger("ListLibrary", ctx)
+		page, err := a.libraryPage(ctx, library.Kind(strings.TrimSpace(ctx.Query("type"))))
+		if err != nil {
+			logger.Warn("list library failed", "err", err)
+			return a.fail(ctx, err, "failed to list library")
+		}
+		logger.Debug("list library", "total", page.Total, "returned", len(page.Items))
+		return ctx.Status(fiber.StatusOK).JSON(page)
+	}
+}
+
+// LibraryUpdates lists downloaded files with newer versions on the model
+// host, as of the last update check; ?refresh=true checks now.
+func (a *Api) LibraryUpdates() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		logger := HttpLogger("LibraryUpdates", ctx)
+		if a.dl == nil || a.library == nil {
+			logger.Error("downloader not configured")
+			return a.fail(ctx, internalError(errors.New("downloader not configured")), "service unavailable")
+		}
+		if ctx.QueryBool("refresh") {
+			if err := a.dl.CheckUpdates(ctx.UserContext()); err != nil {
+				logger.Warn("update check failed", "err", err)
+				return a.fail(ctx, err, "failed to check for updates")
+			}
+		}
+		items, checkedAt := a.dl.Updates()
+		logger.Debug("list updates", "updates", len(items), "checkedAt", checkedAt)
+		return ctx.Status(fiber.StatusOK).JSON(types.LibraryUpdatesResponse{Items: items, CheckedAt: checkedAt})
+	}
+}
+
+// UpgradeModel queues the newer version of a downloaded file. The reply and
+// the download.* events are those of POST /download.
+func (a *Api) UpgradeModel() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		logger := HttpLogger("UpgradeModel", ctx)
+		if a.dl == nil {
+			logger.Error("downloader not configured")
+			return a.fail(ctx, internalError(errors.New("downloader not configured")), "service unavailable")
+		}
+
+		var req UpgradeRequest
+		if err := ctx.BodyParser(&req); err != nil {
+			logger.Error("invalid body", "err", err)
+			return a.fail(ctx, invalidArgument(err), "invalid body")
+		}
+		if req.ClientID == "" {
+			logger.Warn("missing clientId")
+			return a.fail(ctx, invalidArgument(errors.New("clientId is required")), "missing clientId")
+		}
+		if strings.TrimSpace(req.Path) == "" {
+			logger.Warn("missing path")
+			return a.fail(ctx, invalidArgument(errors.New("path is required")), "missing path")
+		}
+
+		job, err := a.dl.Upgrade(uuid.NewString(), req)
+		if err != nil {
+			var already AlreadyQueuedError
+			if errors.As(err, &already) {
+				logger.Info("upgrade already queued", "existingJobId", already.JobID)
+				return ctx.Status(fiber.StatusAccepted).JSON(types.DownloadResponse{JobID: already.JobID, ModelVersionID: job.ModelVersionID})
+			}
+			logger.Warn("upgrade failed", "clientId", req.ClientID, "path", req.Path, "err", err)
+			return a.fail(ctx, err, "failed to enqueue upgrade")
+		}
+
+		logger.Info("upgrade enqueued", "jobId", job.JobID, "path", job.Upgrades, "modelVersionId", job.ModelVersionID, "removeOld", job.RemoveOld)
+		return ctx.Status(fiber.StatusAccepted).JSON(types.DownloadResponse{JobID: job.JobID, ModelVersionID: job.ModelVersionID})
+	}
+}
+
+func (a *Api) ModelInfo() fiber.Handler {
+	return a.fileInfo("ModelInfo", library.KindCheckpoint)
+}
+
+func (a *Api) LoraInfo() fiber.Handler {
+	return a.fileInfo("LoraInfo", library.KindLora)
+}
+
+// fileInfo describes a file under the root for kind from its safetensors
+// header; the tensors themselves are never read.
+func (a *Api) fileInfo(action string, kind library.Kind) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		logger := HttpLogger(action, ctx)
+		if a.library == nil {
+			logger.Error("library not configured")
+			return a.fail(ctx, internalError(errors.New("library not configured")), "service unavailable")
+		}
+
+		path, err := a.library.Resolve(kind, ctx.Query("path"))
+		if err != nil {
+			logger.Warn("invalid path", "path", ctx.Query("path"), "err", err)
+			return a.fail(ctx, err, "invalid path")
+		}
+		if !strings.EqualFold(filepath.Ext(path), ".safetensors") {
+			logger.Warn("not a safetensors file", "path", path)
+			return a.fail(ctx, invalidArgument(errors.New("path must be a .safetensors file")), "invalid path")
+		}
+
+		info, err := safetensors.InspectFile(path)
+		if err != nil {
+			logger.Warn("inspect failed", "path", path, "err", err)
+			return a.fail(ctx, err, "failed to read safetensors header")
+		}
+
+		logger.Debug("inspected", "path", path, "type", info.Type, "arch", info.Arch, "tensors", info.Tensors)
+		return ctx.Status(fiber.StatusOK).JSON(modelInfo(path, info))
+	}
+}
+
+// libraryPage runs the shared list query. The index is scanned on the first
+// request and whenever the client asks for ?refresh=true.
+func (a *Api) libraryPage(ctx *fiber.Ctx, kind library.Kind) (types.LibraryListResponse, error) {
+	if a.library == nil {
+		return types.LibraryListResponse{}, internalError(errors.New("library not configured"))
+	}
+	filter, err := libraryFilter(ctx, kind)
+	if err != nil {
+		return types.LibraryListResponse{}, invalidArgument(err)
+	}
+	if ctx.QueryBool("refresh") || a.library.Scanned().IsZero() {
+		if err := a.library.Scan(); err != nil {
+			return types.LibraryListResponse{}, internalError(err)
+		}
+	}
+
+	entries, total := a.library.List(filter)
+	items := make([]types.LibraryEntry, 0, len(entries))
+	for _, e := range entries {
+		items = append(items, libraryEntry(e))
+	}
+	return types.LibraryListResponse{
+		Items:  items,
+		Total:  total,
+		Offset: filter.Offset,
+		Limit:  filter.Limit,
+	}, nil
+}
+
+// libraryFilter parses the list query. Without a limit every match after
+// offset is returned, which is what the model pickers expect.
+func libraryFilter(ctx *fiber.Ctx, kind library.Kind) (library.Filter, error) {
+	f := library.Filter{
+		Kind:      kind,
+		BaseModel: strings.TrimSpace(ctx.Query("baseModel")),
+		Query:     strings.TrimSpace(ctx.Query("q")),
+		Sort:      strings.TrimSpace(ctx.Query("sort")),
+		Offset:    ctx.QueryInt("offset", 0),
+		Limit:     ctx.QueryInt("limit", 0),
+	}
+	if kind != "" && !slices.Contains(library.Kinds, kind) {
+		return f, fmt.Errorf("type must be one of %s", kindList())
+	}
+	switch strings.ToLower(strings.TrimSpace(ctx.Query("order"))) {
+	case "", "asc":
+	case "desc":
+		f.Desc = true
+	default:
+		return f, errors.New("order must be asc or desc")
+	}
+	if !library.ValidSort(f.Sort) {
+		return f, fmt.Errorf("sort must be one of %s, %s, %s, %s", library.SortName, library.SortSize, library.SortModified, library.SortBaseModel)
+	}
+	if f.Offset < 0 {
+		return f, errors.New("offset must be >= 0")
+	}
+	if f.Limit < 0 || f.Limit > maxLibraryLimit {
+		return f, fmt.Errorf("limit must be between 0 and %d", maxLibraryLimit)
+	}
+	return f, nil
+}
+
+func kindList() string {
+	names := make([]string, len(library.Kinds))
+	for i, k := range library.Kinds {
+		names[i] = string(k)
+	}
+	return strings.Join(names, ", ")
+}
+
+// LibraryEvents returns an OnChange callback that tells every connected
+// client about index changes. Each batch is sent as at most three events,
+// library.added, library.removed and library.changed, each listing its
+// entries, so a scan that touches many files can't overflow the client
+// queues.
+func LibraryEvents(hub *Hub) func([]library.Change) {
+	return func(changes []library.Change) {
+		byType := map[library.ChangeType][]types.LibraryEntry{}
+		for _, c := range changes {
+			byType[c.Type] = append(byType[c.Type], libraryEntry(c.Entry))
+		}
+		for _, ev := range []struct {
+			change library.ChangeType
+			event  string
+		}{
+			{library.ChangeAdded, EventLibraryAdded},
+			{library.ChangeRemoved, EventLibraryRemoved},
+			{library.ChangeChanged, EventLibraryChanged},
+		} {
+			if entries := byType[ev.change]; len(entries) > 0 {
+				hub.Broadcast(WSEvent{
+					Type:    ev.event,
+					Entries: entries,
+				})
+			}
+		}
+	}
+}
+
+func libraryEntry(e library.Entry) types.LibraryEntry {
+	return types.LibraryEntry{
+		Path:      e.Path,
+		Kind:      string(e.Kind),
+		Name:      e.Name,
+		BaseModel: e.BaseModel,
+		Size:      e.Size,
+		ModTime:   e.ModTime,
+		SHA256:    e.SHA256,
+		Verified:  e.Verified,
+	}
+}
+
+func modelInfo(path string, info safetensors.Info) types.ModelInfoResponse {
+	// The raw tag counts are reported parsed under training.tags.
+	meta := make(map[string]string, len(info.Metadata))
+	for k, v := range info.Metadata {
+		if k != "ss_tag_frequency" {
+			meta[k] = v
+		}
+	}
+	resp := types.ModelInfoResponse{
+		Path:       path,
+		Type:       string(info.Type),
+		Arch:       string(info.Arch),
+		Precision:  info.Precision,
+		Tensors:    info.Tensors,
+		HeaderSize: info.Header,
+		Metadata:   meta,
+	}
+	if t := info.Training; t != nil {
+		tags := make([]types.TagCount, 0, len(t.Tags))
+		for _, tc := range t.Tags {
+			tags = append(tags, types.TagCount{Tag: tc.Tag, Count: tc.Count})
+		}
+		resp.Training = &types.TrainingInfo{
+			BaseModel:        t.BaseModel,
+			BaseModelVersion: t.BaseModelVersion,
+			NetworkModule:    t.NetworkModule,
+			NetworkDim:       t.NetworkDim,
+			NetworkAlpha:     t.NetworkAlpha,
+			OutputName:       t.OutputName,
+			Resolution:       t.Resolution,
+			Epochs:           t.Epochs,
+			Steps:            t.Steps,
+			Comment:          t.Comment,
+			Tags:             tags,
+		}
+	}
+	return resp
+}
+
+func libraryPaths(items []types.LibraryEntry) []string {
+	paths := make([]string, 0, len(items))
+	for _, e := range items {
+		paths = append(paths, e.Path)
+	}
+	return paths
+}
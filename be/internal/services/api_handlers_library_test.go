@@ -0,0 +1,41 @@
+package services
+
+import (
+	"be/internal/library"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestLibraryEventsBatch(t *testing.T) {
+	hub := NewHub()
+	c := &WSClient{id: "ui", send: make(chan []byte, 16)}
+	hub.clients[c.id] = c
+
+	// A first scan of a full folder must not overflow the client queue.
+	var changes []library.Change
+	for i := range 40 {
+		changes = append(changes, library.Change{Type: library.ChangeAdded, Entry: library.Entry{Path: fmt.Sprintf("/loras/%d.safetensors", i)}})
+	}
+	changes = append(changes, library.Change{Type: library.ChangeRemoved, Entry: library.Entry{Path: "/loras/old.safetensors"}})
+	LibraryEvents(hub)(changes)
+
+	if _, ok := hub.clients[c.id]; !ok {
+		t.Fatal("client dropped")
+	}
+	if len(c.send) != 2 {
+		t.Fatalf("events = %d, want one per change type", len(c.send))
+	}
+	var added, removed WSEvent
+	for _, ev := range []*WSEvent{&added, &removed} {
+		if err := json.Unmarshal(<-c.send, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if added.Type != "library.added" || len(added.Entries) != 40 {
+		t.Fatalf("added = %s with %d entries", added.Type, len(added.Entries))
+	}
+	if removed.Type != "library.removed" || len(removed.Entries) != 1 || removed.Entries[0].Path != "/loras/old.safetensors" {
+		t.Fatalf("removed = %+v", removed)
+	}
+}
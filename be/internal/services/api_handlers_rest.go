@@ -1,17 +1,16 @@
 package services
 
 import (
+	"be/internal/clients/huggingface"
+	"be/internal/dependencies"
 	"be/proto"
 	"be/types"
 	"errors"
 	"fmt"
-	"io/fs"
-	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/charmbracelet/log"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
@@ -19,9 +18,22 @@ import (
 func (a *Api) Health() fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
 		HttpLogger("Health", ctx).Debug("health")
+		worker := types.WorkerHealth{Workers: []types.WorkerStatus{}}
+		if a.workers != nil {
+			for _, w := range a.workers.Workers() {
+				healthy := w.Healthy()
+				worker.Available = worker.Available || healthy
+				worker.Workers = append(worker.Workers, types.WorkerStatus{
+					Addr:    w.Addr,
+					Healthy: healthy,
+					State:   w.Status(),
+				})
+			}
+		}
 		return ctx.Status(fiber.StatusOK).JSON(types.HealthResponse{
 			Status:    fiber.StatusOK,
 			TimeStamp: time.Now().Unix(),
+			Worker:    worker,
 		})
 	}
 }
@@ -29,121 +41,154 @@ func (a *Api) Health() fiber.Handler {
 func (a *Api) GenerateImage() fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
 		logger := HttpLogger("GenerateImage", ctx)
+		if a.gen == nil {
+			logger.Error("generator not configured")
+			return a.fail(ctx, internalError(errors.New("generator not configured")), "service unavailable")
+		}
 
 		var requestBody types.ImagePostRequest
 		if err := ctx.BodyParser(&requestBody); err != nil {
 			logger.Error("invalid body", "err", err)
-			return ctx.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
-				Error:   err.Error(),
-				Message: "invalid body",
-			})
+			return a.fail(ctx, invalidArgument(err), "invalid body")
 		}
 
-		logger.Info("generate requested", "positiveLen", len(requestBody.PositivePrompt), "negativeLen", len(requestBody.NegativePrompt))
+		requestBody, err := normalizeImageRequest(requestBody)
+		if err != nil {
+			logger.Warn("invalid generation params", "err", err)
+			return a.fail(ctx, invalidArgument(err), "invalid generation parameters")
+		}
+		out, err := parseImageOutput(ctx, "")
+		if err != nil {
+			logger.Warn("invalid output params", "err", err)
+			return a.fail(ctx, invalidArgument(err), "invalid query")
+		}
+
+		logger.Info("generate requested", "positiveLen", len(requestBody.PositivePrompt), "negativeLen", len(requestBody.NegativePrompt), "seed", *requestBody.Seed, "steps", requestBody.Steps, "width", requestBody.Width, "height", requestBody.Height)
 
-		// now is the time to implement then call the rpc service
-		resp, err := a.rpc.GenerateImage(requestBody.PositivePrompt, requestBody.NegativePrompt)
+		historyID := uuid.NewString()
+		resp, err := a.gen.Generate(GenerationJob{
+			JobID:   historyID,
+			Request: requestBody,
+		})
+		var incompatible IncompatibleLorasError
+		if errors.As(err, &incompatible) {
+			logger.Warn("incompatible loras", "rejected", len(incompatible.Loras), "err", err)
+			return a.fail(ctx, err, "LoRAs don't match the model")
+		}
 		if err != nil {
 			logger.Error("generate failed", "err", err)
-			return ctx.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
-				Error:   err.Error(),
-				Message: "python service failed to generate image",
-			})
+			return a.fail(ctx, err, "python service failed to generate image")
 		}
 
-		logger.Info("generate completed", "mimeType", resp.MimeType, "bytes", len(resp.Image))
+		logger.Info("generate completed", "mimeType", resp.MimeType, "bytes", len(resp.Image), "seed", resp.Seed)
 
-		ctx.Set(fiber.HeaderContentType, resp.MimeType)
-		ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf("inline; filename=%s", resp.FilenameHint))
-		ctx.Response().SetBodyRaw(resp.Image)
+		ctx.Set("X-Seed", strconv.FormatInt(resp.Seed, 10))
+		ctx.Set("X-History-Id", historyID)
+		if err := out.send(ctx, resp.Image, resp.MimeType, resp.FilenameHint); err != nil {
+			logger.Error("render image failed", "err", err)
+			return a.fail(ctx, internalError(err), "failed to render image")
+		}
 		return nil
 	}
 }
-func (a *Api) ListModels() fiber.Handler {
-	return func(ctx *fiber.Ctx) error {
-		logger := HttpLogger("ListModels", ctx)
 
-		var files []string
-		extension := "safetensors"
-		root := os.Getenv("MODEL_MOUNT_PATH")
-		if root == "" {
-			root = "/workspace/models"
-		}
-		if !strings.HasPrefix(extension, ".") {
-			extension = "." + extension
+func (a *Api) EnqueueGeneration() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		logger := HttpLogger("EnqueueGeneration", ctx)
+		if a.gen == nil {
+			logger.Error("generator not configured")
+			return a.fail(ctx, internalError(errors.New("generator not configured")), "service unavailable")
 		}
 
-		logger.Debug("scan models", "root", root, "ext", extension)
-		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-			if err != nil {
-				log.Warn("walkdir error", "path", path, "err", err)
-				return nil
-			}
+		var req GenerationRequest
+		if err := ctx.BodyParser(&req); err != nil {
+			logger.Error("invalid body", "err", err)
+			return a.fail(ctx, invalidArgument(err), "invalid body")
+		}
 
-			if !d.IsDir() && strings.HasSuffix(d.Name(), extension) {
-				files = append(files, path)
-			}
-			return nil
-		})
+		if req.ClientID == "" {
+			logger.Warn("missing clientId")
+			return a.fail(ctx, invalidArgument(errors.New("clientId is required")), "missing clientId")
+		}
 
+		params, err := normalizeImageRequest(req.ImagePostRequest)
 		if err != nil {
-			logger.Error("scan models failed", "root", root, "err", err)
-			ctx.Status(fiber.StatusInternalServerError)
-			ctx.JSON(types.ErrorResponse{
-				Error:   err.Error(),
-				Message: "Failed to walk model files.",
-			})
-			return nil
+			logger.Warn("invalid generation params", "err", err)
+			return a.fail(ctx, invalidArgument(err), "invalid generation parameters")
 		}
 
-		logger.Info("scan models completed", "root", root, "count", len(files))
-		ctx.Status(fiber.StatusOK)
-		ctx.JSON(types.ListModelsResponse{ModelPaths: files})
-		return nil
+		jobID := uuid.NewString()
+		logger.Info("generation enqueue requested", "jobId", jobID, "clientId", req.ClientID, "positiveLen", len(req.PositivePrompt), "negativeLen", len(req.NegativePrompt))
+		if err := a.gen.Enqueue(GenerationJob{
+			JobID:    jobID,
+			ClientID: req.ClientID,
+			Request:  params,
+		}); err != nil {
+			logger.Error("generation enqueue failed", "jobId", jobID, "clientId", req.ClientID, "err", err)
+			return a.fail(ctx, err, "failed to enqueue generation")
+		}
+
+		logger.Info("generation enqueued", "jobId", jobID)
+		return ctx.Status(fiber.StatusAccepted).JSON(types.GenerationResponse{JobID: jobID})
 	}
 }
-func (a *Api) ListLoras() fiber.Handler {
+
+// GetGeneration returns the image once the job has completed; until then (or
+// when it failed) it answers with the job status as JSON.
+func (a *Api) GetGeneration() fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
-		logger := HttpLogger("ListLoras", ctx)
+		logger := HttpLogger("GetGeneration", ctx)
+		if a.gen == nil {
+			logger.Error("generator not configured")
+			return a.fail(ctx, internalError(errors.New("generator not configured")), "service unavailable")
+		}
 
-		var files []string
-		extension := "safetensors"
-		root := os.Getenv("LORA_MOUNT_PATH")
-		if root == "" {
-			root = "/workspace/loras"
+		out, err := parseImageOutput(ctx, "")
+		if err != nil {
+			logger.Warn("invalid output params", "err", err)
+			return a.fail(ctx, invalidArgument(err), "invalid query")
 		}
-		if !strings.HasPrefix(extension, ".") {
-			extension = "." + extension
+
+		jobID := strings.TrimSpace(ctx.Params("id"))
+		result, err := a.gen.Result(jobID)
+		if err != nil {
+			logger.Warn("generation lookup failed", "jobId", jobID, "err", err)
+			return a.fail(ctx, err, "unknown generation job")
 		}
 
-		logger.Debug("scan loras", "root", root, "ext", extension)
-		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-			if err != nil {
-				return nil
+		switch result.Status {
+		case GenerationCompleted:
+			index := ctx.QueryInt("index", 0)
+			if index < 0 || index >= len(result.Images) {
+				logger.Warn("generation image index out of range", "jobId", jobID, "index", index, "images", len(result.Images))
+				return a.fail(ctx, notFound(fmt.Errorf("index must be between 0 and %d", len(result.Images)-1)), "unknown generation image")
 			}
 
-			if !d.IsDir() && strings.HasSuffix(d.Name(), extension) {
-				files = append(files, path)
+			logger.Debug("generation fetched", "jobId", jobID, "index", index, "bytes", len(result.Images[index]))
+			ctx.Set("X-Seed", strconv.FormatInt(result.Seed+int64(index), 10))
+			if err := out.send(ctx, result.Images[index], result.MimeType, result.Filename); err != nil {
+				logger.Error("render image failed", "jobId", jobID, "err", err)
+				return a.fail(ctx, internalError(err), "failed to render image")
 			}
 			return nil
-		})
-
-		if err != nil {
-			logger.Error("scan loras failed", "root", root, "err", err)
-			ctx.Status(fiber.StatusInternalServerError)
-			ctx.JSON(types.ErrorResponse{
-				Error:   err.Error(),
-				Message: "Failed to walk model files.",
+		case GenerationFailed:
+			return ctx.Status(fiber.StatusOK).JSON(types.GenerationStatusResponse{
+				JobID:      result.JobID,
+				Status:     result.Status,
+				Error:      result.Error,
+				Code:       result.Code,
+				Details:    result.Details,
+				Seed:       &result.Seed,
+				ImageCount: result.ImageCount,
+			})
+		default:
+			return ctx.Status(fiber.StatusAccepted).JSON(types.GenerationStatusResponse{
+				JobID:      result.JobID,
+				Status:     result.Status,
+				Seed:       &result.Seed,
+				ImageCount: result.ImageCount,
 			})
-			return nil
 		}
-
-		logger.Info("scan loras completed", "root", root, "count", len(files))
-		ctx.Status(fiber.StatusOK)
-		ctx.JSON(types.ListLorasResponse{
-			LoraPaths: files,
-		})
-		return nil
 	}
 }
 
@@ -154,20 +199,16 @@ func (a *Api) SetModel() fiber.Handler {
 		var requestBody types.SetModelRequest
 		if err := ctx.BodyParser(&requestBody); err != nil {
 			logger.Error("invalid body", "err", err)
-			return ctx.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
-				Error:   err.Error(),
-				Message: "invalid body",
-			})
+			return a.fail(ctx, invalidArgument(err), "invalid body")
 		}
 
 		logger.Info("set model requested", "modelPath", requestBody.ModelPath)
-		resp, err := a.rpc.SetModel(requestBody.ModelPath)
+		resp, err := broadcastHeld(a.workers, a.gen, func(w *dependencies.Worker) (*proto.SetModelResponse, error) {
+			return w.SetModel(requestBody.ModelPath)
+		})
 		if err != nil {
 			logger.Error("set model failed", "modelPath", requestBody.ModelPath, "err", err)
-			return ctx.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
-				Error:   err.Error(),
-				Message: "python service failed to set model",
-			})
+			return a.fail(ctx, err, "python service failed to set model")
 		}
 
 		logger.Info("set model completed", "modelPath", resp.ModelPath)
@@ -184,10 +225,7 @@ func (a *Api) SetLoras() fiber.Handler {
 		var requestBody []types.SetLora
 		if err := ctx.BodyParser(&requestBody); err != nil {
 			logger.Error("invalid body", "err", err)
-			return ctx.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
-				Error:   err.Error(),
-				Message: "invalid body",
-			})
+			return a.fail(ctx, invalidArgument(err), "invalid body")
 		}
 
 		logger.Info("set loras requested", "count", len(requestBody))
@@ -195,10 +233,7 @@ func (a *Api) SetLoras() fiber.Handler {
 		for i := range requestBody {
 			if requestBody[i].Weight < 0.1 {
 				logger.Warn("invalid lora weight", "path", requestBody[i].Path, "weight", requestBody[i].Weight)
-				return ctx.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
-					Error:   "invalid lora weight",
-					Message: "LoRA weight must be >= 0.1",
-				})
+				return a.fail(ctx, invalidArgument(errors.New("invalid lora weight")), "LoRA weight must be >= 0.1")
 			}
 			lorapaths = append(lorapaths, &proto.SetLora{
 				Weight: requestBody[i].Weight,
@@ -206,36 +241,28 @@ func (a *Api) SetLoras() fiber.Handler {
 			})
 		}
 
-		resp, err := a.rpc.SetLoras(lorapaths)
+		if ctx.QueryBool("force") {
+			logger.Warn("lora compatibility check skipped", "reason", "force")
+		} else if err := a.checkLoraCompat(requestBody); err != nil {
+			var incompatible IncompatibleLorasError
+			if errors.As(err, &incompatible) {
+				logger.Warn("incompatible loras", "rejected", len(incompatible.Loras), "err", err)
+				return a.fail(ctx, err, "LoRAs don't match the loaded model; pass force=true to apply them anyway")
+			}
+			logger.Warn("lora compatibility check failed", "err", err)
+		}
+
+		resp, err := broadcastHeld(a.workers, a.gen, func(w *dependencies.Worker) (*proto.SetLoraResponse, error) {
+			return w.SetLoras(lorapaths)
+		})
 		if err != nil {
 			logger.Error("set loras failed", "count", len(lorapaths), "err", err)
-			return ctx.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
-				Error:   err.Error(),
-				Message: "python service failed to apply loras",
-			})
+			return a.fail(ctx, err, "python service failed to apply loras")
 		}
 
 		appliedloras := make([]types.SetLora, 0, len(resp.Loras))
 		for _, applied := range resp.Loras {
-
-			base := filepath.Base(applied.Path)
-			stem := strings.TrimSuffix(base, filepath.Ext(applied.Path))
-			modelId := strings.Split(stem, "-")[0]
-
-			var triggers *string
-			if modelId != "" && a.dl != nil && a.dl.client != nil {
-				info, err := a.dl.client.GetModelVersionInfo(modelId)
-				if err == nil && len(info.TrainedWords) > 0 {
-					joined := strings.Join(info.TrainedWords, ",")
-					triggers = &joined
-				}
-			}
-
-			appliedloras = append(appliedloras, types.SetLora{
-				Path:         applied.Path,
-				Weight:       applied.Weight,
-				TriggerWords: triggers,
-			})
+			appliedloras = append(appliedloras, a.describeLora(applied))
 		}
 
 		ctx.Status(fiber.StatusOK)
@@ -246,16 +273,39 @@ func (a *Api) SetLoras() fiber.Handler {
 	}
 }
 
+// describeLora adds what the downloaded metadata says about the LoRA. Files
+// without metadata are reported as they are.
+func (a *Api) describeLora(l *proto.SetLora) types.SetLora {
+	out := types.SetLora{Path: l.Path, Weight: l.Weight}
+	if a.dl == nil {
+		return out
+	}
+	info, ok := a.dl.Metadata(l.Path)
+	if !ok {
+		return out
+	}
+	if len(info.TrainedWords) > 0 {
+		joined := strings.Join(info.TrainedWords, ",")
+		out.TriggerWords = &joined
+	}
+	out.BaseModel = info.BaseModel
+	if info.Description != nil {
+		out.Description = *info.Description
+	}
+	return out
+}
+
 func (a *Api) CurrentModel() fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
 		logger := HttpLogger("CurrentModel", ctx)
-		resp, err := a.rpc.GetCurrentModel()
+		w, err := a.workers.Primary()
+		var resp *proto.GetCurrentModelResponse
+		if err == nil {
+			resp, err = w.GetCurrentModel()
+		}
 		if err != nil {
 			logger.Error("get current model failed", "err", err)
-			return ctx.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
-				Error:   err.Error(),
-				Message: "python service failed to get current model",
-			})
+			return a.fail(ctx, err, "python service failed to get current model")
 		}
 
 		logger.Debug("get current model", "modelPath", resp.ModelPath)
@@ -267,31 +317,19 @@ func (a *Api) CurrentModel() fiber.Handler {
 func (a *Api) CurrentLoras() fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
 		logger := HttpLogger("CurrentLoras", ctx)
-		resp, err := a.rpc.GetCurrentLoras()
+		w, err := a.workers.Primary()
+		var resp *proto.GetCurrentLorasResponse
+		if err == nil {
+			resp, err = w.GetCurrentLoras()
+		}
 		if err != nil {
 			logger.Error("get current loras failed", "err", err)
-			return ctx.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
-				Error:   err.Error(),
-				Message: "python service failed to get current loras",
-			})
+			return a.fail(ctx, err, "python service failed to get current loras")
 		}
 
 		appliedloras := make([]types.SetLora, 0, len(resp.Loras))
 		for _, applied := range resp.Loras {
-			base := filepath.Base(applied.Path)
-			stem := strings.TrimSuffix(base, filepath.Ext(applied.Path))
-			modelId := strings.Split(stem, "-")[0]
-
-			var triggers *string
-			if modelId != "" && a.dl != nil && a.dl.client != nil {
-				info, err := a.dl.client.GetModelVersionInfo(modelId)
-				if err == nil && len(info.TrainedWords) > 0 {
-					joined := strings.Join(info.TrainedWords, ",")
-					triggers = &joined
-				}
-			}
-
-			appliedloras = append(appliedloras, types.SetLora{Path: applied.Path, Weight: applied.Weight, TriggerWords: triggers})
+			appliedloras = append(appliedloras, a.describeLora(applied))
 		}
 
 		logger.Debug("get current loras", "count", len(appliedloras))
@@ -304,13 +342,10 @@ func (a *Api) ClearModel() fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
 		logger := HttpLogger("ClearModel", ctx)
 		logger.Info("clear model requested")
-		resp, err := a.rpc.ClearModel()
+		resp, err := broadcastHeld(a.workers, a.gen, (*dependencies.Worker).ClearModel)
 		if err != nil {
 			logger.Error("clear model failed", "err", err)
-			return ctx.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
-				Error:   err.Error(),
-				Message: "python service failed to clear model",
-			})
+			return a.fail(ctx, err, "python service failed to clear model")
 		}
 
 		loras := make([]types.SetLora, 0, len(resp.Loras))
@@ -331,13 +366,10 @@ func (a *Api) ClearLoras() fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
 		logger := HttpLogger("ClearLoras", ctx)
 		logger.Info("clear loras requested")
-		resp, err := a.rpc.ClearLoras()
+		resp, err := broadcastHeld(a.workers, a.gen, (*dependencies.Worker).ClearLoras)
 		if err != nil {
 			logger.Error("clear loras failed", "err", err)
-			return ctx.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
-				Error:   err.Error(),
-				Message: "python service failed to clear loras",
-			})
+			return a.fail(ctx, err, "python service failed to clear loras")
 		}
 
 		loras := make([]types.SetLora, 0, len(resp.Loras))
@@ -356,60 +388,78 @@ func (a *Api) DownloadModel() fiber.Handler {
 		logger := HttpLogger("DownloadModel", ctx)
 		if a.dl == nil {
 			logger.Error("downloader not configured")
-			return ctx.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{
-				Error:   "downloader not configured",
-				Message: "service unavailable",
-			})
+			return a.fail(ctx, internalError(errors.New("downloader not configured")), "service unavailable")
 		}
 
 		var req DownloadRequest
 		if err := ctx.BodyParser(&req); err != nil {
 			logger.Error("invalid body", "err", err)
-			return ctx.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
-				Error:   err.Error(),
-				Message: "invalid body",
-			})
+			return a.fail(ctx, invalidArgument(err), "invalid body")
 		}
 
 		if req.ClientID == "" {
 			logger.Warn("missing clientId")
-			return ctx.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
-				Error:   "clientId is required",
-				Message: "missing clientId",
-			})
+			return a.fail(ctx, invalidArgument(errors.New("clientId is required")), "missing clientId")
 		}
-		if req.ModelVersionID <= 0 {
-			logger.Warn("invalid modelVersionId", "modelVersionId", req.ModelVersionID)
-			return ctx.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
-				Error:   "modelVersionId must be > 0",
-				Message: "invalid modelVersionId",
-			})
+		switch {
+		case req.ModelID > 0 && req.ModelVersionID != 0:
+			logger.Warn("both modelId and modelVersionId", "modelId", req.ModelID, "modelVersionId", req.ModelVersionID)
+			return a.fail(ctx, invalidArgument(errors.New("set modelId or modelVersionId, not both")), "invalid download")
+		case req.ModelID > 0:
+			sel, err := selectionPolicy(req)
+			if err != nil {
+				logger.Warn("invalid selection policy", "modelId", req.ModelID, "err", err)
+				return a.fail(ctx, invalidArgument(err), "invalid selection policy")
+			}
+			req.Select = sel
+		case req.ModelID < 0 || req.ModelVersionID <= 0:
+			logger.Warn("invalid modelVersionId", "modelVersionId", req.ModelVersionID, "modelId", req.ModelID)
+			return a.fail(ctx, invalidArgument(errors.New("modelVersionId or modelId must be > 0")), "invalid modelVersionId")
+		}
+
+		if len(req.FileIDs) > 0 && !req.Prefer.empty() {
+			logger.Warn("both fileIds and prefer", "fileIds", req.FileIDs)
+			return a.fail(ctx, invalidArgument(errors.New("set fileIds or prefer, not both")), "invalid download")
+		}
+
+		if req.OverridePolicy && !a.dl.Policy().Admin(ctx.Get(HeaderAdminToken)) {
+			logger.Warn("policy override denied", "clientId", req.ClientID, "modelVersionId", req.ModelVersionID)
+			return a.fail(ctx, permissionDenied(errors.New("overriding the download policy needs a valid "+HeaderAdminToken)), "policy override denied")
+		}
+
+		if req.ModelID > 0 {
+			versionID, err := a.dl.ResolveVersion(req)
+			if err != nil {
+				logger.Warn("resolve model version failed", "modelId", req.ModelID, "select", req.Select, "err", err)
+				if !errors.Is(err, huggingface.ErrNotFound) && !errors.Is(err, ErrNoMatchingVersion) {
+					err = unavailable(err)
+				}
+				return a.fail(ctx, err, "failed to resolve model version")
+			}
+			logger.Info("model version resolved", "modelId", req.ModelID, "select", req.Select, "modelVersionId", versionID)
+			req.ModelVersionID = versionID
 		}
 
 		jobID := uuid.NewString()
-		logger.Info("download enqueue requested", "jobId", jobID, "clientId", req.ClientID, "modelVersionId", req.ModelVersionID)
+		logger.Info("download enqueue requested", "jobId", jobID, "clientId", req.ClientID, "modelVersionId", req.ModelVersionID, "overridePolicy", req.OverridePolicy)
 		if err := a.dl.Enqueue(DownloadJob{
 			JobID:          jobID,
 			ClientID:       req.ClientID,
 			ModelVersionID: req.ModelVersionID,
+			OverridePolicy: req.OverridePolicy,
+			FileIDs:        req.FileIDs,
+			Prefer:         req.Prefer,
 		}); err != nil {
-			code := fiber.StatusServiceUnavailable
 			var already AlreadyQueuedError
 			if errors.As(err, &already) {
 				logger.Info("download already queued", "existingJobId", already.JobID)
-				return ctx.Status(fiber.StatusAccepted).JSON(types.DownloadResponse{JobID: already.JobID})
-			}
-			if errors.Is(err, ErrDownloadQueueFull) {
-				code = fiber.StatusTooManyRequests
+				return ctx.Status(fiber.StatusAccepted).JSON(types.DownloadResponse{JobID: already.JobID, ModelVersionID: req.ModelVersionID})
 			}
 			logger.Error("download enqueue failed", "jobId", jobID, "clientId", req.ClientID, "modelVersionId", req.ModelVersionID, "err", err)
-			return ctx.Status(code).JSON(types.ErrorResponse{
-				Error:   err.Error(),
-				Message: "failed to enqueue download",
-			})
+			return a.fail(ctx, err, "failed to enqueue download")
 		}
 
 		logger.Info("download enqueued", "jobId", jobID)
-		return ctx.Status(fiber.StatusAccepted).JSON(types.DownloadResponse{JobID: jobID})
+		return ctx.Status(fiber.StatusAccepted).JSON(types.DownloadResponse{JobID: jobID, ModelVersionID: req.ModelVersionID})
 	}
 }
@@ -0,0 +1,637 @@
+package services
+
+import (
+	"be/config"
+	"be/internal/clients/huggingface"
+	"be/internal/dependencies"
+	"be/internal/fakeworker"
+	"be/internal/history"
+	"be/internal/imaging"
+	"be/internal/library"
+	"be/internal/metadata"
+	"be/internal/modelhash"
+	"be/proto"
+	"be/types"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/image/webp"
+)
+
+type restFixture struct {
+	api       *Api
+	worker    *fakeworker.Server
+	workers   []*fakeworker.Server
+	model     string
+	lora      string
+	embedding string
+}
+
+// newRestFixture wires the real API, generator and pool to an in-process
+// fake worker, with a stub model host that knows trigger words for model
+// version 123.
+func newRestFixture(t *testing.T) *restFixture {
+	t.Helper()
+	return newPoolFixture(t, 1)
+}
+
+// newPoolFixture is newRestFixture with n fake workers in the pool; worker is
+// the first of them.
+func newPoolFixture(t *testing.T, n int) *restFixture {
+	t.Helper()
+	dir := t.TempDir()
+
+	models := filepath.Join(dir, "models")
+	loras := filepath.Join(dir, "loras")
+	embeddings := filepath.Join(dir, "embeddings")
+	f := &restFixture{
+		model:     filepath.Join(models, "sdxl.safetensors"),
+		lora:      filepath.Join(loras, "123-ink.safetensors"),
+		embedding: filepath.Join(embeddings, "SDXL-1.0", "negative.safetensors"),
+	}
+	for _, p := range []string{f.model, f.lora, f.embedding} {
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("weights"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	host := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/versions/123":
+			io.WriteString(w, `{"id":123,"baseModel":"SDXL 1.0","trainedWords":["ink","sketch"],"files":[
+				{"id":1,"name":"ink.safetensors","type":"Model","primary":true,"sizeKB":1024,"pickleScanResult":"Success","virusScanResult":"Success","metadata":{"fp":"fp16","format":"SafeTensor"}},
+				{"id":2,"name":"ink.ckpt","type":"Model","sizeKB":2048,"pickleScanResult":"Danger","virusScanResult":"Success","metadata":{"fp":"fp32","format":"PickleTensor"}}]}`)
+		case "/models/5":
+			io.WriteString(w, `{"id":5,"name":"Ink","modelVersions":[
+				{"id":126,"index":0,"name":"v3 pony","baseModel":"Pony"},
+				{"id":124,"index":1,"name":"v2","baseModel":"SDXL 1.0"},
+				{"id":123,"index":2,"name":"v1","baseModel":"SDXL 1.0"}]}`)
+		case "/search":
+			switch r.URL.Query().Get("query") {
+			case "bad":
+				http.Error(w, `{"error":"invalid cursor"}`, http.StatusBadRequest)
+				return
+			case "down":
+				http.Error(w, "upstream down", http.StatusBadGateway)
+				return
+			}
+			io.WriteString(w, `{"items":[{"id":5,"name":"Ink","type":"LORA","modelVersions":[
+				{"id":124,"name":"v2","baseModel":"SDXL 1.0"},{"id":123,"name":"v1","baseModel":"SDXL 1.0"}]}],"metadata":{}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(host.Close)
+
+	var addrs []string
+	for range n {
+		w := fakeworker.New(0)
+		addr, err := w.Start("127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(w.Stop)
+		f.workers = append(f.workers, w)
+		addrs = append(addrs, addr)
+	}
+	f.worker = f.workers[0]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool, err := dependencies.NewPool(addrs, 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Run(ctx)
+
+	hist, err := history.Open(filepath.Join(dir, "history.db"), filepath.Join(dir, "outputs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumbs, err := imaging.NewThumbCache(filepath.Join(dir, "thumbs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lib, err := library.Open(filepath.Join(dir, "library.db"), map[library.Kind]string{
+		library.KindCheckpoint: models,
+		library.KindLora:       loras,
+		library.KindEmbedding:  embeddings,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hub := NewHub()
+	dl := NewDownloaderService(hub, lib, config.ApiDlConfig{
+		BaseDir:       dir,
+		QuarantineDir: filepath.Join(dir, "quarantine"),
+		QueueSize:     1,
+		MaxConcurrent: 1,
+		Policy:        config.ApiDlPolicyConfig{RequireCleanScans: true, SafetensorsOnly: true},
+		Client: config.ApiDlClientConfig{
+			ApiKey:      "test",
+			DownloadUrl: host.URL + "/download/{id}",
+			ModeInfoUrl: host.URL + "/versions/{id}",
+			SearchUrl:   host.URL + "/search",
+			ModelUrl:    host.URL + "/models/{id}",
+		},
+	}, ctx)
+	gen := NewGenerationService(hub, pool, hist, modelhash.NewCache(), config.ApiGenConfig{QueueSize: 4, MaxConcurrent: 1}, ctx)
+	gen.CheckLoras(NewLoraCompat(lib, dl))
+	gen.Run()
+	dl.Run()
+
+	f.api = NewApi(pool, config.ApiConfig{}, hub, dl, gen, hist, thumbs, lib)
+	f.api.addRoutes()
+
+	t.Cleanup(func() {
+		cancel()
+		gen.Shutdown()
+		dl.Shutdown()
+		pool.Close()
+		hist.Close()
+		lib.Close()
+	})
+	return f
+}
+
+func (f *restFixture) do(t *testing.T, method, path string, body any) *http.Response {
+	t.Helper()
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = bytes.NewReader(b)
+	}
+	req := httptest.NewRequest(method, path, r)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := f.api.server.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	return resp
+}
+
+// expect checks the status and decodes a JSON body into out when it's set.
+func (f *restFixture) expect(t *testing.T, method, path string, body any, code int, out any) *http.Response {
+	t.Helper()
+	resp := f.do(t, method, path, body)
+	if resp.StatusCode != code {
+		b, _ := io.ReadAll(resp.Body)
+		t.Fatalf("%s %s: status %d, want %d: %s", method, path, resp.StatusCode, code, b)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decode: %v", method, path, err)
+		}
+	}
+	return resp
+}
+
+func TestRestHandlersAgainstFakeWorker(t *testing.T) {
+	f := newRestFixture(t)
+
+	var health types.HealthResponse
+	f.expect(t, "GET", "/health", nil, http.StatusOK, &health)
+	if !health.Worker.Available || len(health.Worker.Workers) != 1 {
+		t.Fatalf("health = %+v, want one available worker", health.Worker)
+	}
+
+	var models types.ListModelsResponse
+	f.expect(t, "GET", "/models", nil, http.StatusOK, &models)
+	if len(models.ModelPaths) != 1 || models.ModelPaths[0] != f.model {
+		t.Fatalf("models = %v, want [%s]", models.ModelPaths, f.model)
+	}
+	var loras types.ListLorasResponse
+	f.expect(t, "GET", "/loras", nil, http.StatusOK, &loras)
+	if len(loras.LoraPaths) != 1 || loras.LoraPaths[0] != f.lora {
+		t.Fatalf("loras = %v, want [%s]", loras.LoraPaths, f.lora)
+	}
+	var embeddings types.LibraryListResponse
+	f.expect(t, "GET", "/embeddings", nil, http.StatusOK, &embeddings)
+	if len(embeddings.Items) != 1 || embeddings.Items[0].Path != f.embedding || embeddings.Items[0].Kind != "embedding" || embeddings.Items[0].BaseModel != "SDXL-1.0" {
+		t.Fatalf("embeddings = %+v, want [%s]", embeddings.Items, f.embedding)
+	}
+	var vaes types.LibraryListResponse
+	f.expect(t, "GET", "/vaes", nil, http.StatusOK, &vaes)
+	if vaes.Total != 0 {
+		t.Fatalf("vaes = %+v, want none without a root", vaes.Items)
+	}
+	f.expect(t, "GET", "/library?type=hypernetwork", nil, http.StatusBadRequest, nil)
+
+	prompt := types.ImagePostRequest{PositivePrompt: "a red fox", Steps: 2, Width: 256, Height: 256}
+	f.expect(t, "POST", "/generateimage", prompt, http.StatusBadRequest, nil)
+	f.expect(t, "POST", "/setloras", []types.SetLora{{Path: f.lora, Weight: 0.8}}, http.StatusBadRequest, nil)
+
+	var missing types.ErrorResponse
+	f.expect(t, "POST", "/setmodel", types.SetModelRequest{ModelPath: f.model + ".missing"}, http.StatusNotFound, &missing)
+	if missing.Version != 1 || missing.Code != CodeNotFound || missing.Retryable || strings.HasPrefix(missing.Error, "rpc error") {
+		t.Fatalf("missing model error = %+v, want a not_found envelope without the rpc prefix", missing)
+	}
+	var set types.SetModelResponse
+	f.expect(t, "POST", "/setmodel", types.SetModelRequest{ModelPath: f.model}, http.StatusOK, &set)
+	if set.ModelPath != f.model {
+		t.Fatalf("set model = %q, want %q", set.ModelPath, f.model)
+	}
+	var current types.CurrentModelResponse
+	f.expect(t, "GET", "/currentmodel", nil, http.StatusOK, &current)
+	if current.ModelPath != f.model {
+		t.Fatalf("current model = %q, want %q", current.ModelPath, f.model)
+	}
+
+	f.expect(t, "POST", "/setloras", []types.SetLora{{Path: f.lora, Weight: 0.05}}, http.StatusBadRequest, nil)
+	var applied []types.SetLora
+	f.expect(t, "POST", "/setloras", []types.SetLora{{Path: f.lora, Weight: 0.8}, {Path: f.lora + ".missing", Weight: 1}}, http.StatusOK, &applied)
+	if len(applied) != 1 || applied[0].Path != f.lora {
+		t.Fatalf("applied = %+v, want %s", applied, f.lora)
+	}
+	// The lora has no sidecar yet; its metadata is fetched in the background
+	// and served locally from then on.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var currentLoras []types.SetLora
+		f.expect(t, "GET", "/currentloras", nil, http.StatusOK, &currentLoras)
+		if len(currentLoras) != 1 || currentLoras[0].Weight != 0.8 {
+			t.Fatalf("current loras = %+v, want the applied lora", currentLoras)
+		}
+		if tw := currentLoras[0].TriggerWords; tw != nil && *tw == "ink,sketch" && currentLoras[0].BaseModel == "SDXL 1.0" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("current loras = %+v, want the trigger words and base model from the sidecar", currentLoras)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if _, err := os.Stat(metadata.SidecarPath(f.lora)); err != nil {
+		t.Fatalf("sidecar: %v", err)
+	}
+
+	seed := int64(42)
+	prompt.Seed = &seed
+	resp := f.expect(t, "POST", "/generateimage", prompt, http.StatusOK, nil)
+	if resp.Header.Get("X-Seed") != "42" || resp.Header.Get("X-History-Id") == "" {
+		t.Fatalf("headers = %v, want the seed and a history id", resp.Header)
+	}
+	img, err := png.Decode(resp.Body)
+	if err != nil {
+		t.Fatalf("decode png: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 256 || b.Dy() != 256 {
+		t.Fatalf("image is %v, want 256x256", b)
+	}
+
+	var job types.GenerationResponse
+	f.expect(t, "POST", "/generations", GenerationRequest{ImagePostRequest: prompt}, http.StatusBadRequest, nil)
+	f.expect(t, "POST", "/generations", GenerationRequest{ClientID: "c1", ImagePostRequest: prompt}, http.StatusAccepted, &job)
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		resp := f.do(t, "GET", "/generations/"+job.JobID, nil)
+		if resp.StatusCode == http.StatusOK && strings.HasPrefix(resp.Header.Get("Content-Type"), "image/png") {
+			break
+		}
+		if resp.StatusCode != http.StatusAccepted || time.Now().After(deadline) {
+			b, _ := io.ReadAll(resp.Body)
+			t.Fatalf("generation %s: status %d: %s", job.JobID, resp.StatusCode, b)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	f.expect(t, "GET", "/generations/unknown", nil, http.StatusNotFound, nil)
+	f.expect(t, "GET", "/generations/"+job.JobID+"?format=png&quality=80", nil, http.StatusBadRequest, nil)
+	f.expect(t, "GET", "/generations/"+job.JobID+"?format=webp&quality=80", nil, http.StatusBadRequest, nil)
+	resp = f.expect(t, "GET", "/generations/"+job.JobID+"?format=webp", nil, http.StatusOK, nil)
+	if ct := resp.Header.Get("Content-Type"); ct != "image/webp" {
+		t.Fatalf("content type = %q, want image/webp", ct)
+	}
+	if img, err := webp.Decode(resp.Body); err != nil || img.Bounds().Dx() != 256 {
+		t.Fatalf("webp = %v (%v), want a 256px wide image", img, err)
+	}
+	if resp := f.expect(t, "GET", "/generations/"+job.JobID+"?format=jpeg&quality=80", nil, http.StatusOK, nil); resp.Header.Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("content type = %q, want image/jpeg", resp.Header.Get("Content-Type"))
+	}
+
+	var cleared []types.SetLora
+	f.expect(t, "POST", "/clearloras", nil, http.StatusOK, &cleared)
+	if len(cleared) != 1 {
+		t.Fatalf("cleared loras = %+v, want the applied lora", cleared)
+	}
+	var clearedModel types.ClearModelResponse
+	f.expect(t, "POST", "/clearmodel", nil, http.StatusOK, &clearedModel)
+	if clearedModel.ModelPath != f.model {
+		t.Fatalf("cleared model = %q, want %q", clearedModel.ModelPath, f.model)
+	}
+	f.expect(t, "GET", "/currentmodel", nil, http.StatusOK, &current)
+	if current.ModelPath != "" {
+		t.Fatalf("current model = %q after clear, want none", current.ModelPath)
+	}
+
+	f.expect(t, "POST", "/download", DownloadRequest{ModelVersionID: 123}, http.StatusBadRequest, nil)
+	f.expect(t, "POST", "/download", DownloadRequest{ClientID: "c1"}, http.StatusBadRequest, nil)
+	var denied types.ErrorResponse
+	f.expect(t, "POST", "/download", DownloadRequest{ClientID: "c1", ModelVersionID: 123, OverridePolicy: true}, http.StatusForbidden, &denied)
+	if denied.Code != CodePermissionDenied {
+		t.Fatalf("override error = %+v, want permission_denied", denied)
+	}
+	f.expect(t, "POST", "/download", DownloadRequest{ClientID: "c1", ModelVersionID: 123}, http.StatusAccepted, &job)
+}
+
+func TestRestHandlersWorkerDown(t *testing.T) {
+	f := newRestFixture(t)
+	f.worker.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var health types.HealthResponse
+		f.expect(t, "GET", "/health", nil, http.StatusOK, &health)
+		if !health.Worker.Available {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("health = %+v, want the worker reported unavailable", health.Worker)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	var unavailable types.ErrorResponse
+	resp := f.expect(t, "GET", "/currentmodel", nil, http.StatusServiceUnavailable, &unavailable)
+	if resp.Header.Get("Retry-After") == "" {
+		t.Fatal("503 without Retry-After")
+	}
+	if unavailable.Code != CodeUnavailable || !unavailable.Retryable {
+		t.Fatalf("unavailable error = %+v, want a retryable unavailable envelope", unavailable)
+	}
+	f.expect(t, "POST", "/generateimage", types.ImagePostRequest{PositivePrompt: "a red fox"}, http.StatusServiceUnavailable, nil)
+	f.expect(t, "GET", "/models", nil, http.StatusOK, nil)
+}
+
+// writeSafetensors writes a file holding only a header with the given
+// tensors (name => shape) and metadata.
+func writeSafetensors(t *testing.T, path string, tensors map[string][]int64, meta map[string]string) {
+	t.Helper()
+	header := map[string]any{"__metadata__": meta}
+	for name, shape := range tensors {
+		header[name] = map[string]any{"dtype": "F16", "shape": shape, "data_offsets": []int64{0, 0}}
+	}
+	js, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := append(binary.LittleEndian.AppendUint64(nil, uint64(len(js))), js...)
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestModelInfo(t *testing.T) {
+	f := newRestFixture(t)
+	lora := filepath.Join(filepath.Dir(f.lora), "456-pencil.safetensors")
+	writeSafetensors(t, lora, map[string][]int64{
+		"lora_unet_input_blocks_4_1_transformer_blocks_0_attn2_to_k.lora_down.weight": {32, 2048},
+	}, map[string]string{
+		"ss_network_dim":   "32",
+		"ss_tag_frequency": `{"10_pencil": {"pencil": 7}}`,
+	})
+
+	var info types.ModelInfoResponse
+	f.expect(t, "GET", "/loras/info?path="+lora, nil, http.StatusOK, &info)
+	if info.Type != "lora" || info.Arch != "sdxl" || info.Precision != "F16" || info.Tensors != 1 {
+		t.Fatalf("info = %+v, want an F16 SDXL lora", info)
+	}
+	if info.Training == nil || info.Training.NetworkDim != 32 || len(info.Training.Tags) != 1 || info.Training.Tags[0].Tag != "pencil" {
+		t.Fatalf("training = %+v", info.Training)
+	}
+	if _, ok := info.Metadata["ss_tag_frequency"]; ok {
+		t.Fatal("raw tag frequency in metadata")
+	}
+
+	f.expect(t, "GET", "/models/info?path="+lora, nil, http.StatusBadRequest, nil)
+	f.expect(t, "GET", "/loras/info?path="+lora+"/../../secret.safetensors", nil, http.StatusBadRequest, nil)
+	f.expect(t, "GET", "/models/info?path="+f.model, nil, http.StatusBadRequest, nil)
+	f.expect(t, "GET", "/loras/info?path="+f.lora+".missing.safetensors", nil, http.StatusNotFound, nil)
+}
+
+func TestSetLorasChecksArchitecture(t *testing.T) {
+	f := newRestFixture(t)
+	writeSafetensors(t, f.model, map[string][]int64{
+		"model.diffusion_model.input_blocks.4.1.transformer_blocks.0.attn2.to_k.weight": {640, 2048},
+		"conditioner.embedders.1.model.ln_final.weight":                                 {1280},
+	}, nil)
+	sd15 := filepath.Join(filepath.Dir(f.lora), "sd15.safetensors")
+	writeSafetensors(t, sd15, map[string][]int64{
+		"lora_unet_down_blocks_0_attentions_0_transformer_blocks_0_attn2_to_k.lora_down.weight": {16, 768},
+	}, nil)
+	sdxl := filepath.Join(filepath.Dir(f.lora), "sdxl.safetensors")
+	writeSafetensors(t, sdxl, map[string][]int64{
+		"lora_unet_input_blocks_4_1_transformer_blocks_0_attn2_to_k.lora_down.weight": {32, 2048},
+	}, nil)
+	f.expect(t, "POST", "/setmodel", types.SetModelRequest{ModelPath: f.model}, http.StatusOK, nil)
+
+	var rejected struct {
+		types.ErrorResponse
+		Details []types.LoraIncompatibility `json:"details"`
+	}
+	loras := []types.SetLora{{Path: sdxl, Weight: 1}, {Path: sd15, Weight: 1}, {Path: f.lora, Weight: 1}}
+	f.expect(t, "POST", "/setloras", loras, http.StatusBadRequest, &rejected)
+	if rejected.Code != CodeFailedPrecondition || len(rejected.Details) != 1 {
+		t.Fatalf("rejection = %+v, want one failed_precondition detail", rejected)
+	}
+	if d := rejected.Details[0]; d.Path != sd15 || d.LoraArch != "sd1" || d.ModelArch != "sdxl" || d.Reason == "" {
+		t.Fatalf("detail = %+v, want the SD1 lora explained", d)
+	}
+	var current []types.SetLora
+	f.expect(t, "GET", "/currentloras", nil, http.StatusOK, &current)
+	if len(current) != 0 {
+		t.Fatalf("current loras = %+v after a rejection, want none", current)
+	}
+
+	var applied []types.SetLora
+	f.expect(t, "POST", "/setloras?force=true", loras, http.StatusOK, &applied)
+	if len(applied) != 3 {
+		t.Fatalf("applied = %+v, want all three loras with force", applied)
+	}
+
+	// A generation's own stack is checked against the model it runs on.
+	prompt := types.ImagePostRequest{PositivePrompt: "a red fox", Steps: 2, Width: 256, Height: 256, ModelPath: f.model, Loras: []types.SetLora{{Path: sd15, Weight: 1}}}
+	f.expect(t, "POST", "/generateimage", prompt, http.StatusBadRequest, &rejected)
+	if rejected.Code != CodeFailedPrecondition || len(rejected.Details) != 1 || rejected.Details[0].Path != sd15 || rejected.Details[0].LoraArch != "sd1" {
+		t.Fatalf("generation rejection = %+v, want the SD1 lora as failed_precondition like /setloras", rejected)
+	}
+
+	// A queued job fails the same way, and its status still reports the seed
+	// and batch size it was resolved with.
+	seed := int64(42)
+	prompt.Seed, prompt.BatchSize = &seed, 2
+	var job types.GenerationResponse
+	f.expect(t, "POST", "/generations", GenerationRequest{ClientID: "c1", ImagePostRequest: prompt}, http.StatusAccepted, &job)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var st types.GenerationStatusResponse
+		resp := f.do(t, "GET", "/generations/"+job.JobID, nil)
+		if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+			t.Fatal(err)
+		}
+		if st.Seed == nil || *st.Seed != 42 || st.ImageCount != 2 {
+			t.Fatalf("status = %+v, want seed 42 and two images", st)
+		}
+		if st.Status == GenerationFailed {
+			if st.Code != CodeFailedPrecondition || st.Details == nil {
+				t.Fatalf("status = %+v, want the job failed as failed_precondition with details", st)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status = %+v, want the job failed", st)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestCatalogSearch(t *testing.T) {
+	f := newRestFixture(t)
+
+	var res huggingface.CatalogSearch
+	f.expect(t, "GET", "/catalog/search?q=ink&type=lora&sort=newest", nil, http.StatusOK, &res)
+	if len(res.Items) != 1 || len(res.Items[0].Versions) != 2 {
+		t.Fatalf("search = %+v", res)
+	}
+	if v := res.Items[0].Versions[0]; v.ID != 124 || v.Local {
+		t.Fatalf("new version = %+v", v)
+	}
+	if v := res.Items[0].Versions[1]; v.ID != 123 || !v.Local || v.LocalPath != f.lora {
+		t.Fatalf("downloaded version = %+v; want it marked local", v)
+	}
+
+	f.expect(t, "GET", "/catalog/search?type=vae", nil, http.StatusBadRequest, nil)
+	f.expect(t, "GET", "/catalog/search?limit=500", nil, http.StatusBadRequest, nil)
+
+	// The host's own 4xx replies keep their meaning; only its 5xx replies are
+	// reported as a retryable outage.
+	var rejected, down types.ErrorResponse
+	f.expect(t, "GET", "/catalog/search?q=bad", nil, http.StatusBadRequest, &rejected)
+	if rejected.Code != CodeInvalidArgument || rejected.Retryable {
+		t.Errorf("rejected search = %+v", rejected)
+	}
+	f.expect(t, "GET", "/catalog/search?q=down", nil, http.StatusServiceUnavailable, &down)
+	if down.Code != CodeUnavailable || !down.Retryable {
+		t.Errorf("failed search = %+v", down)
+	}
+}
+
+func TestDownloadByModelID(t *testing.T) {
+	f := newRestFixture(t)
+
+	for _, tc := range []struct {
+		body map[string]any
+		want int64
+	}{
+		{map[string]any{"modelId": 5}, 126},
+		{map[string]any{"modelId": 5, "baseModel": "SDXL-1.0"}, 124},
+		{map[string]any{"modelId": 5, "select": "name", "versionName": "V1"}, 123},
+	} {
+		tc.body["clientId"] = fmt.Sprint("c", tc.want)
+		var resp types.DownloadResponse
+		f.expect(t, "POST", "/download", tc.body, http.StatusAccepted, &resp)
+		if resp.JobID == "" || resp.ModelVersionID != tc.want {
+			t.Errorf("download %v = %+v, want version %d", tc.body, resp, tc.want)
+		}
+	}
+
+	f.expect(t, "POST", "/download", map[string]any{"clientId": "c", "modelId": 5, "versionName": "v9"}, http.StatusNotFound, nil)
+	f.expect(t, "POST", "/download", map[string]any{"clientId": "c", "modelId": 6}, http.StatusNotFound, nil)
+	f.expect(t, "POST", "/download", map[string]any{"clientId": "c", "modelId": 5, "select": "baseModel"}, http.StatusBadRequest, nil)
+	f.expect(t, "POST", "/download", map[string]any{"clientId": "c", "modelId": 5, "modelVersionId": 123}, http.StatusBadRequest, nil)
+}
+
+func TestCatalogVersionFiles(t *testing.T) {
+	f := newRestFixture(t)
+
+	var res huggingface.CatalogVersionFiles
+	f.expect(t, "GET", "/catalog/versions/123/files", nil, http.StatusOK, &res)
+	if res.ModelVersionID != 123 || len(res.Files) != 2 {
+		t.Fatalf("files = %+v", res)
+	}
+	if ok := res.Files[0]; ok.ID != 1 || ok.SizeKB != 1024 || ok.Fp != "fp16" || ok.PickleScan != "Success" || len(ok.Rejections) != 0 {
+		t.Fatalf("clean file = %+v", ok)
+	}
+	if bad := res.Files[1]; bad.PickleScan != "Danger" || len(bad.Rejections) != 2 {
+		t.Fatalf("pickle file = %+v; want a scan and a format rejection", bad)
+	}
+
+	f.expect(t, "GET", "/catalog/versions/999/files", nil, http.StatusNotFound, nil)
+	f.expect(t, "GET", "/catalog/versions/abc/files", nil, http.StatusBadRequest, nil)
+	f.expect(t, "POST", "/download", map[string]any{"clientId": "c", "modelVersionId": 123, "fileIds": []int64{1}, "prefer": map[string]string{"fp": "fp16"}}, http.StatusBadRequest, nil)
+}
+
+func TestSetLorasChecksEveryWorker(t *testing.T) {
+	f := newPoolFixture(t, 2)
+	writeSafetensors(t, f.model, map[string][]int64{
+		"model.diffusion_model.input_blocks.4.1.transformer_blocks.0.attn2.to_k.weight": {640, 2048},
+		"conditioner.embedders.1.model.ln_final.weight":                                 {1280},
+	}, nil)
+	sd15Model := filepath.Join(filepath.Dir(f.model), "sd15.safetensors")
+	writeSafetensors(t, sd15Model, map[string][]int64{
+		"model.diffusion_model.input_blocks.1.1.transformer_blocks.0.attn2.to_k.weight": {320, 768},
+	}, nil)
+	sdxl := filepath.Join(filepath.Dir(f.lora), "sdxl.safetensors")
+	writeSafetensors(t, sdxl, map[string][]int64{
+		"lora_unet_input_blocks_4_1_transformer_blocks_0_attn2_to_k.lora_down.weight": {32, 2048},
+	}, nil)
+
+	// The first worker has the SDXL model, the second an SD1 one.
+	f.expect(t, "POST", "/setmodel", types.SetModelRequest{ModelPath: f.model}, http.StatusOK, nil)
+	if _, err := f.workers[1].SetModel(context.Background(), &proto.SetModelRequest{ModelPath: sd15Model}); err != nil {
+		t.Fatal(err)
+	}
+
+	var rejected struct {
+		types.ErrorResponse
+		Details []types.LoraIncompatibility `json:"details"`
+	}
+	f.expect(t, "POST", "/setloras", []types.SetLora{{Path: sdxl, Weight: 1}}, http.StatusBadRequest, &rejected)
+	if len(rejected.Details) != 1 || rejected.Details[0].LoraArch != "sdxl" || rejected.Details[0].ModelArch != "sd1" {
+		t.Fatalf("rejection = %+v, want the SDXL lora refused for the SD1 worker", rejected)
+	}
+}
+
+func TestGuidanceScaleZero(t *testing.T) {
+	f := newRestFixture(t)
+	f.expect(t, "POST", "/setmodel", types.SetModelRequest{ModelPath: f.model}, http.StatusOK, nil)
+
+	for _, tc := range []struct {
+		body map[string]any
+		want float32
+		text string
+	}{
+		{map[string]any{"positivePrompt": "a red fox", "steps": 2, "width": 256, "height": 256, "guidanceScale": 0}, 0, "CFG scale: 0,"},
+		{map[string]any{"positivePrompt": "a red fox", "steps": 2, "width": 256, "height": 256}, defaultGuidanceScale, "CFG scale: 7,"},
+	} {
+		resp := f.expect(t, "POST", "/generateimage", tc.body, http.StatusOK, nil)
+		if got := f.worker.GuidanceScale(); got != tc.want {
+			t.Errorf("%v: worker ran with guidance %v, want %v", tc.body, got, tc.want)
+		}
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text, _ := imaging.Text(b, imaging.ParametersKey); !strings.Contains(text, tc.text) {
+			t.Errorf("%v: parameters = %q, want %q", tc.body, text, tc.text)
+		}
+	}
+}
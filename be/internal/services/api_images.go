@@ -0,0 +1,140 @@
+package services
+
+import (
+	"be/internal/imaging"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const defaultThumbWidth = 256
+
+// imageOutput holds the query options shared by every endpoint that serves
+// an image: format, quality and stripMetadata.
+type imageOutput struct {
+	format  imaging.Format // empty keeps the stored format
+	quality int
+	strip   bool
+}
+
+// parseImageOutput reads the options. def is the format used when the query
+// names none; empty keeps the stored one. quality only applies to JPEG and
+// is refused for any other output rather than silently ignored.
+func parseImageOutput(ctx *fiber.Ctx, def imaging.Format) (imageOutput, error) {
+	out := imageOutput{
+		format:  def,
+		quality: ctx.QueryInt("quality", imaging.DefaultQuality),
+		strip:   ctx.QueryBool("stripMetadata"),
+	}
+	if out.quality < 1 || out.quality > 100 {
+		return out, InvalidParamsError{"quality", "must be between 1 and 100"}
+	}
+	if v := ctx.Query("format"); v != "" {
+		f, err := imaging.ParseFormat(v)
+		if err != nil {
+			return out, InvalidParamsError{"format", err.Error()}
+		}
+		out.format = f
+	}
+	if ctx.Query("quality") != "" && out.format != imaging.FormatJPEG {
+		return out, InvalidParamsError{"quality", "only applies to format=jpeg"}
+	}
+	return out, nil
+}
+
+// render applies the options to a stored image and returns the bytes to send
+// along with their MIME type and file name.
+func (o imageOutput) render(img []byte, mimeType, filename string) ([]byte, string, string, error) {
+	if o.strip && imaging.IsPNG(img) {
+		stripped, err := imaging.StripText(img)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("strip metadata: %w", err)
+		}
+		img = stripped
+	}
+	if current, ok := imaging.FormatOf(mimeType); o.format == "" || (ok && current == o.format) {
+		return img, mimeType, filename, nil
+	}
+
+	converted, err := imaging.Convert(img, o.format, o.quality)
+	if err != nil {
+		return nil, "", "", err
+	}
+	filename = strings.TrimSuffix(filename, filepath.Ext(filename)) + o.format.Ext()
+	return converted, o.format.MimeType(), filename, nil
+}
+
+// send writes an image response, rendering it with the options first.
+func (o imageOutput) send(ctx *fiber.Ctx, img []byte, mimeType, filename string) error {
+	body, mimeType, filename, err := o.render(img, mimeType, filename)
+	if err != nil {
+		return err
+	}
+	ctx.Set(fiber.HeaderContentType, mimeType)
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf("inline; filename=%s", filename))
+	ctx.Response().SetBodyRaw(body)
+	return nil
+}
+
+// Thumbnail serves a downscaled copy of a history image. Thumbnails are
+// rendered once per id, index, width, format and quality and then served
+// from the cache.
+func (a *Api) Thumbnail() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		logger := HttpLogger("Thumbnail", ctx)
+		if a.history == nil || a.thumbs == nil {
+			logger.Error("history not configured")
+			return a.fail(ctx, internalError(errors.New("history not configured")), "service unavailable")
+		}
+
+		width := ctx.QueryInt("w", defaultThumbWidth)
+		if width < imaging.MinThumbWidth || width > imaging.MaxThumbWidth {
+			logger.Warn("invalid thumbnail width", "w", width)
+			return a.fail(ctx, invalidArgument(fmt.Errorf("w must be between %d and %d", imaging.MinThumbWidth, imaging.MaxThumbWidth)), "invalid query")
+		}
+		out, err := parseImageOutput(ctx, imaging.FormatJPEG)
+		if err != nil {
+			logger.Warn("invalid thumbnail query", "err", err)
+			return a.fail(ctx, invalidArgument(err), "invalid query")
+		}
+
+		id := strings.TrimSpace(ctx.Params("id"))
+		rec, err := a.history.Get(id)
+		if err != nil {
+			return a.historyLookupError(ctx, logger, id, err)
+		}
+		index := ctx.QueryInt("index", 0)
+		path, err := a.history.ImagePath(rec, index)
+		if err != nil {
+			logger.Warn("history image index out of range", "id", id, "index", index, "images", len(rec.Images))
+			return a.fail(ctx, notFound(err), "unknown history image")
+		}
+
+		key := fmt.Sprintf("%s-%d-w%d-q%d%s", rec.ID, index, width, out.quality, out.format.Ext())
+		thumb, ok := a.thumbs.Get(key)
+		if !ok {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				logger.Error("history image read failed", "id", id, "index", index, "err", err)
+				return a.fail(ctx, internalError(err), "failed to read history image")
+			}
+			if thumb, err = imaging.Thumbnail(src, width, out.format, out.quality); err != nil {
+				logger.Error("thumbnail failed", "id", id, "index", index, "err", err)
+				return a.fail(ctx, internalError(err), "failed to render thumbnail")
+			}
+			if err := a.thumbs.Put(key, thumb); err != nil {
+				logger.Warn("thumbnail cache write failed", "key", key, "err", err)
+			}
+		}
+
+		logger.Debug("thumbnail served", "id", id, "index", index, "w", width, "cached", ok, "bytes", len(thumb))
+		ctx.Set(fiber.HeaderContentType, out.format.MimeType())
+		ctx.Set(fiber.HeaderCacheControl, "public, max-age=86400")
+		ctx.Response().SetBodyRaw(thumb)
+		return nil
+	}
+}
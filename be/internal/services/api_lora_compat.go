@@ -0,0 +1,139 @@
+package services
+
+import (
+	"be/internal/dependencies"
+	"be/internal/library"
+	"be/internal/safetensors"
+	"be/types"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var archLabels = map[safetensors.Arch]string{
+	safetensors.ArchSD1:  "SD 1.x",
+	safetensors.ArchSD2:  "SD 2.x",
+	safetensors.ArchSDXL: "SDXL",
+	safetensors.ArchSD3:  "SD 3",
+	safetensors.ArchFlux: "Flux",
+}
+
+// IncompatibleLorasError is returned when LoRAs were trained for a different
+// architecture than the loaded model. Every rejected LoRA is listed, not just
+// the first one.
+type IncompatibleLorasError struct {
+	Loras []types.LoraIncompatibility
+}
+
+func (e IncompatibleLorasError) Error() string {
+	names := make([]string, 0, len(e.Loras))
+	for _, l := range e.Loras {
+		names = append(names, filepath.Base(l.Path))
+	}
+	return fmt.Sprintf("loras incompatible with the loaded model: %s", strings.Join(names, ", "))
+}
+
+func (e IncompatibleLorasError) ErrorDetails() any { return e.Loras }
+
+// LoraCompat tells whether LoRAs were trained for the architecture of a
+// checkpoint. It is shared by /setloras and the generation queue, which
+// applies per-request LoRA stacks itself.
+type LoraCompat struct {
+	library *library.Library
+	dl      *DownloaderService
+}
+
+func NewLoraCompat(lib *library.Library, dl *DownloaderService) *LoraCompat {
+	return &LoraCompat{library: lib, dl: dl}
+}
+
+// checkLoraCompat checks the LoRAs against the model of every healthy
+// worker, since /setloras applies them to all of them and each worker can
+// have a different checkpoint loaded. A LoRA that doesn't fit several
+// workers is listed once per model.
+func (a *Api) checkLoraCompat(loras []types.SetLora) error {
+	workers := a.workers.Healthy()
+	if len(workers) == 0 {
+		return dependencies.ErrNoHealthyWorkers
+	}
+	var rejected []types.LoraIncompatibility
+	for _, w := range workers {
+		current, err := w.GetCurrentModel()
+		if err != nil {
+			return fmt.Errorf("worker %s: %w", w.Addr, err)
+		}
+		var incompatible IncompatibleLorasError
+		if err := a.compat.Check(current.ModelPath, loras); errors.As(err, &incompatible) {
+			for _, l := range incompatible.Loras {
+				if !slices.Contains(rejected, l) {
+					rejected = append(rejected, l)
+				}
+			}
+		}
+	}
+	if len(rejected) > 0 {
+		return IncompatibleLorasError{Loras: rejected}
+	}
+	return nil
+}
+
+// Check compares the architecture of each LoRA with the model at modelPath.
+// LoRAs whose architecture can't be told are let through, as is everything
+// when no model is given; the worker has the final word on those.
+func (c *LoraCompat) Check(modelPath string, loras []types.SetLora) error {
+	if c == nil || modelPath == "" {
+		return nil
+	}
+	modelArch := c.fileArch(library.KindCheckpoint, modelPath)
+	if modelArch == safetensors.ArchUnknown {
+		return nil
+	}
+
+	var rejected []types.LoraIncompatibility
+	for _, l := range loras {
+		loraArch := c.fileArch(library.KindLora, l.Path)
+		if loraArch == safetensors.ArchUnknown || loraArch == modelArch {
+			continue
+		}
+		rejected = append(rejected, types.LoraIncompatibility{
+			Path:      l.Path,
+			LoraArch:  string(loraArch),
+			ModelArch: string(modelArch),
+			Reason: fmt.Sprintf("%s LoRA can't be applied to the loaded %s model %s",
+				archLabels[loraArch], archLabels[modelArch], filepath.Base(modelPath)),
+		})
+	}
+	if len(rejected) > 0 {
+		return IncompatibleLorasError{Loras: rejected}
+	}
+	return nil
+}
+
+// fileArch reads the architecture from the safetensors header and falls back
+// to the downloaded metadata, then to the base model folder the file was
+// filed under. Paths outside the library roots are never opened.
+func (c *LoraCompat) fileArch(kind library.Kind, path string) safetensors.Arch {
+	if c.library == nil {
+		return safetensors.ArchUnknown
+	}
+	path, err := c.library.Resolve(kind, path)
+	if err != nil {
+		return safetensors.ArchUnknown
+	}
+	if info, err := safetensors.InspectFile(path); err == nil && info.Arch != safetensors.ArchUnknown {
+		return info.Arch
+	}
+	if c.dl != nil {
+		if info, ok := c.dl.Metadata(path); ok {
+			if arch := safetensors.ArchFromName(info.BaseModel); arch != safetensors.ArchUnknown {
+				return arch
+			}
+		}
+	}
+	if e, err := c.library.Get(path); err == nil {
+		return safetensors.ArchFromName(e.BaseModel)
+	}
+	return safetensors.ArchUnknown
+}
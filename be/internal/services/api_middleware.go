@@ -1,6 +1,8 @@
 package services
 
 import (
+	"be/internal/dependencies"
+
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 )
@@ -13,3 +15,15 @@ func (a *Api) WsUpgrade() fiber.Handler {
 		return fiber.ErrUpgradeRequired
 	}
 }
+
+// RequireWorker rejects requests to RPC-backed endpoints while no worker is
+// reachable, instead of letting them wait on a dead connection.
+func (a *Api) RequireWorker() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if a.workers != nil && len(a.workers.Healthy()) > 0 {
+			return c.Next()
+		}
+		HttpLogger("RequireWorker", c).Warn("worker unavailable", "retryAfter", a.retryAfter())
+		return a.fail(c, dependencies.ErrNoHealthyWorkers, "python worker unavailable")
+	}
+}
@@ -3,6 +3,9 @@ package services
 import (
 	"be/config"
 	"be/internal/clients/huggingface"
+	"be/internal/library"
+	"be/internal/metadata"
+	"be/types"
 	"context"
 	"errors"
 	"fmt"
@@ -11,6 +14,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"golang.org/x/sync/errgroup"
@@ -19,12 +23,34 @@ import (
 type DownloadRequest struct {
 	ClientID       string `json:"clientId"`
 	ModelVersionID int64  `json:"modelVersionId"`
+	// ModelID downloads a version of a whole model instead, picked by Select:
+	// latest (the default), baseModel (the newest for BaseModel) or name (the
+	// version called VersionName). Select is inferred when only BaseModel or
+	// VersionName is set.
+	ModelID     int64  `json:"modelId,omitempty"`
+	Select      string `json:"select,omitempty"`
+	BaseModel   string `json:"baseModel,omitempty"`
+	VersionName string `json:"versionName,omitempty"`
+	// FileIDs downloads these files of the version, Prefer the model file
+	// that best matches it; by default the primary file is downloaded.
+	FileIDs []int64         `json:"fileIds,omitempty"`
+	Prefer  *FilePreference `json:"prefer,omitempty"`
+	// OverridePolicy downloads the file even when the download policy refuses
+	// it. It needs the admin token in the X-Admin-Token header.
+	OverridePolicy bool `json:"overridePolicy,omitempty"`
 }
 
 type DownloadJob struct {
 	JobID          string
 	ClientID       string
 	ModelVersionID int64
+	OverridePolicy bool
+	// Upgrades is the file this download is a newer version of; with
+	// RemoveOld it is deleted once the new version is in place.
+	Upgrades  string
+	RemoveOld bool
+	FileIDs   []int64
+	Prefer    *FilePreference
 }
 
 var (
@@ -33,8 +59,13 @@ var (
 )
 
 type DownloaderService struct {
-	hub     *Hub
-	baseDir string
+	hub         *Hub
+	library     *library.Library
+	baseDir     string
+	quarantine  string
+	policy      DownloadPolicy
+	backfill    time.Duration
+	updateCheck time.Duration
 
 	queue chan DownloadJob
 	group errgroup.Group
@@ -42,10 +73,25 @@ type DownloaderService struct {
 	mu      sync.RWMutex
 	closing bool
 	client  *huggingface.Hf
+	meta    *metadata.Store
 	ctx     context.Context
 	logger  *log.Logger
 
 	inflight map[string]string // key: clientId:modelVersionId => jobId
+
+	// bg tracks the dispatcher and the periodic jobs; bgCtx is cancelled by
+	// Shutdown so the periodic jobs stop even while the service's context
+	// is still live.
+	bg             sync.WaitGroup
+	bgCtx          context.Context
+	stopBg         context.CancelFunc
+	backfillMu     sync.Mutex
+	backfillMisses map[string]string // path => sha256 the model host didn't know
+
+	checkMu   sync.Mutex // one update check at a time
+	updatesMu sync.RWMutex
+	updates   map[string]types.ModelUpdate // path => update
+	checkedAt time.Time
 }
 
 type AlreadyQueuedError struct {
@@ -59,22 +105,44 @@ func (e AlreadyQueuedError) Error() string {
 	return "download already queued: " + e.JobID
 }
 
-func NewDownloaderService(hub *Hub, config config.ApiDlConfig, ctx context.Context) *DownloaderService {
+func NewDownloaderService(hub *Hub, lib *library.Library, config config.ApiDlConfig, ctx context.Context) *DownloaderService {
+	client := huggingface.NewHfClient(ctx, config.Client)
 	s := &DownloaderService{
-		hub:      hub,
-		baseDir:  config.BaseDir,
-		queue:    make(chan DownloadJob, config.QueueSize),
-		client:   huggingface.NewHfClient(ctx, config.Client),
-		ctx:      ctx,
-		logger:   log.With("component", "downloader"),
-		inflight: map[string]string{},
+		hub:         hub,
+		library:     lib,
+		baseDir:     config.BaseDir,
+		quarantine:  config.QuarantineDir,
+		policy:      newDownloadPolicy(config.Policy),
+		backfill:    time.Duration(config.BackfillMinutes) * time.Minute,
+		updateCheck: time.Duration(config.UpdateCheckHours) * time.Hour,
+		queue:       make(chan DownloadJob, config.QueueSize),
+		client:      client,
+		meta:        metadata.NewStore(client, time.Duration(config.MetadataTtlHours)*time.Hour),
+		ctx:         ctx,
+		logger:      log.With("component", "downloader"),
+		inflight:    map[string]string{},
+
+		backfillMisses: map[string]string{},
+		updates:        map[string]types.ModelUpdate{},
 	}
+	s.bgCtx, s.stopBg = context.WithCancel(ctx)
 	s.group.SetLimit(config.MaxConcurrent) // battery slots
 	return s
 }
 
 func (d *DownloaderService) Run() {
+	d.meta.Run(d.bgCtx)
+	if d.library != nil && d.backfill > 0 && d.client.CanLookupByHash() {
+		d.runPeriodic(d.backfill, func(ctx context.Context) { d.Backfill(ctx) })
+	}
+	if d.library != nil && d.updateCheck > 0 && d.client.CanGetModelInfo() {
+		d.runPeriodic(d.updateCheck, func(ctx context.Context) { d.CheckUpdates(ctx) })
+	}
+	// The dispatcher is tracked in bg so Shutdown can wait for it to stop
+	// calling group.Go before it waits on the group.
+	d.bg.Add(1)
 	go func() {
+		defer d.bg.Done()
 		for {
 			select {
 			case <-d.ctx.Done():
@@ -93,6 +161,36 @@ func (d *DownloaderService) Run() {
 	}()
 }
 
+// runPeriodic calls fn every interval, starting once the library has been
+// scanned, until Shutdown or the service's context stops it.
+func (d *DownloaderService) runPeriodic(interval time.Duration, fn func(context.Context)) {
+	d.bg.Add(1)
+	go func() {
+		defer d.bg.Done()
+		wait := time.NewTicker(time.Second)
+		for d.library.Scanned().IsZero() {
+			select {
+			case <-d.bgCtx.Done():
+				wait.Stop()
+				return
+			case <-wait.C:
+			}
+		}
+		wait.Stop()
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			fn(d.bgCtx)
+			select {
+			case <-d.bgCtx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	}()
+}
+
 func (d *DownloaderService) Enqueue(job DownloadJob) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -114,6 +212,9 @@ func (d *DownloaderService) Enqueue(job DownloadJob) error {
 	}
 }
 
+// Shutdown stops taking downloads and stops the periodic jobs and the
+// metadata refresher, then waits for them and for the downloads already
+// running. It doesn't need the service's context to be cancelled first.
 func (s *DownloaderService) Shutdown() {
 	s.mu.Lock()
 	if !s.closing {
@@ -122,7 +223,39 @@ func (s *DownloaderService) Shutdown() {
 	}
 	s.inflight = map[string]string{}
 	s.mu.Unlock()
+	s.stopBg()
+	s.bg.Wait()
 	_ = s.group.Wait()
+	s.meta.Wait()
+}
+
+// Policy returns the policy downloads are checked against.
+func (d *DownloaderService) Policy() DownloadPolicy {
+	return d.policy
+}
+
+// Metadata returns the model host's description of the downloaded file at
+// path, from its sidecar.
+func (d *DownloaderService) Metadata(path string) (huggingface.ModelVersionIdResponse, bool) {
+	return d.meta.Lookup(path)
+}
+
+// LibraryChanged is the downloader's share of the library's OnChange
+// callback: metadata kept for files that were removed is dropped.
+func (d *DownloaderService) LibraryChanged(changes []library.Change) {
+	for _, c := range changes {
+		if c.Type == library.ChangeRemoved {
+			d.meta.Forget(c.Entry.Path)
+		}
+	}
+}
+
+// writeMetadata stores the version info next to the file. The download has
+// succeeded either way, so a failure is only logged.
+func (d *DownloaderService) writeMetadata(job DownloadJob, path string, info huggingface.ModelVersionIdResponse) {
+	if err := d.meta.Write(path, info); err != nil {
+		d.logger.Warn("write metadata failed", "jobId", job.JobID, "modelVersionId", job.ModelVersionID, "file", path, "err", err)
+	}
 }
 
 func (d *DownloaderService) inflightKey(clientID string, modelVersionID int64) string {
@@ -135,18 +268,20 @@ func (d *DownloaderService) clearInflight(job DownloadJob) {
 	d.mu.Unlock()
 }
 
-func preferredFilename(modelInfo huggingface.ModelVersionIdResponse) string {
+// preferredFile is the file the download endpoint serves for a version: the
+// primary one, else the first with a name.
+func preferredFile(modelInfo huggingface.ModelVersionIdResponse) huggingface.ModelVersionFile {
 	for _, f := range modelInfo.Files {
 		if f.Primary && strings.TrimSpace(f.Name) != "" {
-			return f.Name
+			return f
 		}
 	}
 	for _, f := range modelInfo.Files {
 		if strings.TrimSpace(f.Name) != "" {
-			return f.Name
+			return f
 		}
 	}
-	return ""
+	return huggingface.ModelVersionFile{}
 }
 
 func fileExistsNonEmpty(path string) bool {
@@ -157,7 +292,9 @@ func fileExistsNonEmpty(path string) bool {
 	return !fi.IsDir() && fi.Size() > 0
 }
 
-// WS only emits completion/failure.
+// runJob fetches the version's files, sending download.started and
+// download.progress for each one and download.completed or download.failed
+// once the job is done.
 func (d *DownloaderService) runJob(job DownloadJob) {
 	defer d.clearInflight(job)
 	if d.ctx.Err() != nil {
@@ -171,7 +308,7 @@ func (d *DownloaderService) runJob(job DownloadJob) {
 	if err != nil {
 		d.logger.Error("download failed fetching model info", "jobId", job.JobID, "modelVersionId", job.ModelVersionID, "err", err)
 		d.hub.SendTo(job.ClientID, WSEvent{
-			Type:           "download.failed",
+			Type:           EventDownloadFailed,
 			JobID:          job.JobID,
 			ModelVersionID: job.ModelVersionID,
 			Message:        err.Error(),
@@ -188,7 +325,7 @@ func (d *DownloaderService) runJob(job DownloadJob) {
 	if baseModel == "" {
 		d.logger.Error("download failed missing basemodel", "jobId", job.JobID, "modelVersionId", job.ModelVersionID)
 		d.hub.SendTo(job.ClientID, WSEvent{
-			Type:           "download.failed",
+			Type:           EventDownloadFailed,
 			JobID:          job.JobID,
 			ModelVersionID: job.ModelVersionID,
 			Message:        "couldn't determine basemodel",
@@ -196,87 +333,222 @@ func (d *DownloaderService) runJob(job DownloadJob) {
 		return
 	}
 
-	var folderPath string
-	if folderPath = d.createFolderpath(baseModel, modelType); folderPath == "" {
-		d.logger.Error("download failed invalid folder path", "jobId", job.JobID, "modelVersionId", job.ModelVersionID, "baseModel", baseModel, "modelType", modelType)
+	folderPath, err := d.createFolderpath(baseModel, modelType)
+	if err != nil {
+		d.logger.Error("download failed invalid folder path", "jobId", job.JobID, "modelVersionId", job.ModelVersionID, "baseModel", baseModel, "modelType", modelType, "err", err)
 		d.hub.SendTo(job.ClientID, WSEvent{
-			Type:           "download.failed",
+			Type:           EventDownloadFailed,
 			JobID:          job.JobID,
 			ModelVersionID: job.ModelVersionID,
-			Message:        "failed to create folder path",
+			Message:        err.Error(),
 		})
 		return
 	}
 
-	candidate := huggingface.SanitizeDownloadedFilename(preferredFilename(modelInfo), modelVersionID)
-	finalPath := filepath.Join(folderPath, candidate)
-	if fileExistsNonEmpty(finalPath) {
-		d.logger.Info("download skipped; file exists", "jobId", job.JobID, "modelVersionId", job.ModelVersionID, "file", finalPath)
+	files, err := selectFiles(modelInfo, job.FileIDs, job.Prefer)
+	if err != nil {
+		d.logger.Error("download failed selecting files", "jobId", job.JobID, "modelVersionId", job.ModelVersionID, "fileIds", job.FileIDs, "err", err)
 		d.hub.SendTo(job.ClientID, WSEvent{
-			Type:           "download.completed",
+			Type:           EventDownloadFailed,
 			JobID:          job.JobID,
 			ModelVersionID: job.ModelVersionID,
-			Message:        "already downloaded",
-			Path:           folderPath,
+			Message:        err.Error(),
 		})
 		return
 	}
 
-	if err := d.CreateFolder(folderPath); err != nil {
-		d.logger.Error("download failed creating folder", "jobId", job.JobID, "modelVersionId", job.ModelVersionID, "folder", folderPath, "err", err)
+	var pending []huggingface.ModelVersionFile
+	var existing []string
+	for _, file := range files {
+		finalPath := filepath.Join(folderPath, huggingface.SanitizeDownloadedFilename(file.Name, modelVersionID))
+		if fileExistsNonEmpty(finalPath) {
+			existing = append(existing, finalPath)
+			continue
+		}
+		pending = append(pending, file)
+	}
+	if len(pending) == 0 {
+		d.logger.Info("download skipped; files exist", "jobId", job.JobID, "modelVersionId", job.ModelVersionID, "files", existing)
+		for _, p := range existing {
+			d.writeMetadata(job, p, modelInfo)
+		}
+		d.finishUpgrade(job, existing...)
 		d.hub.SendTo(job.ClientID, WSEvent{
-			Type:           "download.failed",
+			Type:           EventDownloadCompleted,
 			JobID:          job.JobID,
 			ModelVersionID: job.ModelVersionID,
-			Message:        "failed to create folder",
+			Message:        "already downloaded",
+			Path:           folderPath,
+			Files:          existing,
 		})
 		return
 	}
 
-	if err := d.client.DownloadModelIntoFolder(modelVersionID, folderPath); err != nil {
-		d.logger.Error("download failed downloading model", "jobId", job.JobID, "modelVersionId", job.ModelVersionID, "folder", folderPath, "err", err)
+	var rejections []string
+	for _, file := range pending {
+		for _, r := range d.policy.Check(file) {
+			if len(pending) > 1 {
+				r = file.Name + ": " + r
+			}
+			rejections = append(rejections, r)
+		}
+	}
+	if len(rejections) > 0 {
+		if !job.OverridePolicy {
+			d.logger.Warn("download refused by policy", "jobId", job.JobID, "modelVersionId", job.ModelVersionID, "files", len(pending), "rejections", rejections)
+			d.hub.SendTo(job.ClientID, WSEvent{
+				Type:           EventDownloadFailed,
+				JobID:          job.JobID,
+				ModelVersionID: job.ModelVersionID,
+				Message:        "refused by download policy: " + strings.Join(rejections, "; "),
+				Rejections:     rejections,
+			})
+			return
+		}
+		d.logger.Warn("download policy overridden", "jobId", job.JobID, "modelVersionId", job.ModelVersionID, "files", len(pending), "rejections", rejections)
+	}
+
+	if err := d.CreateFolder(folderPath); err != nil {
+		d.logger.Error("download failed creating folder", "jobId", job.JobID, "modelVersionId", job.ModelVersionID, "folder", folderPath, "err", err)
 		d.hub.SendTo(job.ClientID, WSEvent{
-			Type:           "download.failed",
+			Type:           EventDownloadFailed,
 			JobID:          job.JobID,
 			ModelVersionID: job.ModelVersionID,
-			Message:        err.Error(),
+			Message:        "failed to create folder",
 		})
 		return
 	}
 
-	d.logger.Info("download completed", "jobId", job.JobID, "modelVersionId", job.ModelVersionID, "folder", folderPath)
+	// Files are fetched one after the other; the first failure ends the job,
+	// keeping the files that were already in place.
+	paths := existing
+	verified := true
+	for _, file := range pending {
+		downloaded, err := d.client.DownloadFileIntoFolder(modelVersionID, file, folderPath, downloadProgress{d.hub, job})
+		var mismatch *huggingface.HashMismatchError
+		if errors.As(err, &mismatch) {
+			quarantined := d.quarantineFile(mismatch.Path)
+			d.logger.Error("download failed hash mismatch", "jobId", job.JobID, "modelVersionId", job.ModelVersionID, "file", file.Name, "algorithm", mismatch.Algorithm, "quarantined", quarantined, "err", err)
+			d.hub.SendTo(job.ClientID, WSEvent{
+				Type:           EventDownloadFailed,
+				JobID:          job.JobID,
+				ModelVersionID: job.ModelVersionID,
+				Message:        err.Error(),
+				Path:           quarantined,
+				Files:          paths,
+			})
+			return
+		}
+		if err != nil {
+			d.logger.Error("download failed downloading model", "jobId", job.JobID, "modelVersionId", job.ModelVersionID, "file", file.Name, "folder", folderPath, "err", err)
+			d.hub.SendTo(job.ClientID, WSEvent{
+				Type:           EventDownloadFailed,
+				JobID:          job.JobID,
+				ModelVersionID: job.ModelVersionID,
+				Message:        err.Error(),
+				Files:          paths,
+			})
+			return
+		}
+
+		d.writeMetadata(job, downloaded.Path, modelInfo)
+		if downloaded.Verified && d.library != nil {
+			if err := d.library.MarkVerified(downloaded.Path, downloaded.SHA256); err != nil {
+				d.logger.Warn("mark verified failed", "jobId", job.JobID, "file", downloaded.Path, "err", err)
+			}
+		}
+		verified = verified && downloaded.Verified
+		paths = append(paths, downloaded.Path)
+	}
+	d.finishUpgrade(job, paths...)
+
+	d.logger.Info("download completed", "jobId", job.JobID, "modelVersionId", job.ModelVersionID, "files", paths, "verified", verified)
 	d.hub.SendTo(job.ClientID, WSEvent{
-		Type:           "download.completed",
+		Type:           EventDownloadCompleted,
 		JobID:          job.JobID,
 		ModelVersionID: job.ModelVersionID,
 		Message:        "download complete",
 		Path:           folderPath,
+		Verified:       verified,
+		Files:          paths,
 	})
 }
 
-func (d *DownloaderService) createFolderpath(baseModel, modelType string) string {
+// quarantineFile moves a download that failed verification out of the
+// library so it is never loaded, keeping it around for inspection. It returns
+// where the file ended up, or "" when it had to be deleted instead.
+func (d *DownloaderService) quarantineFile(path string) string {
+	name := fmt.Sprintf("%d-%s", time.Now().Unix(), strings.TrimSuffix(filepath.Base(path), ".part"))
+	dest := filepath.Join(d.quarantine, name)
+	err := os.MkdirAll(d.quarantine, 0o755)
+	if err == nil {
+		err = os.Rename(path, dest)
+	}
+	if err != nil {
+		d.logger.Warn("quarantine failed; deleting file", "file", path, "err", err)
+		_ = os.Remove(path)
+		return ""
+	}
+	return dest
+}
+
+// createFolderpath returns the folder a download of modelType for baseModel
+// goes to. Types the library has no root for are refused.
+func (d *DownloaderService) createFolderpath(baseModel, modelType string) (string, error) {
 	baseModel = strings.TrimSpace(baseModel)
 	if baseModel == "" {
-		return ""
+		return "", errors.New("missing base model")
 	}
+	kind, ok := typeKind(modelType)
+	if !ok {
+		return "", fmt.Errorf("unsupported model type %q", modelType)
+	}
+	root := d.rootFor(kind)
+	if strings.TrimSpace(root) == "" {
+		return "", fmt.Errorf("no root configured for %s", kind)
+	}
+	return filepath.Join(root, baseModel), nil
+}
 
-	modelTypeLower := strings.ToLower(strings.TrimSpace(modelType))
+// typeKind maps the model host's model type to the library root it is filed
+// under.
+func typeKind(modelType string) (library.Kind, bool) {
+	t := strings.ToLower(strings.TrimSpace(modelType))
 	switch {
-	case strings.Contains(modelTypeLower, "checkpoint"):
-		modelRoot, _ := rootsFromConfig(d.baseDir)
-		if strings.TrimSpace(modelRoot) == "" {
-			return ""
-		}
-		return filepath.Join(modelRoot, baseModel)
-	case strings.Contains(modelTypeLower, "lora"):
-		_, loraRoot := rootsFromConfig(d.baseDir)
-		if strings.TrimSpace(loraRoot) == "" {
-			return ""
-		}
-		return filepath.Join(loraRoot, baseModel)
+	case strings.Contains(t, "checkpoint"):
+		return library.KindCheckpoint, true
+	case strings.Contains(t, "lora"):
+		return library.KindLora, true
+	case t == "textualinversion":
+		return library.KindEmbedding, true
+	case t == "vae":
+		return library.KindVAE, true
+	case t == "controlnet":
+		return library.KindControlNet, true
+	case t == "upscaler":
+		return library.KindUpscaler, true
+	case t == "locon" || t == "dora":
+		return library.KindLycoris, true
 	default:
+		return "", false
+	}
+}
+
+// rootFor returns where downloads of kind go. Checkpoints and LoRAs follow
+// baseDir; the other kinds go to the library root configured for them, so
+// they are indexed like everything else.
+func (d *DownloaderService) rootFor(kind library.Kind) string {
+	modelRoot, loraRoot := rootsFromConfig(d.baseDir)
+	switch kind {
+	case library.KindCheckpoint:
+		return modelRoot
+	case library.KindLora:
+		return loraRoot
+	}
+	if d.library == nil {
 		return ""
 	}
+	return d.library.Root(kind)
 }
 
 func rootsFromConfig(baseDir string) (modelRoot, loraRoot string) {
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"be/internal/clients/huggingface"
+	"be/internal/library"
+	"be/internal/metadata"
+	"be/internal/modelhash"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// BackfillResult summarises one back-fill run.
+type BackfillResult struct {
+	Checked int // files without metadata that were looked up
+	Matched int
+	Failed  int // hashing or lookup errors; retried on the next run
+}
+
+// Backfill finds the model version of every library file that has no
+// metadata and wasn't downloaded by us, by its SHA256 and then its AutoV2
+// hash, and writes what it finds to the sidecar store. Progress goes to every
+// client as library.backfill.* events. Files the host doesn't know are not
+// looked up again until they change.
+func (d *DownloaderService) Backfill(ctx context.Context) BackfillResult {
+	var todo []library.Entry
+	entries, _ := d.library.List(library.Filter{})
+	for _, e := range entries {
+		if _, ok := metadata.VersionID(e.Path); ok {
+			continue // the metadata store fetches these by ID
+		}
+		if _, ok := d.meta.Lookup(e.Path); ok {
+			continue
+		}
+		if sum, missed := d.backfillMissed(e.Path); missed && sum == e.SHA256 {
+			continue
+		}
+		todo = append(todo, e)
+	}
+
+	var res BackfillResult
+	if len(todo) == 0 {
+		return res
+	}
+	d.logger.Info("metadata backfill started", "files", len(todo))
+	d.hub.Broadcast(WSEvent{Type: EventBackfillStarted, Total: len(todo)})
+
+	for i, e := range todo {
+		if ctx.Err() != nil {
+			break
+		}
+		res.Checked++
+		event := WSEvent{Type: EventBackfillProgress, Path: e.Path, Done: i + 1, Total: len(todo)}
+
+		info, err := d.backfillOne(ctx, e)
+		switch {
+		case errors.Is(err, huggingface.ErrNotFound):
+			event.Message = "no match"
+		case err != nil:
+			res.Failed++
+			event.Message = err.Error()
+			d.logger.Warn("metadata backfill failed", "file", e.Path, "err", err)
+		default:
+			res.Matched++
+			event.ModelVersionID = info.Id
+			event.Message = "matched"
+			d.logger.Info("metadata backfilled", "file", e.Path, "modelVersionId", info.Id)
+		}
+		d.hub.Broadcast(event)
+	}
+
+	d.logger.Info("metadata backfill completed", "checked", res.Checked, "matched", res.Matched, "failed", res.Failed)
+	d.hub.Broadcast(WSEvent{
+		Type:    EventBackfillCompleted,
+		Message: fmt.Sprintf("matched %d of %d files", res.Matched, res.Checked),
+		Done:    res.Checked,
+		Total:   len(todo),
+		Matched: res.Matched,
+	})
+	return res
+}
+
+func (d *DownloaderService) backfillOne(ctx context.Context, e library.Entry) (huggingface.ModelVersionIdResponse, error) {
+	e, err := d.library.Hash(ctx, e.Path)
+	if err != nil {
+		return huggingface.ModelVersionIdResponse{}, err
+	}
+	if e.SHA256 == "" {
+		return huggingface.ModelVersionIdResponse{}, errors.New("file changed while hashing")
+	}
+
+	info, err := d.client.GetModelVersionByHash(e.SHA256)
+	if errors.Is(err, huggingface.ErrNotFound) {
+		info, err = d.client.GetModelVersionByHash(modelhash.Short(e.SHA256))
+	}
+	if errors.Is(err, huggingface.ErrNotFound) {
+		d.backfillMu.Lock()
+		d.backfillMisses[e.Path] = e.SHA256
+		d.backfillMu.Unlock()
+	}
+	if err != nil {
+		return huggingface.ModelVersionIdResponse{}, err
+	}
+	if err := d.meta.Write(e.Path, info); err != nil {
+		return huggingface.ModelVersionIdResponse{}, err
+	}
+	return info, nil
+}
+
+// backfillMissed returns the hash the file had when the host last didn't
+// know it.
+func (d *DownloaderService) backfillMissed(path string) (string, bool) {
+	d.backfillMu.Lock()
+	defer d.backfillMu.Unlock()
+	sum, ok := d.backfillMisses[path]
+	return sum, ok
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"be/config"
+	"be/internal/library"
+	"be/internal/modelhash"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBackfill(t *testing.T) {
+	dir := t.TempDir()
+	loras := filepath.Join(dir, "loras")
+	if err := os.MkdirAll(loras, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	known := filepath.Join(loras, "ink.safetensors")
+	unknown := filepath.Join(loras, "mystery.safetensors")
+	for path, content := range map[string]string{known: "ink weights", unknown: "mystery weights"} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sum := sha256.Sum256([]byte("ink weights"))
+	autoV2 := modelhash.Short(hex.EncodeToString(sum[:]))
+
+	var lookups atomic.Int32
+	host := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hash, ok := strings.CutPrefix(r.URL.Path, "/by-hash/")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		lookups.Add(1)
+		// Only the AutoV2 hash is indexed, so the SHA256 lookup misses first.
+		if !strings.EqualFold(hash, autoV2) {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id":77,"baseModel":"SDXL 1.0","trainedWords":["ink"]}`)
+	}))
+	defer host.Close()
+
+	lib, err := library.Open(filepath.Join(dir, "library.db"), map[library.Kind]string{library.KindLora: loras})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lib.Close()
+	if err := lib.Scan(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dl := NewDownloaderService(NewHub(), lib, config.ApiDlConfig{
+		BaseDir:   dir,
+		QueueSize: 1,
+		Client: config.ApiDlClientConfig{
+			ModeInfoUrl: host.URL + "/versions/{id}",
+			ByHashUrl:   host.URL + "/by-hash/{id}",
+		},
+	}, ctx)
+
+	res := dl.Backfill(ctx)
+	if res.Checked != 2 || res.Matched != 1 || res.Failed != 0 {
+		t.Fatalf("first run = %+v", res)
+	}
+	info, ok := dl.Metadata(known)
+	if !ok || info.Id != 77 || info.BaseModel != "SDXL 1.0" {
+		t.Fatalf("metadata = %+v, %v", info, ok)
+	}
+	if _, err := os.Stat(filepath.Join(loras, "ink.safetensors.json")); err != nil {
+		t.Fatalf("sidecar: %v", err)
+	}
+
+	// Matched files have a sidecar now and misses are remembered.
+	calls := lookups.Load()
+	if res := dl.Backfill(ctx); res.Checked != 0 {
+		t.Fatalf("second run = %+v", res)
+	}
+	if lookups.Load() != calls {
+		t.Fatal("unknown file looked up again")
+	}
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"be/internal/clients/huggingface"
+	"be/internal/library"
+	"be/internal/metadata"
+	"errors"
+)
+
+var ErrCatalogSearchDisabled = errors.New("catalog search needs a search url")
+
+// Search runs a catalog search on the model host and marks the versions that
+// are already in the library.
+func (d *DownloaderService) Search(p huggingface.SearchParams) (huggingface.CatalogSearch, error) {
+	if !d.client.CanSearch() {
+		return huggingface.CatalogSearch{}, ErrCatalogSearchDisabled
+	}
+	res, err := d.client.SearchModels(p)
+	if err != nil {
+		return huggingface.CatalogSearch{}, err
+	}
+	local := d.localVersions()
+	for i := range res.Items {
+		for j := range res.Items[i].Versions {
+			v := &res.Items[i].Versions[j]
+			if path, ok := local[v.ID]; ok {
+				v.Local, v.LocalPath = true, path
+			}
+		}
+	}
+	return res, nil
+}
+
+// localVersions maps the model versions in the library to their files, from
+// the sidecar or, for files without one yet, the downloader's file name. The
+// library is scanned first if nothing has scanned it yet.
+func (d *DownloaderService) localVersions() map[int64]string {
+	local := map[int64]string{}
+	if d.library == nil {
+		return local
+	}
+	if d.library.Scanned().IsZero() {
+		if err := d.library.Scan(); err != nil {
+			d.logger.Warn("library scan failed", "err", err)
+		}
+	}
+	entries, _ := d.library.List(library.Filter{})
+	for _, e := range entries {
+		if info, ok := d.meta.Lookup(e.Path); ok && info.Id > 0 {
+			local[info.Id] = e.Path
+		} else if id, ok := metadata.VersionID(e.Path); ok {
+			local[id] = e.Path
+		}
+	}
+	return local
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"be/internal/clients/huggingface"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrUnknownFile = errors.New("file not in model version")
+
+// FilePreference picks one model file of a version by its metadata instead
+// of the primary file. Empty fields don't matter; files matching more of
+// the set fields win, the primary file breaking ties.
+type FilePreference struct {
+	Fp     string `json:"fp,omitempty"`     // fp16, fp32, bf16
+	Size   string `json:"size,omitempty"`   // pruned or full
+	Format string `json:"format,omitempty"` // SafeTensor, PickleTensor, ...
+}
+
+func (p *FilePreference) empty() bool {
+	return p == nil || (p.Fp == "" && p.Size == "" && p.Format == "")
+}
+
+// VersionFiles lists the files of a model version with what the download
+// policy thinks of each.
+func (d *DownloaderService) VersionFiles(modelVersionID int64) (huggingface.CatalogVersionFiles, error) {
+	info, err := d.client.GetModelVersionInfo(strconv.FormatInt(modelVersionID, 10))
+	if err != nil {
+		return huggingface.CatalogVersionFiles{}, err
+	}
+	out := huggingface.CatalogFiles(info)
+	for i, f := range info.Files {
+		out.Files[i].Rejections = d.policy.Check(f)
+	}
+	return out, nil
+}
+
+// selectFiles returns the files a job downloads: the ones it names by ID,
+// else the model file best matching its preference, else the primary file.
+func selectFiles(info huggingface.ModelVersionIdResponse, ids []int64, prefer *FilePreference) ([]huggingface.ModelVersionFile, error) {
+	if len(ids) > 0 {
+		files := make([]huggingface.ModelVersionFile, 0, len(ids))
+		for _, id := range ids {
+			i := -1
+			for j, f := range info.Files {
+				if f.Id == id {
+					i = j
+					break
+				}
+			}
+			if i < 0 {
+				return nil, fmt.Errorf("%w: file %d in version %d", ErrUnknownFile, id, info.Id)
+			}
+			files = append(files, info.Files[i])
+		}
+		return files, nil
+	}
+
+	if !prefer.empty() {
+		best, bestScore := -1, -1
+		for i, f := range info.Files {
+			if !isModelFile(f) || strings.TrimSpace(f.Name) == "" {
+				continue
+			}
+			score := 0
+			if prefer.Fp != "" && f.Metadata.Fp != nil && strings.EqualFold(*f.Metadata.Fp, prefer.Fp) {
+				score += 2
+			}
+			if prefer.Size != "" && f.Metadata.Size != nil && strings.EqualFold(*f.Metadata.Size, prefer.Size) {
+				score += 2
+			}
+			if prefer.Format != "" && strings.EqualFold(fileFormat(f), prefer.Format) {
+				score += 2
+			}
+			if f.Primary {
+				score++
+			}
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best >= 0 {
+			return []huggingface.ModelVersionFile{info.Files[best]}, nil
+		}
+	}
+
+	// Without any file names this is the zero file, fetched through the
+	// version's download URL.
+	return []huggingface.ModelVersionFile{preferredFile(info)}, nil
+}
+
+// isModelFile leaves out the VAEs, configs and training data that ship
+// next to the weights.
+func isModelFile(f huggingface.ModelVersionFile) bool {
+	t := strings.ToLower(strings.TrimSpace(f.Type))
+	return t == "" || t == "model" || t == "pruned model"
+}
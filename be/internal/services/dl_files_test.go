@@ -0,0 +1,49 @@
+package services
+
+import (
+	"be/internal/clients/huggingface"
+	"errors"
+	"testing"
+)
+
+func TestSelectFiles(t *testing.T) {
+	str := func(s string) *string { return &s }
+	info := huggingface.ModelVersionIdResponse{Id: 7, Files: []huggingface.ModelVersionFile{
+		{Id: 1, Name: "full.safetensors", Type: "Model", Primary: true, Metadata: huggingface.ModelVersionFileMetadata{Fp: str("fp32"), Size: str("full"), Format: str("SafeTensor")}},
+		{Id: 2, Name: "pruned.safetensors", Type: "Pruned Model", Metadata: huggingface.ModelVersionFileMetadata{Fp: str("fp16"), Size: str("pruned"), Format: str("SafeTensor")}},
+		{Id: 3, Name: "pruned.ckpt", Type: "Pruned Model", Metadata: huggingface.ModelVersionFileMetadata{Fp: str("fp16"), Size: str("pruned"), Format: str("PickleTensor")}},
+		{Id: 4, Name: "vae.safetensors", Type: "VAE", Metadata: huggingface.ModelVersionFileMetadata{Fp: str("fp16"), Format: str("SafeTensor")}},
+	}}
+	ids := func(files []huggingface.ModelVersionFile) []int64 {
+		var out []int64
+		for _, f := range files {
+			out = append(out, f.Id)
+		}
+		return out
+	}
+
+	for _, tc := range []struct {
+		name   string
+		ids    []int64
+		prefer *FilePreference
+		want   []int64
+	}{
+		{"primary by default", nil, nil, []int64{1}},
+		{"by id", []int64{2, 4}, nil, []int64{2, 4}},
+		{"fp16 safetensors", nil, &FilePreference{Fp: "FP16", Format: "SafeTensor"}, []int64{2}},
+		{"pruned pickle", nil, &FilePreference{Size: "pruned", Format: "PickleTensor"}, []int64{3}},
+		{"no match keeps primary", nil, &FilePreference{Fp: "bf16"}, []int64{1}},
+	} {
+		files, err := selectFiles(info, tc.ids, tc.prefer)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got := ids(files); len(got) != len(tc.want) || got[0] != tc.want[0] || got[len(got)-1] != tc.want[len(tc.want)-1] {
+			t.Errorf("%s: files %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	if _, err := selectFiles(info, []int64{1, 9}, nil); !errors.Is(err, ErrUnknownFile) {
+		t.Fatalf("unknown file id: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"be/config"
+	"be/internal/clients/huggingface"
+	"crypto/subtle"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// HeaderAdminToken carries the token that lets a download request override
+// the download policy.
+const HeaderAdminToken = "X-Admin-Token"
+
+const scanSuccess = "Success"
+
+// DownloadPolicy decides which files the downloader accepts, from what the
+// model host reports about them before a single byte is fetched.
+type DownloadPolicy struct {
+	RequireCleanScans bool
+	SafetensorsOnly   bool
+	MaxSizeKB         float64 // 0 means no limit
+	adminToken        string
+}
+
+func newDownloadPolicy(c config.ApiDlPolicyConfig) DownloadPolicy {
+	return DownloadPolicy{
+		RequireCleanScans: c.RequireCleanScans,
+		SafetensorsOnly:   c.SafetensorsOnly,
+		MaxSizeKB:         float64(c.MaxSizeMb) * 1024,
+		adminToken:        c.AdminToken,
+	}
+}
+
+// Admin reports whether token may override the policy. Overrides are off
+// when no admin token is configured.
+func (p DownloadPolicy) Admin(token string) bool {
+	return p.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(p.adminToken)) == 1
+}
+
+// Check returns every reason f is refused, or nil when it is accepted.
+func (p DownloadPolicy) Check(f huggingface.ModelVersionFile) []string {
+	var rejections []string
+	if p.RequireCleanScans {
+		if r := scanRejection("pickle", f.PickleScanResult, f.PickleScanMessage); r != "" {
+			rejections = append(rejections, r)
+		}
+		if r := scanRejection("virus", f.VirusScanResult, f.VirusScanMessage); r != "" {
+			rejections = append(rejections, r)
+		}
+	}
+	if p.SafetensorsOnly {
+		if format := fileFormat(f); !strings.EqualFold(format, "SafeTensor") {
+			if format == "" {
+				format = "unknown"
+			}
+			rejections = append(rejections, fmt.Sprintf("format is %s, only SafeTensor is allowed", format))
+		}
+	}
+	if p.MaxSizeKB > 0 && f.SizeKB != nil && *f.SizeKB > p.MaxSizeKB {
+		rejections = append(rejections, fmt.Sprintf("size %.0f MB exceeds the %.0f MB limit", *f.SizeKB/1024, p.MaxSizeKB/1024))
+	}
+	return rejections
+}
+
+func scanRejection(scan string, result, message *string) string {
+	switch {
+	case result == nil || *result == "":
+		return scan + " scan result missing"
+	case *result == scanSuccess:
+		return ""
+	case message != nil && *message != "":
+		return fmt.Sprintf("%s scan %s: %s", scan, *result, *message)
+	}
+	return fmt.Sprintf("%s scan %s", scan, *result)
+}
+
+// fileFormat is the format the host reports, falling back to the file
+// extension for files without metadata.
+func fileFormat(f huggingface.ModelVersionFile) string {
+	if f.Metadata.Format != nil && *f.Metadata.Format != "" {
+		return *f.Metadata.Format
+	}
+	if strings.EqualFold(filepath.Ext(f.Name), ".safetensors") {
+		return "SafeTensor"
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"be/config"
+	"be/internal/clients/huggingface"
+	"reflect"
+	"testing"
+)
+
+func TestDownloadPolicy(t *testing.T) {
+	str := func(s string) *string { return &s }
+	size := func(kb float64) *float64 { return &kb }
+	clean := huggingface.ModelVersionFile{
+		Name:             "ink.safetensors",
+		SizeKB:           size(150 * 1024),
+		PickleScanResult: str("Success"),
+		VirusScanResult:  str("Success"),
+	}
+	p := newDownloadPolicy(config.ApiDlPolicyConfig{RequireCleanScans: true, SafetensorsOnly: true, MaxSizeMb: 100})
+
+	if got := p.Check(huggingface.ModelVersionFile{Name: "a.safetensors", PickleScanResult: str("Success"), VirusScanResult: str("Success")}); got != nil {
+		t.Fatalf("clean file refused: %v", got)
+	}
+
+	dirty := clean
+	dirty.Name = "ink.pt"
+	dirty.Metadata.Format = str("PickleTensor")
+	dirty.PickleScanResult = str("Danger")
+	dirty.PickleScanMessage = str("dangerous import os.system")
+	dirty.VirusScanResult = nil
+	want := []string{
+		"pickle scan Danger: dangerous import os.system",
+		"virus scan result missing",
+		"format is PickleTensor, only SafeTensor is allowed",
+		"size 150 MB exceeds the 100 MB limit",
+	}
+	if got := p.Check(dirty); !reflect.DeepEqual(got, want) {
+		t.Fatalf("rejections = %q, want %q", got, want)
+	}
+
+	if got := (DownloadPolicy{}).Check(dirty); got != nil {
+		t.Fatalf("empty policy refused: %v", got)
+	}
+
+	if p.Admin("") || p.Admin("secret") {
+		t.Fatal("override allowed without an admin token configured")
+	}
+	p = newDownloadPolicy(config.ApiDlPolicyConfig{AdminToken: "secret"})
+	if !p.Admin("secret") || p.Admin("guess") {
+		t.Fatal("admin token not checked")
+	}
+}
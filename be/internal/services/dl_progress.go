@@ -0,0 +1,37 @@
+package services
+
+import (
+	"be/internal/clients/huggingface"
+)
+
+// downloadProgress sends the progress of a job's files to the client that
+// queued it as download.started and download.progress events.
+type downloadProgress struct {
+	hub *Hub
+	job DownloadJob
+}
+
+func (p downloadProgress) DownloadStarted(filename, dest string, total int64) {
+	p.hub.SendTo(p.job.ClientID, WSEvent{
+		Type:           EventDownloadStarted,
+		JobID:          p.job.JobID,
+		ModelVersionID: p.job.ModelVersionID,
+		Message:        "downloading " + filename,
+		Path:           dest,
+		Filename:       filename,
+		TotalBytes:     total,
+	})
+}
+
+func (p downloadProgress) DownloadProgress(pr huggingface.Progress) {
+	p.hub.SendTo(p.job.ClientID, WSEvent{
+		Type:           EventDownloadProgress,
+		JobID:          p.job.JobID,
+		ModelVersionID: p.job.ModelVersionID,
+		Bytes:          pr.Received,
+		TotalBytes:     pr.Total,
+		Rate:           pr.Rate,
+		AvgRate:        pr.AvgRate,
+		EtaSeconds:     pr.ETA.Seconds(),
+	})
+}
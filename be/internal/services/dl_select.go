@@ -0,0 +1,110 @@
+package services
+
+import (
+	"be/internal/clients/huggingface"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Version selection policies for downloads by model ID.
+const (
+	SelectLatest    = "latest"    // newest version
+	SelectBaseModel = "baseModel" // newest version for DownloadRequest.BaseModel
+	SelectName      = "name"      // the version named DownloadRequest.VersionName
+)
+
+var ErrNoMatchingVersion = errors.New("no matching model version")
+
+// ResolveVersion returns the version of req.ModelID the request's selection
+// policy picks. Requests by version ID are returned as they are.
+func (d *DownloaderService) ResolveVersion(req DownloadRequest) (int64, error) {
+	if req.ModelID <= 0 {
+		return req.ModelVersionID, nil
+	}
+	if !d.client.CanGetModelInfo() {
+		return 0, ErrNoModelLookups
+	}
+	model, err := d.client.GetModelInfo(strconv.FormatInt(req.ModelID, 10))
+	if err != nil {
+		return 0, err
+	}
+	v, err := selectVersion(model, req.Select, req.BaseModel, req.VersionName)
+	if err != nil {
+		return 0, err
+	}
+	d.logger.Debug("model version resolved", "modelId", req.ModelID, "select", req.Select, "modelVersionId", v.Id, "name", v.Name)
+	return v.Id, nil
+}
+
+// selectionPolicy returns the policy a request asks for, inferring it from
+// the fields that are set when none is named.
+func selectionPolicy(req DownloadRequest) (string, error) {
+	sel := strings.TrimSpace(req.Select)
+	if sel == "" {
+		switch {
+		case strings.TrimSpace(req.VersionName) != "":
+			sel = SelectName
+		case strings.TrimSpace(req.BaseModel) != "":
+			sel = SelectBaseModel
+		default:
+			sel = SelectLatest
+		}
+	}
+	switch sel {
+	case SelectLatest:
+	case SelectBaseModel:
+		if strings.TrimSpace(req.BaseModel) == "" {
+			return "", errors.New("select=baseModel needs baseModel")
+		}
+	case SelectName:
+		if strings.TrimSpace(req.VersionName) == "" {
+			return "", errors.New("select=name needs versionName")
+		}
+	default:
+		return "", fmt.Errorf("select must be %s, %s or %s", SelectLatest, SelectBaseModel, SelectName)
+	}
+	return sel, nil
+}
+
+func selectVersion(model huggingface.ModelIdResponse, sel, baseModel, name string) (huggingface.ModelVersionSummary, error) {
+	for _, v := range versionsNewestFirst(model) {
+		switch sel {
+		case SelectBaseModel:
+			if !sameBaseModel(v.BaseModel, baseModel) {
+				continue
+			}
+		case SelectName:
+			if !strings.EqualFold(strings.TrimSpace(v.Name), strings.TrimSpace(name)) {
+				continue
+			}
+		}
+		return v, nil
+	}
+	switch sel {
+	case SelectBaseModel:
+		return huggingface.ModelVersionSummary{}, fmt.Errorf("%w: model %d has no %s version", ErrNoMatchingVersion, model.Id, baseModel)
+	case SelectName:
+		return huggingface.ModelVersionSummary{}, fmt.Errorf("%w: model %d has no version named %q", ErrNoMatchingVersion, model.Id, name)
+	}
+	return huggingface.ModelVersionSummary{}, fmt.Errorf("%w: model %d has no versions", ErrNoMatchingVersion, model.Id)
+}
+
+// versionsNewestFirst returns the model's versions newest first. The host
+// lists them that way; index says so explicitly.
+func versionsNewestFirst(model huggingface.ModelIdResponse) []huggingface.ModelVersionSummary {
+	versions := model.ModelVersions
+	if !slices.ContainsFunc(versions, func(v huggingface.ModelVersionSummary) bool { return v.Index == nil }) {
+		versions = slices.Clone(versions)
+		slices.SortStableFunc(versions, func(a, b huggingface.ModelVersionSummary) int { return *a.Index - *b.Index })
+	}
+	return versions
+}
+
+// sameBaseModel compares base models the way the host names them ("SDXL
+// 1.0") and the way the downloader files them ("SDXL-1.0").
+func sameBaseModel(a, b string) bool {
+	return strings.EqualFold(dashifySpaces(a), dashifySpaces(b))
+}
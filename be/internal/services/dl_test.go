@@ -0,0 +1,82 @@
+package services
+
+import (
+	"be/config"
+	"be/internal/library"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCreateFolderpath(t *testing.T) {
+	dir := t.TempDir()
+	embeddings := filepath.Join(dir, "mnt", "embeddings")
+	vaes := filepath.Join(dir, "mnt", "vae")
+	lib, err := library.Open(filepath.Join(dir, "library.db"), map[library.Kind]string{
+		library.KindEmbedding: embeddings,
+		library.KindVAE:       vaes,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lib.Close()
+
+	base := filepath.Join(dir, "py", "models")
+	dl := NewDownloaderService(NewHub(), lib, config.ApiDlConfig{BaseDir: base, QueueSize: 1}, context.Background())
+
+	for modelType, want := range map[string]string{
+		"Checkpoint":       filepath.Join(base, "SDXL-1.0"),
+		"LORA":             filepath.Join(dir, "py", "loras", "SDXL-1.0"),
+		"TextualInversion": filepath.Join(embeddings, "SDXL-1.0"),
+		"VAE":              filepath.Join(vaes, "SDXL-1.0"),
+	} {
+		if got, err := dl.createFolderpath("SDXL-1.0", modelType); err != nil || got != want {
+			t.Errorf("%s: folder = %q, %v; want %q", modelType, got, err, want)
+		}
+	}
+
+	// Without a library root the file would never be indexed.
+	for modelType, want := range map[string]string{
+		"Controlnet":   "no root configured for controlnet",
+		"Upscaler":     "no root configured for upscaler",
+		"LoCon":        "no root configured for lycoris",
+		"DoRA":         "no root configured for lycoris",
+		"Hypernetwork": `unsupported model type "Hypernetwork"`,
+	} {
+		if got, err := dl.createFolderpath("SDXL-1.0", modelType); err == nil || err.Error() != want {
+			t.Errorf("%s: folder = %q, %v; want %q", modelType, got, err, want)
+		}
+	}
+}
+
+func TestShutdownStopsPeriodicJobs(t *testing.T) {
+	dir := t.TempDir()
+	lib, err := library.Open(filepath.Join(dir, "library.db"), map[library.Kind]string{library.KindLora: filepath.Join(dir, "loras")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lib.Close()
+
+	// The context is never cancelled; Shutdown alone has to stop the update
+	// check, which is still waiting for the first scan.
+	dl := NewDownloaderService(NewHub(), lib, config.ApiDlConfig{
+		BaseDir:          dir,
+		QueueSize:        1,
+		MaxConcurrent:    1,
+		UpdateCheckHours: 1,
+		Client:           config.ApiDlClientConfig{ModelUrl: "http://127.0.0.1:0/models/{id}"},
+	}, context.Background())
+	dl.Run()
+
+	done := make(chan struct{})
+	go func() {
+		dl.Shutdown()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown hung on the periodic jobs")
+	}
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"be/internal/clients/huggingface"
+	"be/internal/library"
+	"be/internal/metadata"
+	"be/types"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoUpdate       = errors.New("no update available")
+	ErrNoModelLookups = errors.New("model lookups need a model url")
+)
+
+// UpgradeRequest asks for the newer version of a downloaded file.
+type UpgradeRequest struct {
+	ClientID string `json:"clientId"`
+	Path     string `json:"path"`
+	// ModelVersionID picks one of the newer versions; 0 takes the newest.
+	ModelVersionID int64 `json:"modelVersionId,omitempty"`
+	// RemoveOld deletes the old file and its sidecar once the new version
+	// is in place. The old file is kept by default.
+	RemoveOld bool `json:"removeOld,omitempty"`
+}
+
+// CheckUpdates looks up the model of every file with metadata and records
+// the files that have newer versions for the same base model. Each model is
+// fetched once however many of its versions are in the library. Clients are
+// sent library.update.available for every update that wasn't known before.
+func (d *DownloaderService) CheckUpdates(ctx context.Context) error {
+	if !d.client.CanGetModelInfo() {
+		return ErrNoModelLookups
+	}
+	d.checkMu.Lock()
+	defer d.checkMu.Unlock()
+
+	type file struct {
+		path string
+		info huggingface.ModelVersionIdResponse
+	}
+	byModel := map[int64][]file{}
+	local := map[int64]bool{} // version IDs in the library
+	entries, _ := d.library.List(library.Filter{})
+	for _, e := range entries {
+		info, ok := d.meta.Lookup(e.Path)
+		if !ok || info.Id <= 0 {
+			continue
+		}
+		local[info.Id] = true
+		if info.ModelId > 0 {
+			byModel[info.ModelId] = append(byModel[info.ModelId], file{e.Path, info})
+		}
+	}
+
+	d.updatesMu.RLock()
+	previous := d.updates
+	d.updatesMu.RUnlock()
+
+	updates := map[string]types.ModelUpdate{}
+	failed := 0
+	for modelID, files := range byModel {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		model, err := d.client.GetModelInfo(strconv.FormatInt(modelID, 10))
+		if err != nil {
+			// Keep what the last check found rather than dropping the updates
+			// while the host is down.
+			failed++
+			d.logger.Warn("update check failed", "modelId", modelID, "err", err)
+			for _, f := range files {
+				if u, ok := previous[f.path]; ok {
+					updates[f.path] = u
+				}
+			}
+			continue
+		}
+		for _, f := range files {
+			if u, ok := modelUpdate(model, f.path, f.info, local); ok {
+				updates[f.path] = u
+			}
+		}
+	}
+
+	d.updatesMu.Lock()
+	d.updates = updates
+	d.checkedAt = time.Now()
+	d.updatesMu.Unlock()
+
+	for path, u := range updates {
+		if p, ok := previous[path]; ok && p.Newer[0].ID == u.Newer[0].ID {
+			continue
+		}
+		update := u
+		d.hub.Broadcast(WSEvent{
+			Type:           EventUpdateAvailable,
+			ModelVersionID: u.Newer[0].ID,
+			Path:           path,
+			Message:        fmt.Sprintf("%s %s is available", u.ModelName, u.Newer[0].Name),
+			Update:         &update,
+		})
+	}
+	d.logger.Info("update check completed", "models", len(byModel), "updates", len(updates), "failed", failed)
+	return nil
+}
+
+// Updates returns what the last check found, by path, and when it finished.
+func (d *DownloaderService) Updates() ([]types.ModelUpdate, time.Time) {
+	d.updatesMu.RLock()
+	defer d.updatesMu.RUnlock()
+	items := make([]types.ModelUpdate, 0, len(d.updates))
+	for _, u := range d.updates {
+		items = append(items, u)
+	}
+	slices.SortFunc(items, func(a, b types.ModelUpdate) int { return strings.Compare(a.Path, b.Path) })
+	return items, d.checkedAt
+}
+
+// Upgrade queues the download of a newer version of the file at req.Path.
+func (d *DownloaderService) Upgrade(jobID string, req UpgradeRequest) (DownloadJob, error) {
+	path := filepath.Clean(req.Path)
+	d.updatesMu.RLock()
+	u, ok := d.updates[path]
+	d.updatesMu.RUnlock()
+	if !ok {
+		return DownloadJob{}, fmt.Errorf("%w for %s", ErrNoUpdate, path)
+	}
+
+	version := u.Newer[0].ID
+	if req.ModelVersionID != 0 {
+		if !slices.ContainsFunc(u.Newer, func(v types.ModelVersion) bool { return v.ID == req.ModelVersionID }) {
+			return DownloadJob{}, fmt.Errorf("%w: version %d is not newer than %s", ErrNoUpdate, req.ModelVersionID, path)
+		}
+		version = req.ModelVersionID
+	}
+
+	job := DownloadJob{
+		JobID:          jobID,
+		ClientID:       req.ClientID,
+		ModelVersionID: version,
+		Upgrades:       path,
+		RemoveOld:      req.RemoveOld,
+	}
+	return job, d.Enqueue(job)
+}
+
+// finishUpgrade forgets the update a job installed and removes the old file
+// when asked to. A failed removal leaves both versions in the library, so it
+// is only logged.
+func (d *DownloaderService) finishUpgrade(job DownloadJob, newPaths ...string) {
+	if job.Upgrades == "" {
+		return
+	}
+	d.updatesMu.Lock()
+	delete(d.updates, job.Upgrades)
+	d.updatesMu.Unlock()
+
+	if !job.RemoveOld || slices.ContainsFunc(newPaths, func(p string) bool { return filepath.Clean(p) == job.Upgrades }) {
+		return
+	}
+	for _, p := range []string{job.Upgrades, metadata.SidecarPath(job.Upgrades)} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			d.logger.Warn("remove upgraded file failed", "jobId", job.JobID, "file", p, "err", err)
+			return
+		}
+	}
+	d.logger.Info("upgraded file removed", "jobId", job.JobID, "file", job.Upgrades, "replacement", newPaths)
+}
+
+// modelUpdate returns the versions of model newer than current that share
+// its base model, newest first. It stops at the newest such version already
+// in the library: a file whose upgrade was downloaded next to it is up to
+// date.
+func modelUpdate(model huggingface.ModelIdResponse, path string, current huggingface.ModelVersionIdResponse, local map[int64]bool) (types.ModelUpdate, bool) {
+	versions := versionsNewestFirst(model)
+	if !slices.ContainsFunc(versions, func(v huggingface.ModelVersionSummary) bool { return v.Id == current.Id }) {
+		return types.ModelUpdate{}, false
+	}
+
+	var newer []types.ModelVersion
+	for _, v := range versions {
+		if !sameBaseModel(v.BaseModel, current.BaseModel) {
+			continue
+		}
+		if local[v.Id] {
+			break
+		}
+		newer = append(newer, types.ModelVersion{ID: v.Id, Name: v.Name})
+	}
+	if len(newer) == 0 {
+		return types.ModelUpdate{}, false
+	}
+	return types.ModelUpdate{
+		Path:             path,
+		ModelID:          model.Id,
+		ModelName:        model.Name,
+		BaseModel:        current.BaseModel,
+		CurrentVersionID: current.Id,
+		CurrentVersion:   current.Name,
+		Newer:            newer,
+	}, true
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"be/config"
+	"be/internal/clients/huggingface"
+	"be/internal/library"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckUpdates(t *testing.T) {
+	dir := t.TempDir()
+	loras := filepath.Join(dir, "loras", "SDXL-1.0")
+	if err := os.MkdirAll(loras, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	ink := filepath.Join(loras, "10-ink.safetensors")
+	pony := filepath.Join(loras, "11-ink-pony.safetensors")
+	for _, p := range []string{ink, pony} {
+		if err := os.WriteFile(p, []byte("weights"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	host := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/models/5":
+			io.WriteString(w, `{"id":5,"name":"Ink","modelVersions":[
+				{"id":13,"name":"v3","baseModel":"SDXL 1.0"},
+				{"id":12,"name":"v2","baseModel":"SDXL 1.0"},
+				{"id":11,"name":"v1 pony","baseModel":"Pony"},
+				{"id":10,"name":"v1","baseModel":"SDXL 1.0"}]}`)
+		case "/versions/13":
+			io.WriteString(w, `{"id":13,"modelId":5,"name":"v3","baseModel":"SDXL 1.0","model":{"type":"LORA"},
+				"files":[{"name":"ink.safetensors","primary":true,"pickleScanResult":"Success","virusScanResult":"Success"}]}`)
+		case "/download/13":
+			w.Header().Set("Content-Disposition", `attachment; filename="ink.safetensors"`)
+			io.WriteString(w, "weights v3")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer host.Close()
+
+	lib, err := library.Open(filepath.Join(dir, "library.db"), map[library.Kind]string{library.KindLora: filepath.Join(dir, "loras")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lib.Close()
+	if err := lib.Scan(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dl := NewDownloaderService(NewHub(), lib, config.ApiDlConfig{
+		BaseDir:       dir,
+		QuarantineDir: filepath.Join(dir, "quarantine"),
+		QueueSize:     1,
+		MaxConcurrent: 1,
+		Client: config.ApiDlClientConfig{
+			DownloadUrl: host.URL + "/download/{id}",
+			ModeInfoUrl: host.URL + "/versions/{id}",
+			ModelUrl:    host.URL + "/models/{id}",
+		},
+	}, ctx)
+	dl.Run()
+	defer func() {
+		cancel()
+		dl.Shutdown()
+	}()
+
+	for path, info := range map[string]huggingface.ModelVersionIdResponse{
+		ink:  {Id: 10, ModelId: 5, Name: "v1", BaseModel: "SDXL 1.0"},
+		pony: {Id: 11, ModelId: 5, Name: "v1 pony", BaseModel: "Pony"},
+	} {
+		if err := dl.meta.Write(path, info); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := dl.CheckUpdates(ctx); err != nil {
+		t.Fatal(err)
+	}
+	updates, checkedAt := dl.Updates()
+	if checkedAt.IsZero() || len(updates) != 1 {
+		t.Fatalf("updates = %+v; want only the SDXL file", updates)
+	}
+	u := updates[0]
+	if u.Path != ink || u.ModelName != "Ink" || len(u.Newer) != 2 || u.Newer[0].ID != 13 || u.Newer[1].ID != 12 {
+		t.Fatalf("update = %+v", u)
+	}
+
+	if _, err := dl.Upgrade("job-1", UpgradeRequest{ClientID: "c", Path: pony}); !errors.Is(err, ErrNoUpdate) {
+		t.Fatalf("upgrade without an update: %v", err)
+	}
+	if _, err := dl.Upgrade("job-1", UpgradeRequest{ClientID: "c", Path: ink, ModelVersionID: 11}); !errors.Is(err, ErrNoUpdate) {
+		t.Fatalf("upgrade to another base model: %v", err)
+	}
+	job, err := dl.Upgrade("job-1", UpgradeRequest{ClientID: "c", Path: ink, RemoveOld: true})
+	if err != nil || job.ModelVersionID != 13 {
+		t.Fatalf("upgrade = %+v, %v", job, err)
+	}
+
+	upgraded := filepath.Join(loras, "13-ink.safetensors")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, newErr := os.Stat(upgraded)
+		_, oldErr := os.Stat(ink)
+		if newErr == nil && errors.Is(oldErr, os.ErrNotExist) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("upgrade not installed: new %v, old %v", newErr, oldErr)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := os.Stat(filepath.Join(loras, "10-ink.safetensors.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("old sidecar kept: %v", err)
+	}
+	if updates, _ := dl.Updates(); len(updates) != 0 {
+		t.Fatalf("updates after upgrade = %+v", updates)
+	}
+}
@@ -0,0 +1,496 @@
+package services
+
+import (
+	"be/config"
+	"be/internal/dependencies"
+	"be/internal/history"
+	"be/internal/imaging"
+	"be/internal/modelhash"
+	"be/proto"
+	"be/types"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type GenerationRequest struct {
+	ClientID string `json:"clientId"`
+	types.ImagePostRequest
+}
+
+type GenerationJob struct {
+	JobID    string
+	ClientID string
+	Request  types.ImagePostRequest
+
+	// done is set on jobs Generate waits for; they are reported through it
+	// rather than the results and the hub.
+	done chan generationOutcome
+}
+
+type generationOutcome struct {
+	resp *proto.GenerateImageResponse
+	err  error
+}
+
+const (
+	GenerationQueued    = "queued"
+	GenerationRunning   = "running"
+	GenerationCompleted = "completed"
+	GenerationFailed    = "failed"
+)
+
+// Finished results are kept around so clients can fetch them after the
+// completion event; anything older than this is pruned on the next enqueue
+// or sweep, whichever comes first.
+const (
+	generationResultTTL   = 30 * time.Minute
+	generationPrunePeriod = time.Minute
+)
+
+var (
+	ErrGenerationShuttingDown = errors.New("service shutting down")
+	ErrGenerationQueueFull    = errors.New("generation queue full")
+	ErrGenerationNotFound     = errors.New("generation not found")
+)
+
+type GenerationResult struct {
+	JobID    string
+	ClientID string
+	Status   string
+	Error    string
+	// Code and Details are those of the error envelope the failure maps to.
+	Code    string
+	Details any
+
+	// Seed is the resolved seed of the first image and ImageCount the batch
+	// size, both known from the request before the job runs.
+	Seed       int64
+	ImageCount int
+	Images     [][]byte
+	MimeType   string
+	Filename   string
+	CreatedAt  time.Time
+	FinishedAt time.Time
+}
+
+type GenerationService struct {
+	hub     *Hub
+	workers []*generationWorker
+	history *history.Store
+	hashes  *modelhash.Cache
+	compat  *LoraCompat
+
+	// pending is ordered by arrival; next() may pick a later job whose
+	// model is already loaded. cond is signalled on enqueue and shutdown.
+	pending   []GenerationJob
+	queueSize int
+	headSkips int
+	cond      *sync.Cond
+	runners   int
+	group     errgroup.Group
+
+	mu      sync.RWMutex
+	closing bool
+	ctx     context.Context
+	logger  *log.Logger
+
+	results map[string]*GenerationResult // key: jobId
+}
+
+// NewGenerationService runs up to config.MaxConcurrent jobs per worker in
+// the pool.
+func NewGenerationService(hub *Hub, pool *dependencies.Pool, history *history.Store, hashes *modelhash.Cache, config config.ApiGenConfig, ctx context.Context) *GenerationService {
+	s := &GenerationService{
+		hub:       hub,
+		history:   history,
+		hashes:    hashes,
+		queueSize: config.QueueSize,
+		runners:   config.MaxConcurrent * len(pool.Workers()),
+		ctx:       ctx,
+		logger:    log.With("component", "generator"),
+		results:   map[string]*GenerationResult{},
+	}
+	s.cond = sync.NewCond(&s.mu)
+	for _, w := range pool.Workers() {
+		s.workers = append(s.workers, newGenerationWorker(w))
+	}
+	return s
+}
+
+// CheckLoras makes jobs that bring their own LoRA stack check it against the
+// model it is applied to; mismatches fail the job with an
+// IncompatibleLorasError. Set it before Run.
+func (g *GenerationService) CheckLoras(c *LoraCompat) {
+	g.compat = c
+}
+
+func (g *GenerationService) Run() {
+	for i := 0; i < g.runners; i++ {
+		g.group.Go(func() error {
+			for {
+				job, ok := g.next()
+				if !ok {
+					return nil
+				}
+				g.runJob(job)
+			}
+		})
+	}
+
+	go func() {
+		t := time.NewTicker(generationPrunePeriod)
+		defer t.Stop()
+		for {
+			select {
+			case <-g.ctx.Done():
+				g.mu.Lock()
+				g.cond.Broadcast()
+				g.mu.Unlock()
+				return
+			case now := <-t.C:
+				g.mu.Lock()
+				g.pruneLocked(now)
+				g.mu.Unlock()
+			}
+		}
+	}()
+}
+
+func (g *GenerationService) Enqueue(job GenerationJob) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closing {
+		return ErrGenerationShuttingDown
+	}
+
+	g.pruneLocked(time.Now())
+	if len(g.pending) >= g.queueSize {
+		return ErrGenerationQueueFull
+	}
+
+	g.pending = append(g.pending, job)
+	g.cond.Signal()
+	if job.done != nil {
+		g.logger.Debug("generation enqueued", "jobId", job.JobID, "sync", true, "modelPath", job.Request.ModelPath)
+		return nil
+	}
+	r := &GenerationResult{
+		JobID:      job.JobID,
+		ClientID:   job.ClientID,
+		Status:     GenerationQueued,
+		ImageCount: int(job.Request.BatchSize),
+		CreatedAt:  time.Now(),
+	}
+	if job.Request.Seed != nil {
+		r.Seed = *job.Request.Seed
+	}
+	g.results[job.JobID] = r
+	g.logger.Debug("generation enqueued", "jobId", job.JobID, "clientId", job.ClientID, "modelPath", job.Request.ModelPath)
+	return nil
+}
+
+// next blocks until a job is pending and removes the one to run next. It
+// returns false once the service is shutting down.
+func (g *GenerationService) next() (GenerationJob, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for len(g.pending) == 0 && !g.closing && g.ctx.Err() == nil {
+		g.cond.Wait()
+	}
+	if g.closing || g.ctx.Err() != nil {
+		return GenerationJob{}, false
+	}
+
+	i := g.pickLocked()
+	job := g.pending[i]
+	g.pending = append(g.pending[:i], g.pending[i+1:]...)
+	if i == 0 {
+		g.headSkips = 0
+	} else {
+		g.headSkips++
+		g.logger.Debug("generation reordered for loaded model", "jobId", job.JobID, "skipped", i)
+	}
+	return job, true
+}
+
+// Result returns a copy of the job's current state.
+func (g *GenerationService) Result(jobID string) (GenerationResult, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	r, ok := g.results[jobID]
+	if !ok {
+		return GenerationResult{}, ErrGenerationNotFound
+	}
+	return *r, nil
+}
+
+// Shutdown stops the runners, waits for running jobs and fails whatever is
+// still queued, telling each job's client.
+func (g *GenerationService) Shutdown() {
+	g.mu.Lock()
+	g.closing = true
+	g.cond.Broadcast()
+	g.mu.Unlock()
+	_ = g.group.Wait()
+
+	g.mu.Lock()
+	dropped := g.pending
+	g.pending = nil
+	g.mu.Unlock()
+	for _, job := range dropped {
+		g.fail(job, ErrGenerationShuttingDown)
+	}
+}
+
+func (g *GenerationService) pruneLocked(now time.Time) {
+	for id, r := range g.results {
+		if !r.FinishedAt.IsZero() && now.Sub(r.FinishedAt) > generationResultTTL {
+			delete(g.results, id)
+		}
+	}
+}
+
+func (g *GenerationService) update(jobID string, fn func(r *GenerationResult)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if r, ok := g.results[jobID]; ok {
+		fn(r)
+	}
+}
+
+// fail records the job as failed and sends generation.failed to its client.
+func (g *GenerationService) fail(job GenerationJob, err error) {
+	if job.done != nil {
+		job.done <- generationOutcome{err: err}
+		return
+	}
+	class, _ := classifyError(err)
+	var details any
+	var de detailedError
+	if errors.As(err, &de) {
+		details = de.ErrorDetails()
+	}
+	g.update(job.JobID, func(r *GenerationResult) {
+		r.Status = GenerationFailed
+		r.Error = err.Error()
+		r.Code = class.code
+		r.Details = details
+		r.FinishedAt = time.Now()
+	})
+	g.hub.SendTo(job.ClientID, WSEvent{
+		Type:    EventGenerationFailed,
+		JobID:   job.JobID,
+		Message: err.Error(),
+		Code:    class.code,
+	})
+}
+
+func (g *GenerationService) runJob(job GenerationJob) {
+	if g.ctx.Err() != nil {
+		g.fail(job, ErrGenerationShuttingDown)
+		return
+	}
+
+	g.logger.Info("generation started", "jobId", job.JobID, "clientId", job.ClientID)
+	g.update(job.JobID, func(r *GenerationResult) { r.Status = GenerationRunning })
+
+	start := time.Now()
+	resp, state, err := g.generate(job)
+	if err != nil {
+		g.logger.Error("generation failed", "jobId", job.JobID, "dur", time.Since(start).String(), "err", err)
+		g.fail(job, err)
+		return
+	}
+	g.finish(job, resp, state, start)
+	if job.done != nil {
+		g.logger.Info("generation completed", "jobId", job.JobID, "dur", time.Since(start).String(), "seed", resp.Seed, "images", len(resp.Images))
+		job.done <- generationOutcome{resp: resp}
+		return
+	}
+
+	g.update(job.JobID, func(r *GenerationResult) {
+		r.Status = GenerationCompleted
+		r.Seed = resp.Seed
+		r.Images = responseImages(resp)
+		r.ImageCount = len(r.Images)
+		r.MimeType = resp.MimeType
+		r.Filename = resp.FilenameHint
+		r.FinishedAt = time.Now()
+	})
+
+	g.logger.Info("generation completed", "jobId", job.JobID, "dur", time.Since(start).String(), "seed", resp.Seed, "images", len(resp.Images))
+	g.hub.SendTo(job.ClientID, WSEvent{
+		Type:    EventGenerationCompleted,
+		JobID:   job.JobID,
+		Message: "generation complete",
+	})
+}
+
+// Generate queues the job like Enqueue, so it counts against the same queue
+// size and runner limit, and waits for it to finish. It is recorded in the
+// history like any other job.
+func (g *GenerationService) Generate(job GenerationJob) (*proto.GenerateImageResponse, error) {
+	job.done = make(chan generationOutcome, 1)
+	if err := g.Enqueue(job); err != nil {
+		return nil, err
+	}
+	out := <-job.done
+	return out.resp, out.err
+}
+
+// generate routes the job to a worker and brings that worker into the
+// job's model and LoRA state, then streams the job so step progress reaches
+// the client, falling back to the unary RPC for workers that don't
+// implement streaming. Jobs without a client have nobody to report progress
+// to and go straight to unary.
+func (g *GenerationService) generate(job GenerationJob) (*proto.GenerateImageResponse, workerState, error) {
+	w, err := g.route(job.Request)
+	if err != nil {
+		return nil, workerState{}, err
+	}
+	g.logger.Debug("generation routed", "jobId", job.JobID, "worker", w.Addr)
+
+	state, err := w.acquire(job.Request, g.compat)
+	if err != nil {
+		return nil, state, fmt.Errorf("worker %s: %w", w.Addr, err)
+	}
+	defer w.release()
+
+	req := generateImageRequestProto(job.Request)
+	if job.ClientID == "" {
+		resp, err := w.GenerateImage(req)
+		return resp, state, err
+	}
+	resp, err := w.GenerateImageStream(req, func(p *proto.GenerationProgress) {
+		g.hub.SendTo(job.ClientID, WSEvent{
+			Type:            EventGenerationProgress,
+			JobID:           job.JobID,
+			Step:            p.Step,
+			TotalSteps:      p.TotalSteps,
+			Preview:         p.Preview,
+			PreviewMimeType: p.PreviewMimeType,
+		})
+	})
+	if status.Code(err) == codes.Unimplemented {
+		g.logger.Warn("worker does not support streaming; using unary generate", "jobId", job.JobID)
+		resp, err = w.GenerateImage(req)
+	}
+	return resp, state, err
+}
+
+// finish embeds the generation parameters into each PNG of the batch and
+// records the job in the history. resp is updated in place so callers serve
+// the annotated images.
+func (g *GenerationService) finish(job GenerationJob, resp *proto.GenerateImageResponse, state workerState, start time.Time) {
+	images := responseImages(resp)
+	for i, img := range images {
+		if !imaging.IsPNG(img) {
+			continue
+		}
+		annotated, err := imaging.SetText(img, imaging.ParametersKey, g.infotext(job.Request, state, resp.Seed+int64(i)).String())
+		if err != nil {
+			g.logger.Warn("embed parameters failed", "jobId", job.JobID, "index", i, "err", err)
+			continue
+		}
+		images[i] = annotated
+	}
+	resp.Images = images
+	resp.Image = images[0]
+
+	g.record(job, resp, state, start)
+}
+
+func (g *GenerationService) infotext(req types.ImagePostRequest, state workerState, seed int64) imaging.Parameters {
+	p := imaging.Parameters{
+		Prompt:         req.PositivePrompt,
+		NegativePrompt: req.NegativePrompt,
+		Steps:          req.Steps,
+		Sampler:        knownSchedulers[req.Scheduler],
+		CFGScale:       *req.GuidanceScale,
+		Seed:           seed,
+		Width:          req.Width,
+		Height:         req.Height,
+		Model:          fileStem(state.ModelPath),
+	}
+	// diffusers counts skipped layers, A1111 counts from 1 (= none skipped)
+	if req.ClipSkip > 0 {
+		p.ClipSkip = req.ClipSkip + 1
+	}
+	if state.ModelPath != "" && g.hashes != nil {
+		if sum, ok := g.hashes.Lookup(state.ModelPath); ok {
+			p.ModelHash = modelhash.Short(sum)
+		}
+	}
+	for _, l := range state.Loras {
+		p.Loras = append(p.Loras, imaging.Lora{Name: fileStem(l.Path), Weight: l.Weight})
+	}
+	return p
+}
+
+// record saves a finished generation to the history. Failures are only
+// logged: the client gets its image either way.
+func (g *GenerationService) record(job GenerationJob, resp *proto.GenerateImageResponse, state workerState, start time.Time) {
+	if g.history == nil {
+		return
+	}
+
+	req := job.Request
+	rec := history.Record{
+		ID:             job.JobID,
+		CreatedAt:      start,
+		PositivePrompt: req.PositivePrompt,
+		NegativePrompt: req.NegativePrompt,
+		ModelPath:      state.ModelPath,
+		Loras:          make([]history.Lora, 0, len(state.Loras)),
+		Params: history.Params{
+			Seed:          resp.Seed,
+			Steps:         req.Steps,
+			GuidanceScale: *req.GuidanceScale,
+			Width:         req.Width,
+			Height:        req.Height,
+			Scheduler:     req.Scheduler,
+			ClipSkip:      req.ClipSkip,
+			BatchSize:     req.BatchSize,
+		},
+		Duration: time.Since(start),
+		MimeType: resp.MimeType,
+	}
+	for _, l := range state.Loras {
+		rec.Loras = append(rec.Loras, history.Lora{Path: l.Path, Weight: l.Weight})
+	}
+
+	ext := filepath.Ext(resp.FilenameHint)
+	if ext == "" {
+		ext = ".png"
+	}
+	if _, err := g.history.Save(rec, responseImages(resp), ext); err != nil {
+		g.logger.Error("history save failed", "jobId", job.JobID, "err", err)
+	}
+}
+
+func fileStem(path string) string {
+	base := filepath.Base(path)
+	if path == "" || base == "." {
+		return ""
+	}
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// responseImages returns every image of the batch; workers that predate
+// batching only fill the single image field.
+func responseImages(resp *proto.GenerateImageResponse) [][]byte {
+	if len(resp.Images) > 0 {
+		return resp.Images
+	}
+	return [][]byte{resp.Image}
+}
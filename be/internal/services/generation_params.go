@@ -0,0 +1,163 @@
+package services
+
+import (
+	"be/proto"
+	"be/types"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+)
+
+// Defaults mirror what the python worker used before these were configurable.
+const (
+	defaultSteps         = 30
+	defaultGuidanceScale = 7
+	defaultImageSize     = 1024
+	defaultBatchSize     = 1
+
+	maxSteps         = 150
+	maxGuidanceScale = 30
+	maxClipSkip      = 12
+	maxBatchSize     = 8
+	// the worker rejects anything lower
+	minLoraWeight = 0.1
+
+	minImageSide = 256
+	maxImageSide = 2048
+	// SDXL is trained around 1MP; allow up to ~2.3MP per image and cap the
+	// whole batch so it fits on a single GPU.
+	maxImagePixels = 1536 * 1536
+	maxBatchPixels = 4 * 1024 * 1024
+)
+
+// Keys must match the SCHEDULERS table in py/services/grpc/image_service.py;
+// values are the A1111 sampler names written into PNG metadata.
+var knownSchedulers = map[string]string{
+	"euler":               "Euler",
+	"euler_a":             "Euler a",
+	"heun":                "Heun",
+	"lms":                 "LMS",
+	"ddim":                "DDIM",
+	"unipc":               "UniPC",
+	"dpmpp_2m":            "DPM++ 2M",
+	"dpmpp_2m_karras":     "DPM++ 2M Karras",
+	"dpmpp_2m_sde":        "DPM++ 2M SDE",
+	"dpmpp_2m_sde_karras": "DPM++ 2M SDE Karras",
+}
+
+type InvalidParamsError struct {
+	Field  string
+	Reason string
+}
+
+func (e InvalidParamsError) Error() string {
+	return e.Field + ": " + e.Reason
+}
+
+// normalizeImageRequest fills in defaults, validates the sampler parameters
+// and resolves a concrete seed so the request is reproducible.
+func normalizeImageRequest(req types.ImagePostRequest) (types.ImagePostRequest, error) {
+	if req.Steps == 0 {
+		req.Steps = defaultSteps
+	}
+	if req.GuidanceScale == nil {
+		scale := float32(defaultGuidanceScale)
+		req.GuidanceScale = &scale
+	}
+	if req.Width == 0 {
+		req.Width = defaultImageSize
+	}
+	if req.Height == 0 {
+		req.Height = defaultImageSize
+	}
+	if req.BatchSize == 0 {
+		req.BatchSize = defaultBatchSize
+	}
+	req.Scheduler = strings.ToLower(strings.TrimSpace(req.Scheduler))
+
+	if req.Steps < 1 || req.Steps > maxSteps {
+		return req, InvalidParamsError{"steps", fmt.Sprintf("must be between 1 and %d", maxSteps)}
+	}
+	if *req.GuidanceScale < 0 || *req.GuidanceScale > maxGuidanceScale {
+		return req, InvalidParamsError{"guidanceScale", fmt.Sprintf("must be between 0 and %d", maxGuidanceScale)}
+	}
+	if err := validateImageSide("width", req.Width); err != nil {
+		return req, err
+	}
+	if err := validateImageSide("height", req.Height); err != nil {
+		return req, err
+	}
+	pixels := int64(req.Width) * int64(req.Height)
+	if pixels > maxImagePixels {
+		return req, InvalidParamsError{"width", fmt.Sprintf("%dx%d exceeds the %d pixel budget", req.Width, req.Height, maxImagePixels)}
+	}
+	if req.BatchSize < 1 || req.BatchSize > maxBatchSize {
+		return req, InvalidParamsError{"batchSize", fmt.Sprintf("must be between 1 and %d", maxBatchSize)}
+	}
+	if pixels*int64(req.BatchSize) > maxBatchPixels {
+		return req, InvalidParamsError{"batchSize", fmt.Sprintf("batch of %d at %dx%d exceeds the %d pixel budget", req.BatchSize, req.Width, req.Height, maxBatchPixels)}
+	}
+	if req.ClipSkip < 0 || req.ClipSkip > maxClipSkip {
+		return req, InvalidParamsError{"clipSkip", fmt.Sprintf("must be between 0 and %d", maxClipSkip)}
+	}
+	if req.PreviewInterval < 0 || req.PreviewInterval > req.Steps {
+		return req, InvalidParamsError{"previewInterval", "must be between 0 and steps"}
+	}
+	if req.Scheduler != "" {
+		if _, ok := knownSchedulers[req.Scheduler]; !ok {
+			return req, InvalidParamsError{"scheduler", "must be one of " + strings.Join(schedulerNames(), ", ")}
+		}
+	}
+	req.ModelPath = strings.TrimSpace(req.ModelPath)
+	for i := range req.Loras {
+		req.Loras[i].Path = strings.TrimSpace(req.Loras[i].Path)
+		if req.Loras[i].Path == "" {
+			return req, InvalidParamsError{"loras", "path is required"}
+		}
+		if req.Loras[i].Weight < minLoraWeight {
+			return req, InvalidParamsError{"loras", fmt.Sprintf("weight must be >= %g", minLoraWeight)}
+		}
+	}
+
+	if req.Seed == nil || *req.Seed < 0 {
+		seed := rand.Int64N(1 << 32)
+		req.Seed = &seed
+	}
+	return req, nil
+}
+
+func validateImageSide(field string, v int32) error {
+	if v < minImageSide || v > maxImageSide {
+		return InvalidParamsError{field, fmt.Sprintf("must be between %d and %d", minImageSide, maxImageSide)}
+	}
+	if v%8 != 0 {
+		return InvalidParamsError{field, "must be a multiple of 8"}
+	}
+	return nil
+}
+
+func schedulerNames() []string {
+	names := make([]string, 0, len(knownSchedulers))
+	for name := range knownSchedulers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func generateImageRequestProto(req types.ImagePostRequest) *proto.GenerateImageRequest {
+	return &proto.GenerateImageRequest{
+		PositivePrompt:  req.PositivePrompt,
+		NegativePrompt:  req.NegativePrompt,
+		Seed:            req.Seed,
+		Steps:           req.Steps,
+		GuidanceScale:   req.GuidanceScale,
+		Width:           req.Width,
+		Height:          req.Height,
+		Scheduler:       req.Scheduler,
+		ClipSkip:        req.ClipSkip,
+		BatchSize:       req.BatchSize,
+		PreviewInterval: req.PreviewInterval,
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"be/types"
+	"errors"
+	"testing"
+)
+
+func TestNormalizeImageRequest(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		got, err := normalizeImageRequest(types.ImagePostRequest{PositivePrompt: "cat"})
+		if err != nil {
+			t.Fatalf("normalize: %v", err)
+		}
+		if got.Steps != defaultSteps || got.GuidanceScale == nil || *got.GuidanceScale != defaultGuidanceScale || got.Width != defaultImageSize || got.Height != defaultImageSize || got.BatchSize != defaultBatchSize {
+			t.Fatalf("defaults not applied: %+v", got)
+		}
+		if got.Seed == nil || *got.Seed < 0 {
+			t.Fatalf("expected a resolved seed, got %v", got.Seed)
+		}
+	})
+
+	t.Run("explicit_seed_kept", func(t *testing.T) {
+		seed := int64(42)
+		got, err := normalizeImageRequest(types.ImagePostRequest{Seed: &seed, Scheduler: " DPMPP_2M_Karras "})
+		if err != nil {
+			t.Fatalf("normalize: %v", err)
+		}
+		if *got.Seed != 42 {
+			t.Fatalf("seed changed: %d", *got.Seed)
+		}
+		if got.Scheduler != "dpmpp_2m_karras" {
+			t.Fatalf("scheduler not normalized: %q", got.Scheduler)
+		}
+	})
+
+	t.Run("zero_guidance_kept", func(t *testing.T) {
+		scale := float32(0)
+		got, err := normalizeImageRequest(types.ImagePostRequest{GuidanceScale: &scale})
+		if err != nil {
+			t.Fatalf("normalize: %v", err)
+		}
+		if *got.GuidanceScale != 0 {
+			t.Fatalf("guidance scale = %v, want the explicit 0", *got.GuidanceScale)
+		}
+	})
+
+	negative := float32(-1)
+	invalid := map[string]types.ImagePostRequest{
+		"width":         {Width: 1020, Height: 1024},
+		"height":        {Width: 1024, Height: 128},
+		"pixel_budget":  {Width: 2048, Height: 2048},
+		"batch_budget":  {Width: 1024, Height: 1024, BatchSize: 8},
+		"steps":         {Steps: 500},
+		"guidanceScale": {GuidanceScale: &negative},
+		"clipSkip":      {ClipSkip: 13},
+		"scheduler":     {Scheduler: "karras_magic"},
+	}
+	for name, req := range invalid {
+		t.Run("invalid_"+name, func(t *testing.T) {
+			_, err := normalizeImageRequest(req)
+			var perr InvalidParamsError
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected InvalidParamsError, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"be/internal/dependencies"
+	"be/proto"
+	"be/types"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/charmbracelet/log"
+)
+
+// A queued job whose model is already loaded may overtake the head of the
+// queue at most this many times in a row, so a job waiting for a different
+// checkpoint is never starved by a steady stream of matching ones.
+const maxModelBatch = 8
+
+// workerState is the model and LoRA stack loaded in the worker.
+type workerState struct {
+	ModelPath string
+	Loras     []*proto.SetLora
+}
+
+// satisfies reports whether a request can run on this state. Requests that
+// don't name a model accept whatever is loaded; requests that leave out
+// loras accept the current stack.
+func (s workerState) satisfies(req types.ImagePostRequest) bool {
+	if req.ModelPath != "" && req.ModelPath != s.ModelPath {
+		return false
+	}
+	if req.Loras == nil {
+		return true
+	}
+	return sameLoras(s.Loras, req.Loras)
+}
+
+func sameLoras(loaded []*proto.SetLora, want []types.SetLora) bool {
+	if len(loaded) != len(want) {
+		return false
+	}
+	key := func(path string, weight float32) string { return fmt.Sprintf("%s@%g", path, weight) }
+	a := make([]string, 0, len(loaded))
+	for _, l := range loaded {
+		a = append(a, key(l.Path, l.Weight))
+	}
+	b := make([]string, 0, len(want))
+	for _, l := range want {
+		b = append(b, key(l.Path, l.Weight))
+	}
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// generationWorker tracks the model and LoRA state of one pool worker.
+type generationWorker struct {
+	*dependencies.Worker
+
+	stateMu   sync.Mutex
+	stateCond *sync.Cond
+	loaded    atomic.Pointer[workerState]
+	active    int
+	switching int  // jobs waiting for or making a switch
+	changing  bool // a switch is talking to the worker
+	logger    *log.Logger
+}
+
+func newGenerationWorker(w *dependencies.Worker) *generationWorker {
+	gw := &generationWorker{
+		Worker: w,
+		logger: log.With("component", "generator", "worker", w.Addr),
+	}
+	gw.stateCond = sync.NewCond(&gw.stateMu)
+	return gw
+}
+
+// load counts jobs running on or waiting for the worker.
+func (w *generationWorker) load() int {
+	w.stateMu.Lock()
+	defer w.stateMu.Unlock()
+	return w.active + w.switching
+}
+
+// pickLocked returns the index of the pending job to run next: the oldest
+// job some healthy worker's loaded state already satisfies, unless the head
+// has been passed over too often. Callers hold g.mu.
+func (g *GenerationService) pickLocked() int {
+	if g.headSkips >= maxModelBatch {
+		return 0
+	}
+	var loaded []*workerState
+	for _, w := range g.workers {
+		if st := w.loaded.Load(); st != nil && w.Healthy() {
+			loaded = append(loaded, st)
+		}
+	}
+	for i, job := range g.pending {
+		for _, st := range loaded {
+			if st.satisfies(job.Request) {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// route picks the worker for a request: an idle worker that already has the
+// requested state, otherwise the least loaded healthy worker, preferring one
+// with the state on a tie.
+func (g *GenerationService) route(req types.ImagePostRequest) (*generationWorker, error) {
+	var best *generationWorker
+	bestLoad, bestMatch := 0, false
+	for _, w := range g.workers {
+		if !w.Healthy() {
+			continue
+		}
+		load := w.load()
+		st := w.loaded.Load()
+		match := st != nil && st.satisfies(req)
+		if match && load == 0 {
+			return w, nil
+		}
+		if best == nil || load < bestLoad || (load == bestLoad && match && !bestMatch) {
+			best, bestLoad, bestMatch = w, load, match
+		}
+	}
+	if best == nil {
+		return nil, dependencies.ErrNoHealthyWorkers
+	}
+	return best, nil
+}
+
+// acquire waits until the worker holds the model and LoRA stack the request
+// asks for and returns that state. Jobs sharing a state run side by side;
+// switching waits until nothing is generating, and once a switch is waiting
+// no new job joins the current state. The switch itself runs without the
+// lock so routing never waits on a model load. A request's own LoRA stack is
+// checked with compat against the model it will be applied to before
+// anything is loaded. release must be called when the job is done.
+func (w *generationWorker) acquire(req types.ImagePostRequest, compat *LoraCompat) (workerState, error) {
+	w.stateMu.Lock()
+	if w.active > 0 && w.switching == 0 {
+		if st := w.loaded.Load(); st != nil && st.satisfies(req) {
+			w.active++
+			w.stateMu.Unlock()
+			return *st, nil
+		}
+	}
+
+	w.beginChangeLocked()
+	w.stateMu.Unlock()
+
+	// The worker can also be changed through /setmodel and friends, or have
+	// restarted, so check what it actually has before deciding to switch.
+	st, err := w.queryState()
+	if err == nil && !st.satisfies(req) {
+		err = checkRequestLoras(compat, st, req)
+		if err == nil {
+			st, err = w.applyState(st, req)
+		}
+	}
+
+	w.stateMu.Lock()
+	defer w.stateMu.Unlock()
+	w.endChangeLocked()
+	if err != nil {
+		w.loaded.Store(nil)
+		return workerState{}, err
+	}
+	w.loaded.Store(&st)
+	w.active++
+	return st, nil
+}
+
+// beginChangeLocked waits until nothing generates on the worker and no other
+// change is under way, then marks a change as started. Until
+// endChangeLocked no job joins the current state. Callers hold stateMu.
+func (w *generationWorker) beginChangeLocked() {
+	w.switching++
+	for w.active > 0 || w.changing {
+		w.stateCond.Wait()
+	}
+	w.changing = true
+}
+
+func (w *generationWorker) endChangeLocked() {
+	w.changing = false
+	w.switching--
+	w.stateCond.Broadcast()
+}
+
+// Hold runs fn on the pool worker while nothing generates on it and keeps
+// jobs off it until fn returns. It is for changes to the model or LoRAs made
+// outside the scheduler, such as /setmodel; the next job checks what the
+// worker has afterwards. Workers the service doesn't know run fn as is.
+func (g *GenerationService) Hold(pw *dependencies.Worker, fn func() error) error {
+	var w *generationWorker
+	if g != nil {
+		for _, gw := range g.workers {
+			if gw.Worker == pw {
+				w = gw
+				break
+			}
+		}
+	}
+	if w == nil {
+		return fn()
+	}
+
+	w.stateMu.Lock()
+	w.beginChangeLocked()
+	w.stateMu.Unlock()
+	defer func() {
+		w.stateMu.Lock()
+		w.loaded.Store(nil)
+		w.endChangeLocked()
+		w.stateMu.Unlock()
+	}()
+	return fn()
+}
+
+// broadcastHeld is dependencies.Broadcast for calls that change the loaded
+// model or LoRAs: each worker is changed under Hold, so a running job never
+// has its checkpoint swapped underneath it.
+func broadcastHeld[T any](pool *dependencies.Pool, gen *GenerationService, fn func(*dependencies.Worker) (T, error)) (T, error) {
+	return dependencies.Broadcast(pool, func(w *dependencies.Worker) (T, error) {
+		var res T
+		err := gen.Hold(w, func() (err error) {
+			res, err = fn(w)
+			return err
+		})
+		return res, err
+	})
+}
+
+// checkRequestLoras runs the compatibility check on the LoRAs a request
+// brings, against the model it names or else the one the worker has. A
+// mismatch is the same IncompatibleLorasError /setloras fails with.
+func checkRequestLoras(compat *LoraCompat, st workerState, req types.ImagePostRequest) error {
+	if len(req.Loras) == 0 {
+		return nil
+	}
+	model := req.ModelPath
+	if model == "" {
+		model = st.ModelPath
+	}
+	return compat.Check(model, req.Loras)
+}
+
+func (w *generationWorker) release() {
+	w.stateMu.Lock()
+	w.active--
+	if w.active == 0 {
+		w.stateCond.Broadcast()
+	}
+	w.stateMu.Unlock()
+}
+
+func (w *generationWorker) queryState() (workerState, error) {
+	m, err := w.GetCurrentModel()
+	if err != nil {
+		return workerState{}, fmt.Errorf("get current model: %w", err)
+	}
+	l, err := w.GetCurrentLoras()
+	if err != nil {
+		return workerState{}, fmt.Errorf("get current loras: %w", err)
+	}
+	return workerState{ModelPath: m.ModelPath, Loras: l.Loras}, nil
+}
+
+// applyState loads the requested checkpoint and LoRA stack. Loading a
+// checkpoint drops the worker's LoRAs, so the stack is reapplied when the
+// request names one.
+func (w *generationWorker) applyState(st workerState, req types.ImagePostRequest) (workerState, error) {
+	if req.ModelPath != "" && req.ModelPath != st.ModelPath {
+		w.logger.Info("switching model", "from", st.ModelPath, "to", req.ModelPath)
+		resp, err := w.SetModel(req.ModelPath)
+		if err != nil {
+			return st, fmt.Errorf("set model %s: %w", req.ModelPath, err)
+		}
+		st = workerState{ModelPath: resp.ModelPath}
+	}
+	if req.Loras == nil || sameLoras(st.Loras, req.Loras) {
+		return st, nil
+	}
+
+	if len(req.Loras) == 0 {
+		if _, err := w.ClearLoras(); err != nil {
+			return st, fmt.Errorf("clear loras: %w", err)
+		}
+		st.Loras = nil
+		return st, nil
+	}
+
+	loras := make([]*proto.SetLora, 0, len(req.Loras))
+	for _, l := range req.Loras {
+		loras = append(loras, &proto.SetLora{Path: l.Path, Weight: l.Weight})
+	}
+	w.logger.Info("switching loras", "modelPath", st.ModelPath, "count", len(loras))
+	resp, err := w.SetLoras(loras)
+	if err != nil {
+		return st, fmt.Errorf("set loras: %w", err)
+	}
+	st.Loras = resp.Loras
+
+	// the worker skips LoRAs it can't find instead of failing
+	if !sameLoras(st.Loras, req.Loras) {
+		applied := make([]string, 0, len(st.Loras))
+		for _, l := range st.Loras {
+			applied = append(applied, l.Path)
+		}
+		return st, fmt.Errorf("worker applied %d of %d loras [%s]", len(st.Loras), len(req.Loras), strings.Join(applied, ", "))
+	}
+	return st, nil
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"be/internal/dependencies"
+	"be/proto"
+	"be/types"
+	"context"
+	"encoding/json"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/charmbracelet/log"
+)
+
+func TestPickPrefersLoadedModel(t *testing.T) {
+	job := func(id, model string) GenerationJob {
+		return GenerationJob{JobID: id, Request: types.ImagePostRequest{ModelPath: model}}
+	}
+	w := newGenerationWorker(dependencies.NewWorker("worker-a:50051", nil))
+	g := &GenerationService{workers: []*generationWorker{w}}
+	w.loaded.Store(&workerState{ModelPath: "/models/a.safetensors", Loras: []*proto.SetLora{{Path: "/loras/x.safetensors", Weight: 0.8}}})
+
+	g.pending = []GenerationJob{job("1", "/models/b.safetensors"), job("2", "/models/a.safetensors"), job("3", "")}
+	if i := g.pickLocked(); i != 1 {
+		t.Fatalf("picked %d, want the job matching the loaded model", i)
+	}
+
+	g.headSkips = maxModelBatch
+	if i := g.pickLocked(); i != 0 {
+		t.Fatalf("picked %d, want the head once it has waited long enough", i)
+	}
+
+	g.headSkips = 0
+	g.pending = []GenerationJob{job("1", "/models/b.safetensors"), {JobID: "2", Request: types.ImagePostRequest{
+		ModelPath: "/models/a.safetensors",
+		Loras:     []types.SetLora{},
+	}}}
+	if i := g.pickLocked(); i != 0 {
+		t.Fatalf("picked %d, an explicit empty lora stack should not match the loaded one", i)
+	}
+}
+
+func TestRoutePrefersIdleLoadedWorker(t *testing.T) {
+	a := newGenerationWorker(dependencies.NewWorker("worker-a:50051", nil))
+	b := newGenerationWorker(dependencies.NewWorker("worker-b:50051", nil))
+	a.loaded.Store(&workerState{ModelPath: "/models/a.safetensors"})
+	b.loaded.Store(&workerState{ModelPath: "/models/b.safetensors"})
+	g := &GenerationService{workers: []*generationWorker{a, b}}
+
+	if w, err := g.route(types.ImagePostRequest{ModelPath: "/models/b.safetensors"}); err != nil || w != b {
+		t.Fatalf("routed to %v (%v), want the worker with the model loaded", w, err)
+	}
+
+	b.active = 1
+	if w, err := g.route(types.ImagePostRequest{ModelPath: "/models/b.safetensors"}); err != nil || w != a {
+		t.Fatalf("routed to %v (%v), want the idle worker over the busy one", w, err)
+	}
+}
+
+func TestShutdownFailsPendingJobs(t *testing.T) {
+	hub := NewHub()
+	c := &WSClient{id: "ui", send: make(chan []byte, 16)}
+	hub.clients[c.id] = c
+	g := &GenerationService{
+		hub:       hub,
+		queueSize: 4,
+		ctx:       context.Background(),
+		logger:    log.With("component", "generator"),
+		results:   map[string]*GenerationResult{},
+	}
+	g.cond = sync.NewCond(&g.mu)
+	if err := g.Enqueue(GenerationJob{JobID: "1", ClientID: "ui"}); err != nil {
+		t.Fatal(err)
+	}
+	g.Shutdown()
+
+	if r, err := g.Result("1"); err != nil || r.Status != GenerationFailed || r.Code != CodeUnavailable {
+		t.Fatalf("result = %+v (%v), want failed as unavailable", r, err)
+	}
+	if len(c.send) != 1 {
+		t.Fatalf("events = %d, want one generation.failed", len(c.send))
+	}
+	var ev WSEvent
+	if err := json.Unmarshal(<-c.send, &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Type != "generation.failed" || ev.JobID != "1" || ev.Code != CodeUnavailable {
+		t.Fatalf("event = %+v", ev)
+	}
+}
+
+func TestGenerateGoesThroughTheQueue(t *testing.T) {
+	g := &GenerationService{
+		hub:       NewHub(),
+		queueSize: 2,
+		ctx:       context.Background(),
+		logger:    log.With("component", "generator"),
+		results:   map[string]*GenerationResult{},
+	}
+	g.cond = sync.NewCond(&g.mu)
+
+	// No runners: the synchronous job waits in the queue like a queued one.
+	errc := make(chan error, 1)
+	go func() {
+		_, err := g.Generate(GenerationJob{JobID: "sync"})
+		errc <- err
+	}()
+	if err := g.Enqueue(GenerationJob{JobID: "1", ClientID: "ui"}); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		g.mu.Lock()
+		n := len(g.pending)
+		g.mu.Unlock()
+		if n == 2 {
+			break
+		}
+		runtime.Gosched()
+	}
+	if _, err := g.Generate(GenerationJob{JobID: "full"}); !errors.Is(err, ErrGenerationQueueFull) {
+		t.Fatalf("generate on a full queue: %v, want ErrGenerationQueueFull", err)
+	}
+
+	g.Shutdown()
+	if err := <-errc; !errors.Is(err, ErrGenerationShuttingDown) {
+		t.Fatalf("waiting generate: %v, want ErrGenerationShuttingDown", err)
+	}
+	if _, err := g.Result("sync"); !errors.Is(err, ErrGenerationNotFound) {
+		t.Fatalf("synchronous job in the results: %v", err)
+	}
+}
+
+func TestHoldWaitsForRunningJobs(t *testing.T) {
+	pw := dependencies.NewWorker("worker-a:50051", nil)
+	w := newGenerationWorker(pw)
+	w.loaded.Store(&workerState{ModelPath: "/models/a.safetensors"})
+	g := &GenerationService{workers: []*generationWorker{w}}
+
+	w.active = 1 // a job is generating
+	held := make(chan struct{})
+	go g.Hold(pw, func() error {
+		close(held)
+		return nil
+	})
+	for w.load() < 2 {
+		runtime.Gosched()
+	}
+	select {
+	case <-held:
+		t.Fatal("changed the worker under a running job")
+	default:
+	}
+
+	w.release()
+	<-held
+	for w.load() > 0 {
+		runtime.Gosched()
+	}
+	if w.loaded.Load() != nil {
+		t.Fatal("loaded state kept after a change made outside the scheduler")
+	}
+}
@@ -1,18 +1,83 @@
 package services
 
 import (
+	"be/types"
 	"encoding/json"
 	"sync"
 
 	"github.com/charmbracelet/log"
 )
 
+// Event types sent over the hub. The README's WebSocket section lists who
+// receives each one and which fields it carries.
+const (
+	EventDownloadStarted   = "download.started"
+	EventDownloadProgress  = "download.progress"
+	EventDownloadCompleted = "download.completed"
+	EventDownloadFailed    = "download.failed"
+
+	EventGenerationProgress  = "generation.progress"
+	EventGenerationCompleted = "generation.completed"
+	EventGenerationFailed    = "generation.failed"
+
+	EventLibraryAdded   = "library.added"
+	EventLibraryRemoved = "library.removed"
+	EventLibraryChanged = "library.changed"
+
+	EventBackfillStarted   = "library.backfill.started"
+	EventBackfillProgress  = "library.backfill.progress"
+	EventBackfillCompleted = "library.backfill.completed"
+
+	EventUpdateAvailable = "library.update.available"
+)
+
 type WSEvent struct {
-	Type           string `json:"type"` // only completed/failed
+	Type           string `json:"type"` // one of the Event* constants
 	JobID          string `json:"jobId"`
-	ModelVersionID int64  `json:"modelVersionId"`
+	ModelVersionID int64  `json:"modelVersionId,omitempty"`
 	Message        string `json:"message,omitempty"`
 	Path           string `json:"path,omitempty"`
+
+	// generation.failed only: the code of the error envelope, such as
+	// failed_precondition for LoRAs that don't match the model
+	Code string `json:"code,omitempty"`
+
+	// generation.progress only
+	Step            int32  `json:"step,omitempty"`
+	TotalSteps      int32  `json:"totalSteps,omitempty"`
+	Preview         []byte `json:"preview,omitempty"` // base64 in JSON
+	PreviewMimeType string `json:"previewMimeType,omitempty"`
+
+	// download.started only: the file being fetched; Path is where it goes
+	Filename string `json:"filename,omitempty"`
+	// download.started and download.progress: bytes received and the size
+	// from Content-Length (omitted when the host didn't send one)
+	Bytes      int64 `json:"bytes,omitempty"`
+	TotalBytes int64 `json:"totalBytes,omitempty"`
+	// download.progress only: bytes/s since the previous event and since the
+	// start, and the seconds left at the average rate
+	Rate       float64 `json:"rate,omitempty"`
+	AvgRate    float64 `json:"avgRate,omitempty"`
+	EtaSeconds float64 `json:"etaSeconds,omitempty"`
+
+	// download.completed only
+	Verified bool `json:"verified,omitempty"`
+	// download.completed: every file of the job; download.failed: the files
+	// that were in place before the failure
+	Files []string `json:"files,omitempty"`
+	// download.failed only: every reason the download policy refused the file
+	Rejections []string `json:"rejections,omitempty"`
+
+	// library.added/removed/changed only: every entry of the batch
+	Entries []types.LibraryEntry `json:"entries,omitempty"`
+
+	// library.update.available only
+	Update *types.ModelUpdate `json:"update,omitempty"`
+
+	// library.backfill.* only
+	Done    int `json:"done,omitempty"`
+	Total   int `json:"total,omitempty"`
+	Matched int `json:"matched,omitempty"`
 }
 
 type Hub struct {
@@ -92,3 +157,27 @@ func (h *Hub) SendTo(clientId string, event WSEvent) {
 		h.Remove(clientId)
 	}
 }
+
+// Broadcast sends event to every connected client. Like SendTo, clients
+// that can't keep up are dropped rather than allowed to stall the others.
+func (h *Hub) Broadcast(event WSEvent) {
+	b, _ := json.Marshal(event)
+
+	var slow []string
+	h.mu.RLock()
+	sent := len(h.clients)
+	for id, c := range h.clients {
+		select {
+		case c.send <- b:
+		default:
+			slow = append(slow, id)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, id := range slow {
+		h.logger.Warn("ws send queue full; dropping client", "clientId", id, "type", event.Type)
+		h.Remove(id)
+	}
+	h.logger.Debug("ws broadcast", "type", event.Type, "clients", sent-len(slow))
+}
@@ -25,8 +25,21 @@ type GenerateImageRequest struct {
 	state          protoimpl.MessageState `protogen:"open.v1"`
 	PositivePrompt string                 `protobuf:"bytes,1,opt,name=positive_prompt,json=positivePrompt,proto3" json:"positive_prompt,omitempty"`
 	NegativePrompt string                 `protobuf:"bytes,2,opt,name=negative_prompt,json=negativePrompt,proto3" json:"negative_prompt,omitempty"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+	// Unset lets the worker pick a random seed; the one used is echoed back.
+	Seed *int64 `protobuf:"varint,3,opt,name=seed,proto3,oneof" json:"seed,omitempty"`
+	// Zero values fall back to the worker defaults.
+	Steps int32 `protobuf:"varint,4,opt,name=steps,proto3" json:"steps,omitempty"`
+	// Unset uses the worker default; 0 disables classifier-free guidance.
+	GuidanceScale *float32 `protobuf:"fixed32,5,opt,name=guidance_scale,json=guidanceScale,proto3,oneof" json:"guidance_scale,omitempty"`
+	Width         int32    `protobuf:"varint,6,opt,name=width,proto3" json:"width,omitempty"`
+	Height        int32    `protobuf:"varint,7,opt,name=height,proto3" json:"height,omitempty"`
+	Scheduler     string   `protobuf:"bytes,8,opt,name=scheduler,proto3" json:"scheduler,omitempty"`
+	ClipSkip      int32    `protobuf:"varint,9,opt,name=clip_skip,json=clipSkip,proto3" json:"clip_skip,omitempty"`
+	BatchSize     int32    `protobuf:"varint,10,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"`
+	// Streaming only: attach a low-resolution preview every N steps; 0 disables previews.
+	PreviewInterval int32 `protobuf:"varint,11,opt,name=preview_interval,json=previewInterval,proto3" json:"preview_interval,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *GenerateImageRequest) Reset() {
@@ -73,11 +86,78 @@ func (x *GenerateImageRequest) GetNegativePrompt() string {
 	return ""
 }
 
+func (x *GenerateImageRequest) GetSeed() int64 {
+	if x != nil && x.Seed != nil {
+		return *x.Seed
+	}
+	return 0
+}
+
+func (x *GenerateImageRequest) GetSteps() int32 {
+	if x != nil {
+		return x.Steps
+	}
+	return 0
+}
+
+func (x *GenerateImageRequest) GetGuidanceScale() float32 {
+	if x != nil && x.GuidanceScale != nil {
+		return *x.GuidanceScale
+	}
+	return 0
+}
+
+func (x *GenerateImageRequest) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *GenerateImageRequest) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *GenerateImageRequest) GetScheduler() string {
+	if x != nil {
+		return x.Scheduler
+	}
+	return ""
+}
+
+func (x *GenerateImageRequest) GetClipSkip() int32 {
+	if x != nil {
+		return x.ClipSkip
+	}
+	return 0
+}
+
+func (x *GenerateImageRequest) GetBatchSize() int32 {
+	if x != nil {
+		return x.BatchSize
+	}
+	return 0
+}
+
+func (x *GenerateImageRequest) GetPreviewInterval() int32 {
+	if x != nil {
+		return x.PreviewInterval
+	}
+	return 0
+}
+
 type GenerateImageResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Image         []byte                 `protobuf:"bytes,1,opt,name=image,proto3" json:"image,omitempty"`
-	MimeType      string                 `protobuf:"bytes,2,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"`
-	FilenameHint  string                 `protobuf:"bytes,3,opt,name=filename_hint,json=filenameHint,proto3" json:"filename_hint,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// First image of the batch.
+	Image        []byte `protobuf:"bytes,1,opt,name=image,proto3" json:"image,omitempty"`
+	MimeType     string `protobuf:"bytes,2,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"`
+	FilenameHint string `protobuf:"bytes,3,opt,name=filename_hint,json=filenameHint,proto3" json:"filename_hint,omitempty"`
+	// Seed of the first image; image i of a batch used seed + i.
+	Seed          int64    `protobuf:"varint,4,opt,name=seed,proto3" json:"seed,omitempty"`
+	Images        [][]byte `protobuf:"bytes,5,rep,name=images,proto3" json:"images,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -133,6 +213,170 @@ func (x *GenerateImageResponse) GetFilenameHint() string {
 	return ""
 }
 
+func (x *GenerateImageResponse) GetSeed() int64 {
+	if x != nil {
+		return x.Seed
+	}
+	return 0
+}
+
+func (x *GenerateImageResponse) GetImages() [][]byte {
+	if x != nil {
+		return x.Images
+	}
+	return nil
+}
+
+type GenerationProgress struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Step            int32                  `protobuf:"varint,1,opt,name=step,proto3" json:"step,omitempty"`
+	TotalSteps      int32                  `protobuf:"varint,2,opt,name=total_steps,json=totalSteps,proto3" json:"total_steps,omitempty"`
+	Preview         []byte                 `protobuf:"bytes,3,opt,name=preview,proto3" json:"preview,omitempty"`
+	PreviewMimeType string                 `protobuf:"bytes,4,opt,name=preview_mime_type,json=previewMimeType,proto3" json:"preview_mime_type,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *GenerationProgress) Reset() {
+	*x = GenerationProgress{}
+	mi := &file_image_service_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerationProgress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerationProgress) ProtoMessage() {}
+
+func (x *GenerationProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_image_service_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerationProgress.ProtoReflect.Descriptor instead.
+func (*GenerationProgress) Descriptor() ([]byte, []int) {
+	return file_image_service_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GenerationProgress) GetStep() int32 {
+	if x != nil {
+		return x.Step
+	}
+	return 0
+}
+
+func (x *GenerationProgress) GetTotalSteps() int32 {
+	if x != nil {
+		return x.TotalSteps
+	}
+	return 0
+}
+
+func (x *GenerationProgress) GetPreview() []byte {
+	if x != nil {
+		return x.Preview
+	}
+	return nil
+}
+
+func (x *GenerationProgress) GetPreviewMimeType() string {
+	if x != nil {
+		return x.PreviewMimeType
+	}
+	return ""
+}
+
+type GenerateImageEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*GenerateImageEvent_Progress
+	//	*GenerateImageEvent_Result
+	Event         isGenerateImageEvent_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateImageEvent) Reset() {
+	*x = GenerateImageEvent{}
+	mi := &file_image_service_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateImageEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateImageEvent) ProtoMessage() {}
+
+func (x *GenerateImageEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_image_service_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateImageEvent.ProtoReflect.Descriptor instead.
+func (*GenerateImageEvent) Descriptor() ([]byte, []int) {
+	return file_image_service_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GenerateImageEvent) GetEvent() isGenerateImageEvent_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *GenerateImageEvent) GetProgress() *GenerationProgress {
+	if x != nil {
+		if x, ok := x.Event.(*GenerateImageEvent_Progress); ok {
+			return x.Progress
+		}
+	}
+	return nil
+}
+
+func (x *GenerateImageEvent) GetResult() *GenerateImageResponse {
+	if x != nil {
+		if x, ok := x.Event.(*GenerateImageEvent_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+type isGenerateImageEvent_Event interface {
+	isGenerateImageEvent_Event()
+}
+
+type GenerateImageEvent_Progress struct {
+	Progress *GenerationProgress `protobuf:"bytes,1,opt,name=progress,proto3,oneof"`
+}
+
+type GenerateImageEvent_Result struct {
+	Result *GenerateImageResponse `protobuf:"bytes,2,opt,name=result,proto3,oneof"`
+}
+
+func (*GenerateImageEvent_Progress) isGenerateImageEvent_Event() {}
+
+func (*GenerateImageEvent_Result) isGenerateImageEvent_Event() {}
+
 // List Modeles
 type ListModelsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -142,7 +386,7 @@ type ListModelsRequest struct {
 
 func (x *ListModelsRequest) Reset() {
 	*x = ListModelsRequest{}
-	mi := &file_image_service_proto_msgTypes[2]
+	mi := &file_image_service_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -154,7 +398,7 @@ func (x *ListModelsRequest) String() string {
 func (*ListModelsRequest) ProtoMessage() {}
 
 func (x *ListModelsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_image_service_proto_msgTypes[2]
+	mi := &file_image_service_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -167,7 +411,7 @@ func (x *ListModelsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListModelsRequest.ProtoReflect.Descriptor instead.
 func (*ListModelsRequest) Descriptor() ([]byte, []int) {
-	return file_image_service_proto_rawDescGZIP(), []int{2}
+	return file_image_service_proto_rawDescGZIP(), []int{4}
 }
 
 type ListModelResponse struct {
@@ -179,7 +423,7 @@ type ListModelResponse struct {
 
 func (x *ListModelResponse) Reset() {
 	*x = ListModelResponse{}
-	mi := &file_image_service_proto_msgTypes[3]
+	mi := &file_image_service_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -191,7 +435,7 @@ func (x *ListModelResponse) String() string {
 func (*ListModelResponse) ProtoMessage() {}
 
 func (x *ListModelResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_image_service_proto_msgTypes[3]
+	mi := &file_image_service_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -204,7 +448,7 @@ func (x *ListModelResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListModelResponse.ProtoReflect.Descriptor instead.
 func (*ListModelResponse) Descriptor() ([]byte, []int) {
-	return file_image_service_proto_rawDescGZIP(), []int{3}
+	return file_image_service_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *ListModelResponse) GetModelPaths() []string {
@@ -224,7 +468,7 @@ type SetModelRequest struct {
 
 func (x *SetModelRequest) Reset() {
 	*x = SetModelRequest{}
-	mi := &file_image_service_proto_msgTypes[4]
+	mi := &file_image_service_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -236,7 +480,7 @@ func (x *SetModelRequest) String() string {
 func (*SetModelRequest) ProtoMessage() {}
 
 func (x *SetModelRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_image_service_proto_msgTypes[4]
+	mi := &file_image_service_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -249,7 +493,7 @@ func (x *SetModelRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SetModelRequest.ProtoReflect.Descriptor instead.
 func (*SetModelRequest) Descriptor() ([]byte, []int) {
-	return file_image_service_proto_rawDescGZIP(), []int{4}
+	return file_image_service_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *SetModelRequest) GetModelPath() string {
@@ -268,7 +512,7 @@ type SetModelResponse struct {
 
 func (x *SetModelResponse) Reset() {
 	*x = SetModelResponse{}
-	mi := &file_image_service_proto_msgTypes[5]
+	mi := &file_image_service_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -280,7 +524,7 @@ func (x *SetModelResponse) String() string {
 func (*SetModelResponse) ProtoMessage() {}
 
 func (x *SetModelResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_image_service_proto_msgTypes[5]
+	mi := &file_image_service_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -293,7 +537,7 @@ func (x *SetModelResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SetModelResponse.ProtoReflect.Descriptor instead.
 func (*SetModelResponse) Descriptor() ([]byte, []int) {
-	return file_image_service_proto_rawDescGZIP(), []int{5}
+	return file_image_service_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *SetModelResponse) GetModelPath() string {
@@ -311,7 +555,7 @@ type GetCurrentModelRequest struct {
 
 func (x *GetCurrentModelRequest) Reset() {
 	*x = GetCurrentModelRequest{}
-	mi := &file_image_service_proto_msgTypes[6]
+	mi := &file_image_service_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -323,7 +567,7 @@ func (x *GetCurrentModelRequest) String() string {
 func (*GetCurrentModelRequest) ProtoMessage() {}
 
 func (x *GetCurrentModelRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_image_service_proto_msgTypes[6]
+	mi := &file_image_service_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -336,7 +580,7 @@ func (x *GetCurrentModelRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetCurrentModelRequest.ProtoReflect.Descriptor instead.
 func (*GetCurrentModelRequest) Descriptor() ([]byte, []int) {
-	return file_image_service_proto_rawDescGZIP(), []int{6}
+	return file_image_service_proto_rawDescGZIP(), []int{8}
 }
 
 type GetCurrentModelResponse struct {
@@ -348,7 +592,7 @@ type GetCurrentModelResponse struct {
 
 func (x *GetCurrentModelResponse) Reset() {
 	*x = GetCurrentModelResponse{}
-	mi := &file_image_service_proto_msgTypes[7]
+	mi := &file_image_service_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -360,7 +604,7 @@ func (x *GetCurrentModelResponse) String() string {
 func (*GetCurrentModelResponse) ProtoMessage() {}
 
 func (x *GetCurrentModelResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_image_service_proto_msgTypes[7]
+	mi := &file_image_service_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -373,7 +617,7 @@ func (x *GetCurrentModelResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetCurrentModelResponse.ProtoReflect.Descriptor instead.
 func (*GetCurrentModelResponse) Descriptor() ([]byte, []int) {
-	return file_image_service_proto_rawDescGZIP(), []int{7}
+	return file_image_service_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *GetCurrentModelResponse) GetModelPath() string {
@@ -391,7 +635,7 @@ type ClearModelRequest struct {
 
 func (x *ClearModelRequest) Reset() {
 	*x = ClearModelRequest{}
-	mi := &file_image_service_proto_msgTypes[8]
+	mi := &file_image_service_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -403,7 +647,7 @@ func (x *ClearModelRequest) String() string {
 func (*ClearModelRequest) ProtoMessage() {}
 
 func (x *ClearModelRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_image_service_proto_msgTypes[8]
+	mi := &file_image_service_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -416,7 +660,7 @@ func (x *ClearModelRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClearModelRequest.ProtoReflect.Descriptor instead.
 func (*ClearModelRequest) Descriptor() ([]byte, []int) {
-	return file_image_service_proto_rawDescGZIP(), []int{8}
+	return file_image_service_proto_rawDescGZIP(), []int{10}
 }
 
 type ClearModelResponse struct {
@@ -429,7 +673,7 @@ type ClearModelResponse struct {
 
 func (x *ClearModelResponse) Reset() {
 	*x = ClearModelResponse{}
-	mi := &file_image_service_proto_msgTypes[9]
+	mi := &file_image_service_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -441,7 +685,7 @@ func (x *ClearModelResponse) String() string {
 func (*ClearModelResponse) ProtoMessage() {}
 
 func (x *ClearModelResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_image_service_proto_msgTypes[9]
+	mi := &file_image_service_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -454,7 +698,7 @@ func (x *ClearModelResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClearModelResponse.ProtoReflect.Descriptor instead.
 func (*ClearModelResponse) Descriptor() ([]byte, []int) {
-	return file_image_service_proto_rawDescGZIP(), []int{9}
+	return file_image_service_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *ClearModelResponse) GetModelPath() string {
@@ -479,7 +723,7 @@ type GetCurrentLorasRequest struct {
 
 func (x *GetCurrentLorasRequest) Reset() {
 	*x = GetCurrentLorasRequest{}
-	mi := &file_image_service_proto_msgTypes[10]
+	mi := &file_image_service_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -491,7 +735,7 @@ func (x *GetCurrentLorasRequest) String() string {
 func (*GetCurrentLorasRequest) ProtoMessage() {}
 
 func (x *GetCurrentLorasRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_image_service_proto_msgTypes[10]
+	mi := &file_image_service_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -504,7 +748,7 @@ func (x *GetCurrentLorasRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetCurrentLorasRequest.ProtoReflect.Descriptor instead.
 func (*GetCurrentLorasRequest) Descriptor() ([]byte, []int) {
-	return file_image_service_proto_rawDescGZIP(), []int{10}
+	return file_image_service_proto_rawDescGZIP(), []int{12}
 }
 
 type GetCurrentLorasResponse struct {
@@ -516,7 +760,7 @@ type GetCurrentLorasResponse struct {
 
 func (x *GetCurrentLorasResponse) Reset() {
 	*x = GetCurrentLorasResponse{}
-	mi := &file_image_service_proto_msgTypes[11]
+	mi := &file_image_service_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -528,7 +772,7 @@ func (x *GetCurrentLorasResponse) String() string {
 func (*GetCurrentLorasResponse) ProtoMessage() {}
 
 func (x *GetCurrentLorasResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_image_service_proto_msgTypes[11]
+	mi := &file_image_service_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -541,7 +785,7 @@ func (x *GetCurrentLorasResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetCurrentLorasResponse.ProtoReflect.Descriptor instead.
 func (*GetCurrentLorasResponse) Descriptor() ([]byte, []int) {
-	return file_image_service_proto_rawDescGZIP(), []int{11}
+	return file_image_service_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *GetCurrentLorasResponse) GetLoras() []*SetLora {
@@ -559,7 +803,7 @@ type ClearLorasRequest struct {
 
 func (x *ClearLorasRequest) Reset() {
 	*x = ClearLorasRequest{}
-	mi := &file_image_service_proto_msgTypes[12]
+	mi := &file_image_service_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -571,7 +815,7 @@ func (x *ClearLorasRequest) String() string {
 func (*ClearLorasRequest) ProtoMessage() {}
 
 func (x *ClearLorasRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_image_service_proto_msgTypes[12]
+	mi := &file_image_service_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -584,7 +828,7 @@ func (x *ClearLorasRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClearLorasRequest.ProtoReflect.Descriptor instead.
 func (*ClearLorasRequest) Descriptor() ([]byte, []int) {
-	return file_image_service_proto_rawDescGZIP(), []int{12}
+	return file_image_service_proto_rawDescGZIP(), []int{14}
 }
 
 type ClearLorasResponse struct {
@@ -596,7 +840,7 @@ type ClearLorasResponse struct {
 
 func (x *ClearLorasResponse) Reset() {
 	*x = ClearLorasResponse{}
-	mi := &file_image_service_proto_msgTypes[13]
+	mi := &file_image_service_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -608,7 +852,7 @@ func (x *ClearLorasResponse) String() string {
 func (*ClearLorasResponse) ProtoMessage() {}
 
 func (x *ClearLorasResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_image_service_proto_msgTypes[13]
+	mi := &file_image_service_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -621,7 +865,7 @@ func (x *ClearLorasResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClearLorasResponse.ProtoReflect.Descriptor instead.
 func (*ClearLorasResponse) Descriptor() ([]byte, []int) {
-	return file_image_service_proto_rawDescGZIP(), []int{13}
+	return file_image_service_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *ClearLorasResponse) GetLoras() []*SetLora {
@@ -640,7 +884,7 @@ type ListLorasRequest struct {
 
 func (x *ListLorasRequest) Reset() {
 	*x = ListLorasRequest{}
-	mi := &file_image_service_proto_msgTypes[14]
+	mi := &file_image_service_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -652,7 +896,7 @@ func (x *ListLorasRequest) String() string {
 func (*ListLorasRequest) ProtoMessage() {}
 
 func (x *ListLorasRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_image_service_proto_msgTypes[14]
+	mi := &file_image_service_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -665,7 +909,7 @@ func (x *ListLorasRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListLorasRequest.ProtoReflect.Descriptor instead.
 func (*ListLorasRequest) Descriptor() ([]byte, []int) {
-	return file_image_service_proto_rawDescGZIP(), []int{14}
+	return file_image_service_proto_rawDescGZIP(), []int{16}
 }
 
 type ListLorasResponse struct {
@@ -677,7 +921,7 @@ type ListLorasResponse struct {
 
 func (x *ListLorasResponse) Reset() {
 	*x = ListLorasResponse{}
-	mi := &file_image_service_proto_msgTypes[15]
+	mi := &file_image_service_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -689,7 +933,7 @@ func (x *ListLorasResponse) String() string {
 func (*ListLorasResponse) ProtoMessage() {}
 
 func (x *ListLorasResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_image_service_proto_msgTypes[15]
+	mi := &file_image_service_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -702,7 +946,7 @@ func (x *ListLorasResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListLorasResponse.ProtoReflect.Descriptor instead.
 func (*ListLorasResponse) Descriptor() ([]byte, []int) {
-	return file_image_service_proto_rawDescGZIP(), []int{15}
+	return file_image_service_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *ListLorasResponse) GetLoraPath() []string {
@@ -723,7 +967,7 @@ type SetLora struct {
 
 func (x *SetLora) Reset() {
 	*x = SetLora{}
-	mi := &file_image_service_proto_msgTypes[16]
+	mi := &file_image_service_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -735,7 +979,7 @@ func (x *SetLora) String() string {
 func (*SetLora) ProtoMessage() {}
 
 func (x *SetLora) ProtoReflect() protoreflect.Message {
-	mi := &file_image_service_proto_msgTypes[16]
+	mi := &file_image_service_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -748,7 +992,7 @@ func (x *SetLora) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SetLora.ProtoReflect.Descriptor instead.
 func (*SetLora) Descriptor() ([]byte, []int) {
-	return file_image_service_proto_rawDescGZIP(), []int{16}
+	return file_image_service_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *SetLora) GetWeight() float32 {
@@ -774,7 +1018,7 @@ type SetLoraRequest struct {
 
 func (x *SetLoraRequest) Reset() {
 	*x = SetLoraRequest{}
-	mi := &file_image_service_proto_msgTypes[17]
+	mi := &file_image_service_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -786,7 +1030,7 @@ func (x *SetLoraRequest) String() string {
 func (*SetLoraRequest) ProtoMessage() {}
 
 func (x *SetLoraRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_image_service_proto_msgTypes[17]
+	mi := &file_image_service_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -799,7 +1043,7 @@ func (x *SetLoraRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SetLoraRequest.ProtoReflect.Descriptor instead.
 func (*SetLoraRequest) Descriptor() ([]byte, []int) {
-	return file_image_service_proto_rawDescGZIP(), []int{17}
+	return file_image_service_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *SetLoraRequest) GetLoras() []*SetLora {
@@ -818,7 +1062,7 @@ type SetLoraResponse struct {
 
 func (x *SetLoraResponse) Reset() {
 	*x = SetLoraResponse{}
-	mi := &file_image_service_proto_msgTypes[18]
+	mi := &file_image_service_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -830,7 +1074,7 @@ func (x *SetLoraResponse) String() string {
 func (*SetLoraResponse) ProtoMessage() {}
 
 func (x *SetLoraResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_image_service_proto_msgTypes[18]
+	mi := &file_image_service_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -843,7 +1087,7 @@ func (x *SetLoraResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SetLoraResponse.ProtoReflect.Descriptor instead.
 func (*SetLoraResponse) Descriptor() ([]byte, []int) {
-	return file_image_service_proto_rawDescGZIP(), []int{18}
+	return file_image_service_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *SetLoraResponse) GetLoras() []*SetLora {
@@ -857,14 +1101,39 @@ var File_image_service_proto protoreflect.FileDescriptor
 
 const file_image_service_proto_rawDesc = "" +
 	"\n" +
-	"\x13image_service.proto\x12\tgenerator\"h\n" +
+	"\x13image_service.proto\x12\tgenerator\"\x92\x03\n" +
 	"\x14GenerateImageRequest\x12'\n" +
 	"\x0fpositive_prompt\x18\x01 \x01(\tR\x0epositivePrompt\x12'\n" +
-	"\x0fnegative_prompt\x18\x02 \x01(\tR\x0enegativePrompt\"o\n" +
+	"\x0fnegative_prompt\x18\x02 \x01(\tR\x0enegativePrompt\x12\x17\n" +
+	"\x04seed\x18\x03 \x01(\x03H\x00R\x04seed\x88\x01\x01\x12\x14\n" +
+	"\x05steps\x18\x04 \x01(\x05R\x05steps\x12*\n" +
+	"\x0eguidance_scale\x18\x05 \x01(\x02H\x01R\rguidanceScale\x88\x01\x01\x12\x14\n" +
+	"\x05width\x18\x06 \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\a \x01(\x05R\x06height\x12\x1c\n" +
+	"\tscheduler\x18\b \x01(\tR\tscheduler\x12\x1b\n" +
+	"\tclip_skip\x18\t \x01(\x05R\bclipSkip\x12\x1d\n" +
+	"\n" +
+	"batch_size\x18\n" +
+	" \x01(\x05R\tbatchSize\x12)\n" +
+	"\x10preview_interval\x18\v \x01(\x05R\x0fpreviewIntervalB\a\n" +
+	"\x05_seedB\x11\n" +
+	"\x0f_guidance_scale\"\x9b\x01\n" +
 	"\x15GenerateImageResponse\x12\x14\n" +
 	"\x05image\x18\x01 \x01(\fR\x05image\x12\x1b\n" +
 	"\tmime_type\x18\x02 \x01(\tR\bmimeType\x12#\n" +
-	"\rfilename_hint\x18\x03 \x01(\tR\ffilenameHint\"\x13\n" +
+	"\rfilename_hint\x18\x03 \x01(\tR\ffilenameHint\x12\x12\n" +
+	"\x04seed\x18\x04 \x01(\x03R\x04seed\x12\x16\n" +
+	"\x06images\x18\x05 \x03(\fR\x06images\"\x8f\x01\n" +
+	"\x12GenerationProgress\x12\x12\n" +
+	"\x04step\x18\x01 \x01(\x05R\x04step\x12\x1f\n" +
+	"\vtotal_steps\x18\x02 \x01(\x05R\n" +
+	"totalSteps\x12\x18\n" +
+	"\apreview\x18\x03 \x01(\fR\apreview\x12*\n" +
+	"\x11preview_mime_type\x18\x04 \x01(\tR\x0fpreviewMimeType\"\x96\x01\n" +
+	"\x12GenerateImageEvent\x12;\n" +
+	"\bprogress\x18\x01 \x01(\v2\x1d.generator.GenerationProgressH\x00R\bprogress\x12:\n" +
+	"\x06result\x18\x02 \x01(\v2 .generator.GenerateImageResponseH\x00R\x06resultB\a\n" +
+	"\x05event\"\x13\n" +
 	"\x11ListModelsRequest\"4\n" +
 	"\x11ListModelResponse\x12\x1f\n" +
 	"\vmodel_paths\x18\x01 \x03(\tR\n" +
@@ -899,9 +1168,10 @@ const file_image_service_proto_rawDesc = "" +
 	"\x0eSetLoraRequest\x12(\n" +
 	"\x05loras\x18\x01 \x03(\v2\x12.generator.SetLoraR\x05loras\";\n" +
 	"\x0fSetLoraResponse\x12(\n" +
-	"\x05loras\x18\x01 \x03(\v2\x12.generator.SetLoraR\x05loras2\xc5\x05\n" +
+	"\x05loras\x18\x01 \x03(\v2\x12.generator.SetLoraR\x05loras2\x9e\x06\n" +
 	"\fImageService\x12R\n" +
-	"\rGenerateImage\x12\x1f.generator.GenerateImageRequest\x1a .generator.GenerateImageResponse\x12H\n" +
+	"\rGenerateImage\x12\x1f.generator.GenerateImageRequest\x1a .generator.GenerateImageResponse\x12W\n" +
+	"\x13GenerateImageStream\x12\x1f.generator.GenerateImageRequest\x1a\x1d.generator.GenerateImageEvent0\x01\x12H\n" +
 	"\n" +
 	"ListModels\x12\x1c.generator.ListModelsRequest\x1a\x1c.generator.ListModelResponse\x12C\n" +
 	"\bSetModel\x12\x1a.generator.SetModelRequest\x1a\x1b.generator.SetModelResponse\x12X\n" +
@@ -926,57 +1196,63 @@ func file_image_service_proto_rawDescGZIP() []byte {
 	return file_image_service_proto_rawDescData
 }
 
-var file_image_service_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
+var file_image_service_proto_msgTypes = make([]protoimpl.MessageInfo, 21)
 var file_image_service_proto_goTypes = []any{
 	(*GenerateImageRequest)(nil),    // 0: generator.GenerateImageRequest
 	(*GenerateImageResponse)(nil),   // 1: generator.GenerateImageResponse
-	(*ListModelsRequest)(nil),       // 2: generator.ListModelsRequest
-	(*ListModelResponse)(nil),       // 3: generator.ListModelResponse
-	(*SetModelRequest)(nil),         // 4: generator.SetModelRequest
-	(*SetModelResponse)(nil),        // 5: generator.SetModelResponse
-	(*GetCurrentModelRequest)(nil),  // 6: generator.GetCurrentModelRequest
-	(*GetCurrentModelResponse)(nil), // 7: generator.GetCurrentModelResponse
-	(*ClearModelRequest)(nil),       // 8: generator.ClearModelRequest
-	(*ClearModelResponse)(nil),      // 9: generator.ClearModelResponse
-	(*GetCurrentLorasRequest)(nil),  // 10: generator.GetCurrentLorasRequest
-	(*GetCurrentLorasResponse)(nil), // 11: generator.GetCurrentLorasResponse
-	(*ClearLorasRequest)(nil),       // 12: generator.ClearLorasRequest
-	(*ClearLorasResponse)(nil),      // 13: generator.ClearLorasResponse
-	(*ListLorasRequest)(nil),        // 14: generator.ListLorasRequest
-	(*ListLorasResponse)(nil),       // 15: generator.ListLorasResponse
-	(*SetLora)(nil),                 // 16: generator.SetLora
-	(*SetLoraRequest)(nil),          // 17: generator.SetLoraRequest
-	(*SetLoraResponse)(nil),         // 18: generator.SetLoraResponse
+	(*GenerationProgress)(nil),      // 2: generator.GenerationProgress
+	(*GenerateImageEvent)(nil),      // 3: generator.GenerateImageEvent
+	(*ListModelsRequest)(nil),       // 4: generator.ListModelsRequest
+	(*ListModelResponse)(nil),       // 5: generator.ListModelResponse
+	(*SetModelRequest)(nil),         // 6: generator.SetModelRequest
+	(*SetModelResponse)(nil),        // 7: generator.SetModelResponse
+	(*GetCurrentModelRequest)(nil),  // 8: generator.GetCurrentModelRequest
+	(*GetCurrentModelResponse)(nil), // 9: generator.GetCurrentModelResponse
+	(*ClearModelRequest)(nil),       // 10: generator.ClearModelRequest
+	(*ClearModelResponse)(nil),      // 11: generator.ClearModelResponse
+	(*GetCurrentLorasRequest)(nil),  // 12: generator.GetCurrentLorasRequest
+	(*GetCurrentLorasResponse)(nil), // 13: generator.GetCurrentLorasResponse
+	(*ClearLorasRequest)(nil),       // 14: generator.ClearLorasRequest
+	(*ClearLorasResponse)(nil),      // 15: generator.ClearLorasResponse
+	(*ListLorasRequest)(nil),        // 16: generator.ListLorasRequest
+	(*ListLorasResponse)(nil),       // 17: generator.ListLorasResponse
+	(*SetLora)(nil),                 // 18: generator.SetLora
+	(*SetLoraRequest)(nil),          // 19: generator.SetLoraRequest
+	(*SetLoraResponse)(nil),         // 20: generator.SetLoraResponse
 }
 var file_image_service_proto_depIdxs = []int32{
-	16, // 0: generator.ClearModelResponse.loras:type_name -> generator.SetLora
-	16, // 1: generator.GetCurrentLorasResponse.loras:type_name -> generator.SetLora
-	16, // 2: generator.ClearLorasResponse.loras:type_name -> generator.SetLora
-	16, // 3: generator.SetLoraRequest.loras:type_name -> generator.SetLora
-	16, // 4: generator.SetLoraResponse.loras:type_name -> generator.SetLora
-	0,  // 5: generator.ImageService.GenerateImage:input_type -> generator.GenerateImageRequest
-	2,  // 6: generator.ImageService.ListModels:input_type -> generator.ListModelsRequest
-	4,  // 7: generator.ImageService.SetModel:input_type -> generator.SetModelRequest
-	6,  // 8: generator.ImageService.GetCurrentModel:input_type -> generator.GetCurrentModelRequest
-	8,  // 9: generator.ImageService.ClearModel:input_type -> generator.ClearModelRequest
-	14, // 10: generator.ImageService.ListLoras:input_type -> generator.ListLorasRequest
-	17, // 11: generator.ImageService.SetLora:input_type -> generator.SetLoraRequest
-	10, // 12: generator.ImageService.GetCurrentLoras:input_type -> generator.GetCurrentLorasRequest
-	12, // 13: generator.ImageService.ClearLoras:input_type -> generator.ClearLorasRequest
-	1,  // 14: generator.ImageService.GenerateImage:output_type -> generator.GenerateImageResponse
-	3,  // 15: generator.ImageService.ListModels:output_type -> generator.ListModelResponse
-	5,  // 16: generator.ImageService.SetModel:output_type -> generator.SetModelResponse
-	7,  // 17: generator.ImageService.GetCurrentModel:output_type -> generator.GetCurrentModelResponse
-	9,  // 18: generator.ImageService.ClearModel:output_type -> generator.ClearModelResponse
-	15, // 19: generator.ImageService.ListLoras:output_type -> generator.ListLorasResponse
-	18, // 20: generator.ImageService.SetLora:output_type -> generator.SetLoraResponse
-	11, // 21: generator.ImageService.GetCurrentLoras:output_type -> generator.GetCurrentLorasResponse
-	13, // 22: generator.ImageService.ClearLoras:output_type -> generator.ClearLorasResponse
-	14, // [14:23] is the sub-list for method output_type
-	5,  // [5:14] is the sub-list for method input_type
-	5,  // [5:5] is the sub-list for extension type_name
-	5,  // [5:5] is the sub-list for extension extendee
-	0,  // [0:5] is the sub-list for field type_name
+	2,  // 0: generator.GenerateImageEvent.progress:type_name -> generator.GenerationProgress
+	1,  // 1: generator.GenerateImageEvent.result:type_name -> generator.GenerateImageResponse
+	18, // 2: generator.ClearModelResponse.loras:type_name -> generator.SetLora
+	18, // 3: generator.GetCurrentLorasResponse.loras:type_name -> generator.SetLora
+	18, // 4: generator.ClearLorasResponse.loras:type_name -> generator.SetLora
+	18, // 5: generator.SetLoraRequest.loras:type_name -> generator.SetLora
+	18, // 6: generator.SetLoraResponse.loras:type_name -> generator.SetLora
+	0,  // 7: generator.ImageService.GenerateImage:input_type -> generator.GenerateImageRequest
+	0,  // 8: generator.ImageService.GenerateImageStream:input_type -> generator.GenerateImageRequest
+	4,  // 9: generator.ImageService.ListModels:input_type -> generator.ListModelsRequest
+	6,  // 10: generator.ImageService.SetModel:input_type -> generator.SetModelRequest
+	8,  // 11: generator.ImageService.GetCurrentModel:input_type -> generator.GetCurrentModelRequest
+	10, // 12: generator.ImageService.ClearModel:input_type -> generator.ClearModelRequest
+	16, // 13: generator.ImageService.ListLoras:input_type -> generator.ListLorasRequest
+	19, // 14: generator.ImageService.SetLora:input_type -> generator.SetLoraRequest
+	12, // 15: generator.ImageService.GetCurrentLoras:input_type -> generator.GetCurrentLorasRequest
+	14, // 16: generator.ImageService.ClearLoras:input_type -> generator.ClearLorasRequest
+	1,  // 17: generator.ImageService.GenerateImage:output_type -> generator.GenerateImageResponse
+	3,  // 18: generator.ImageService.GenerateImageStream:output_type -> generator.GenerateImageEvent
+	5,  // 19: generator.ImageService.ListModels:output_type -> generator.ListModelResponse
+	7,  // 20: generator.ImageService.SetModel:output_type -> generator.SetModelResponse
+	9,  // 21: generator.ImageService.GetCurrentModel:output_type -> generator.GetCurrentModelResponse
+	11, // 22: generator.ImageService.ClearModel:output_type -> generator.ClearModelResponse
+	17, // 23: generator.ImageService.ListLoras:output_type -> generator.ListLorasResponse
+	20, // 24: generator.ImageService.SetLora:output_type -> generator.SetLoraResponse
+	13, // 25: generator.ImageService.GetCurrentLoras:output_type -> generator.GetCurrentLorasResponse
+	15, // 26: generator.ImageService.ClearLoras:output_type -> generator.ClearLorasResponse
+	17, // [17:27] is the sub-list for method output_type
+	7,  // [7:17] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
 }
 
 func init() { file_image_service_proto_init() }
@@ -984,13 +1260,18 @@ func file_image_service_proto_init() {
 	if File_image_service_proto != nil {
 		return
 	}
+	file_image_service_proto_msgTypes[0].OneofWrappers = []any{}
+	file_image_service_proto_msgTypes[3].OneofWrappers = []any{
+		(*GenerateImageEvent_Progress)(nil),
+		(*GenerateImageEvent_Result)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_image_service_proto_rawDesc), len(file_image_service_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   19,
+			NumMessages:   21,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,15 +19,16 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ImageService_GenerateImage_FullMethodName   = "/generator.ImageService/GenerateImage"
-	ImageService_ListModels_FullMethodName      = "/generator.ImageService/ListModels"
-	ImageService_SetModel_FullMethodName        = "/generator.ImageService/SetModel"
-	ImageService_GetCurrentModel_FullMethodName = "/generator.ImageService/GetCurrentModel"
-	ImageService_ClearModel_FullMethodName      = "/generator.ImageService/ClearModel"
-	ImageService_ListLoras_FullMethodName       = "/generator.ImageService/ListLoras"
-	ImageService_SetLora_FullMethodName         = "/generator.ImageService/SetLora"
-	ImageService_GetCurrentLoras_FullMethodName = "/generator.ImageService/GetCurrentLoras"
-	ImageService_ClearLoras_FullMethodName      = "/generator.ImageService/ClearLoras"
+	ImageService_GenerateImage_FullMethodName       = "/generator.ImageService/GenerateImage"
+	ImageService_GenerateImageStream_FullMethodName = "/generator.ImageService/GenerateImageStream"
+	ImageService_ListModels_FullMethodName          = "/generator.ImageService/ListModels"
+	ImageService_SetModel_FullMethodName            = "/generator.ImageService/SetModel"
+	ImageService_GetCurrentModel_FullMethodName     = "/generator.ImageService/GetCurrentModel"
+	ImageService_ClearModel_FullMethodName          = "/generator.ImageService/ClearModel"
+	ImageService_ListLoras_FullMethodName           = "/generator.ImageService/ListLoras"
+	ImageService_SetLora_FullMethodName             = "/generator.ImageService/SetLora"
+	ImageService_GetCurrentLoras_FullMethodName     = "/generator.ImageService/GetCurrentLoras"
+	ImageService_ClearLoras_FullMethodName          = "/generator.ImageService/ClearLoras"
 )
 
 // ImageServiceClient is the client API for ImageService service.
@@ -35,6 +36,9 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ImageServiceClient interface {
 	GenerateImage(ctx context.Context, in *GenerateImageRequest, opts ...grpc.CallOption) (*GenerateImageResponse, error)
+	// Same as GenerateImage but reports step progress (and optional previews)
+	// before the final result.
+	GenerateImageStream(ctx context.Context, in *GenerateImageRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GenerateImageEvent], error)
 	ListModels(ctx context.Context, in *ListModelsRequest, opts ...grpc.CallOption) (*ListModelResponse, error)
 	SetModel(ctx context.Context, in *SetModelRequest, opts ...grpc.CallOption) (*SetModelResponse, error)
 	GetCurrentModel(ctx context.Context, in *GetCurrentModelRequest, opts ...grpc.CallOption) (*GetCurrentModelResponse, error)
@@ -63,6 +67,25 @@ func (c *imageServiceClient) GenerateImage(ctx context.Context, in *GenerateImag
 	return out, nil
 }
 
+func (c *imageServiceClient) GenerateImageStream(ctx context.Context, in *GenerateImageRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GenerateImageEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ImageService_ServiceDesc.Streams[0], ImageService_GenerateImageStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GenerateImageRequest, GenerateImageEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ImageService_GenerateImageStreamClient = grpc.ServerStreamingClient[GenerateImageEvent]
+
 func (c *imageServiceClient) ListModels(ctx context.Context, in *ListModelsRequest, opts ...grpc.CallOption) (*ListModelResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ListModelResponse)
@@ -148,6 +171,9 @@ func (c *imageServiceClient) ClearLoras(ctx context.Context, in *ClearLorasReque
 // for forward compatibility.
 type ImageServiceServer interface {
 	GenerateImage(context.Context, *GenerateImageRequest) (*GenerateImageResponse, error)
+	// Same as GenerateImage but reports step progress (and optional previews)
+	// before the final result.
+	GenerateImageStream(*GenerateImageRequest, grpc.ServerStreamingServer[GenerateImageEvent]) error
 	ListModels(context.Context, *ListModelsRequest) (*ListModelResponse, error)
 	SetModel(context.Context, *SetModelRequest) (*SetModelResponse, error)
 	GetCurrentModel(context.Context, *GetCurrentModelRequest) (*GetCurrentModelResponse, error)
@@ -169,6 +195,9 @@ type UnimplementedImageServiceServer struct{}
 func (UnimplementedImageServiceServer) GenerateImage(context.Context, *GenerateImageRequest) (*GenerateImageResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method GenerateImage not implemented")
 }
+func (UnimplementedImageServiceServer) GenerateImageStream(*GenerateImageRequest, grpc.ServerStreamingServer[GenerateImageEvent]) error {
+	return status.Error(codes.Unimplemented, "method GenerateImageStream not implemented")
+}
 func (UnimplementedImageServiceServer) ListModels(context.Context, *ListModelsRequest) (*ListModelResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method ListModels not implemented")
 }
@@ -232,6 +261,17 @@ func _ImageService_GenerateImage_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ImageService_GenerateImageStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GenerateImageRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ImageServiceServer).GenerateImageStream(m, &grpc.GenericServerStream[GenerateImageRequest, GenerateImageEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ImageService_GenerateImageStreamServer = grpc.ServerStreamingServer[GenerateImageEvent]
+
 func _ImageService_ListModels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ListModelsRequest)
 	if err := dec(in); err != nil {
@@ -420,6 +460,12 @@ var ImageService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _ImageService_ClearLoras_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GenerateImageStream",
+			Handler:       _ImageService_GenerateImageStream_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "image_service.proto",
 }
@@ -1,8 +1,30 @@
 package types
 
+import "time"
+
 type ImagePostRequest struct {
 	PositivePrompt string `json:"positivePrompt"`
 	NegativePrompt string `json:"negativePrompt"`
+	// Seed is optional; nil or a negative value picks a random seed.
+	Seed  *int64 `json:"seed,omitempty"`
+	Steps int32  `json:"steps,omitempty"`
+	// GuidanceScale is optional; nil uses the default, 0 disables
+	// classifier-free guidance.
+	GuidanceScale *float32 `json:"guidanceScale,omitempty"`
+	Width         int32    `json:"width,omitempty"`
+	Height        int32    `json:"height,omitempty"`
+	Scheduler     string   `json:"scheduler,omitempty"`
+	ClipSkip      int32    `json:"clipSkip,omitempty"`
+	BatchSize     int32    `json:"batchSize,omitempty"`
+	// ModelPath and Loras pick the checkpoint and LoRA stack for this request;
+	// the worker is switched only when its current state differs. Leave
+	// ModelPath empty to use the loaded model and omit Loras to keep the
+	// current stack (an empty list clears it).
+	ModelPath string    `json:"modelPath,omitempty"`
+	Loras     []SetLora `json:"loras,omitempty"`
+	// PreviewInterval only applies to queued generations: every N steps the
+	// generation.progress event carries a low-resolution preview.
+	PreviewInterval int32 `json:"previewInterval,omitempty"`
 }
 
 type SetModelRequest struct {
@@ -26,6 +48,10 @@ type SetLora struct {
 	Weight       float32 `json:"weight"`
 	Path         string  `json:"path"`
 	TriggerWords *string `json:"triggerWords"`
+	// BaseModel and Description come from the downloaded metadata and are
+	// only set in responses.
+	BaseModel   string `json:"baseModel,omitempty"`
+	Description string `json:"description,omitempty"`
 }
 
 type ImagePostResponse struct {
@@ -34,24 +60,184 @@ type ImagePostResponse struct {
 	Filename   string `json:"filename,omitempty"`
 }
 
+// ErrorResponse is the body of every error reply. Code is machine readable
+// and stable; Error and Message are meant for people. Retryable says whether
+// the same request may succeed later unchanged. Details is only set by
+// errors that carry structured information, such as rejected LoRAs.
 type ErrorResponse struct {
-	Error   string `json:"error,omitempty"`
-	Message string `json:"message,omitempty"`
+	Version   int    `json:"version"`
+	Code      string `json:"code"`
+	Error     string `json:"error,omitempty"`
+	Message   string `json:"message,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+	Retryable bool   `json:"retryable"`
+	Details   any    `json:"details,omitempty"`
+}
+
+// LoraIncompatibility explains why a LoRA was not applied to the loaded
+// model. It is reported in the Details of a failed_precondition error.
+type LoraIncompatibility struct {
+	Path      string `json:"path"`
+	LoraArch  string `json:"loraArch"`
+	ModelArch string `json:"modelArch"`
+	Reason    string `json:"reason"`
 }
 
 type HealthResponse struct {
-	Status    int   `json:"status"`
-	TimeStamp int64 `json:"timestamp"`
+	Status    int          `json:"status"`
+	TimeStamp int64        `json:"timestamp"`
+	Worker    WorkerHealth `json:"worker"`
+}
+
+// WorkerHealth reports whether generation and model endpoints can be served.
+// The API stays up while every worker is down; those endpoints answer 503
+// until one comes back.
+type WorkerHealth struct {
+	Available bool           `json:"available"`
+	Workers   []WorkerStatus `json:"workers"`
+}
+
+type WorkerStatus struct {
+	Addr    string `json:"addr"`
+	Healthy bool   `json:"healthy"`
+	State   string `json:"state"` // idle, connecting, ready, transient_failure or shutdown
+}
+
+type LibraryEntry struct {
+	Path      string    `json:"path"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	BaseModel string    `json:"baseModel,omitempty"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"modTime"`
+	SHA256    string    `json:"sha256,omitempty"` // empty until the file has been hashed
+	// Verified means the download matched the hash the model host published.
+	Verified bool `json:"verified,omitempty"`
+}
+
+type LibraryListResponse struct {
+	Items  []LibraryEntry `json:"items"`
+	Total  int            `json:"total"`
+	Offset int            `json:"offset"`
+	Limit  int            `json:"limit"`
+}
+
+// ModelInfoResponse describes a .safetensors file from its header alone.
+// Type and Arch are empty when they can't be told.
+type ModelInfoResponse struct {
+	Path       string            `json:"path"`
+	Type       string            `json:"type"` // checkpoint, lora, vae, embedding or controlnet
+	Arch       string            `json:"arch"` // sd1, sd2, sdxl, sd3 or flux
+	Precision  string            `json:"precision,omitempty"`
+	Tensors    int               `json:"tensors"`
+	HeaderSize int64             `json:"headerSize"`
+	Metadata   map[string]string `json:"metadata"`
+	Training   *TrainingInfo     `json:"training,omitempty"`
+}
+
+// TrainingInfo holds the kohya (ss_*) training fields of a LoRA.
+type TrainingInfo struct {
+	BaseModel        string     `json:"baseModel,omitempty"`
+	BaseModelVersion string     `json:"baseModelVersion,omitempty"`
+	NetworkModule    string     `json:"networkModule,omitempty"`
+	NetworkDim       int        `json:"networkDim,omitempty"`
+	NetworkAlpha     float64    `json:"networkAlpha,omitempty"`
+	OutputName       string     `json:"outputName,omitempty"`
+	Resolution       string     `json:"resolution,omitempty"`
+	Epochs           int        `json:"epochs,omitempty"`
+	Steps            int        `json:"steps,omitempty"`
+	Comment          string     `json:"comment,omitempty"`
+	Tags             []TagCount `json:"tags,omitempty"`
+}
+
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
 }
 
+// ListModelsResponse keeps the bare path list for older clients next to the
+// catalog entries; both cover the same page.
 type ListModelsResponse struct {
 	ModelPaths []string `json:"modelPaths"`
+	LibraryListResponse
 }
 
 type ListLorasResponse struct {
 	LoraPaths []string `json:"lorapaths"`
+	LibraryListResponse
+}
+
+// ModelUpdate is a downloaded file whose model has newer versions for the
+// same base model on the model host.
+type ModelUpdate struct {
+	Path             string         `json:"path"`
+	ModelID          int64          `json:"modelId"`
+	ModelName        string         `json:"modelName,omitempty"`
+	BaseModel        string         `json:"baseModel"`
+	CurrentVersionID int64          `json:"currentVersionId"`
+	CurrentVersion   string         `json:"currentVersion,omitempty"`
+	Newer            []ModelVersion `json:"newer"` // newest first
+}
+
+type ModelVersion struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type LibraryUpdatesResponse struct {
+	Items     []ModelUpdate `json:"items"`
+	CheckedAt time.Time     `json:"checkedAt"` // zero until the first check has finished
 }
 
 type DownloadResponse struct {
 	JobID string `json:"jobId"`
+	// ModelVersionID is the version being downloaded, resolved from the
+	// model ID when the request named a model.
+	ModelVersionID int64 `json:"modelVersionId,omitempty"`
+}
+
+type GenerationResponse struct {
+	JobID string `json:"jobId"`
+}
+
+type GenerationStatusResponse struct {
+	JobID      string `json:"jobId"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	Code       string `json:"code,omitempty"`       // error envelope code of a failed job
+	Details    any    `json:"details,omitempty"`    // as in the error envelope
+	Seed       *int64 `json:"seed,omitempty"`       // of the first image; image i used seed + i
+	ImageCount int    `json:"imageCount,omitempty"` // images the job produces
+}
+
+type HistoryLora struct {
+	Path   string  `json:"path"`
+	Weight float32 `json:"weight"`
+}
+
+type HistoryEntry struct {
+	ID             string        `json:"id"`
+	CreatedAt      time.Time     `json:"createdAt"`
+	PositivePrompt string        `json:"positivePrompt"`
+	NegativePrompt string        `json:"negativePrompt"`
+	ModelPath      string        `json:"modelPath"`
+	Loras          []HistoryLora `json:"loras"`
+	Seed           int64         `json:"seed"`
+	Steps          int32         `json:"steps"`
+	GuidanceScale  float32       `json:"guidanceScale"`
+	Width          int32         `json:"width"`
+	Height         int32         `json:"height"`
+	Scheduler      string        `json:"scheduler,omitempty"`
+	ClipSkip       int32         `json:"clipSkip,omitempty"`
+	BatchSize      int32         `json:"batchSize"`
+	DurationMs     int64         `json:"durationMs"`
+	MimeType       string        `json:"mimeType"`
+	ImageCount     int           `json:"imageCount"`
+}
+
+type HistoryListResponse struct {
+	Items  []HistoryEntry `json:"items"`
+	Total  int            `json:"total"`
+	Offset int            `json:"offset"`
+	Limit  int            `json:"limit"`
 }
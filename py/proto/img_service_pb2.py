@@ -24,7 +24,7 @@ _sym_db = _symbol_database.Default()
 
 
 
-DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x11img_service.proto\x12\tgenerator\"H\n\x14GenerateImageRequest\x12\x17\n\x0fpositive_prompt\x18\x01 \x01(\t\x12\x17\n\x0fnegative_prompt\x18\x02 \x01(\t\"P\n\x15GenerateImageResponse\x12\r\n\x05image\x18\x01 \x01(\x0c\x12\x11\n\tmime_type\x18\x02 \x01(\t\x12\x15\n\rfilename_hint\x18\x03 \x01(\t\"\x13\n\x11ListModelsRequest\"(\n\x11ListModelResponse\x12\x13\n\x0bmodel_paths\x18\x01 \x03(\t\"%\n\x0fSetModelRequest\x12\x12\n\nmodel_path\x18\x01 \x01(\t\"&\n\x10SetModelResponse\x12\x12\n\nmodel_path\x18\x01 \x01(\t\"\x18\n\x16GetCurrentModelRequest\"-\n\x17GetCurrentModelResponse\x12\x12\n\nmodel_path\x18\x01 \x01(\t\"\x13\n\x11\x43learModelRequest\"K\n\x12\x43learModelResponse\x12\x12\n\nmodel_path\x18\x01 \x01(\t\x12!\n\x05loras\x18\x02 \x03(\x0b\x32\x12.generator.SetLora\"\x18\n\x16GetCurrentLorasRequest\"<\n\x17GetCurrentLorasResponse\x12!\n\x05loras\x18\x01 \x03(\x0b\x32\x12.generator.SetLora\"\x13\n\x11\x43learLorasRequest\"7\n\x12\x43learLorasResponse\x12!\n\x05loras\x18\x01 \x03(\x0b\x32\x12.generator.SetLora\"\x12\n\x10ListLorasRequest\"&\n\x11ListLorasResponse\x12\x11\n\tlora_path\x18\x01 \x03(\t\"\'\n\x07SetLora\x12\x0e\n\x06weight\x18\x01 \x01(\x02\x12\x0c\n\x04path\x18\x02 \x01(\t\"3\n\x0eSetLoraRequest\x12!\n\x05loras\x18\x01 \x03(\x0b\x32\x12.generator.SetLora\"4\n\x0fSetLoraResponse\x12!\n\x05loras\x18\x01 \x03(\x0b\x32\x12.generator.SetLora2\xc5\x05\n\x0cImageService\x12R\n\rGenerateImage\x12\x1f.generator.GenerateImageRequest\x1a .generator.GenerateImageResponse\x12H\n\nListModels\x12\x1c.generator.ListModelsRequest\x1a\x1c.generator.ListModelResponse\x12\x43\n\x08SetModel\x12\x1a.generator.SetModelRequest\x1a\x1b.generator.SetModelResponse\x12X\n\x0fGetCurrentModel\x12!.generator.GetCurrentModelRequest\x1a\".generator.GetCurrentModelResponse\x12I\n\nClearModel\x12\x1c.generator.ClearModelRequest\x1a\x1d.generator.ClearModelResponse\x12\x46\n\tListLoras\x12\x1b.generator.ListLorasRequest\x1a\x1c.generator.ListLorasResponse\x12@\n\x07SetLora\x12\x19.generator.SetLoraRequest\x1a\x1a.generator.SetLoraResponse\x12X\n\x0fGetCurrentLoras\x12!.generator.GetCurrentLorasRequest\x1a\".generator.GetCurrentLorasResponse\x12I\n\nClearLoras\x12\x1c.generator.ClearLorasRequest\x1a\x1d.generator.ClearLorasResponseB\x08Z\x06proto/b\x06proto3')
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x11img_service.proto\x12\tgenerator\"\x96\x02\n\x14GenerateImageRequest\x12\x17\n\x0fpositive_prompt\x18\x01 \x01(\t\x12\x17\n\x0fnegative_prompt\x18\x02 \x01(\t\x12\x11\n\x04seed\x18\x03 \x01(\x03H\x00\x88\x01\x01\x12\r\n\x05steps\x18\x04 \x01(\x05\x12\x1b\n\x0eguidance_scale\x18\x05 \x01(\x02H\x01\x88\x01\x01\x12\r\n\x05width\x18\x06 \x01(\x05\x12\x0e\n\x06height\x18\x07 \x01(\x05\x12\x11\n\tscheduler\x18\x08 \x01(\t\x12\x11\n\tclip_skip\x18\t \x01(\x05\x12\x12\n\nbatch_size\x18\n \x01(\x05\x12\x18\n\x10preview_interval\x18\x0b \x01(\x05\x42\x07\n\x05_seedB\x11\n\x0f_guidance_scale\"n\n\x15GenerateImageResponse\x12\r\n\x05image\x18\x01 \x01(\x0c\x12\x11\n\tmime_type\x18\x02 \x01(\t\x12\x15\n\rfilename_hint\x18\x03 \x01(\t\x12\x0c\n\x04seed\x18\x04 \x01(\x03\x12\x0e\n\x06images\x18\x05 \x03(\x0c\"c\n\x12GenerationProgress\x12\x0c\n\x04step\x18\x01 \x01(\x05\x12\x13\n\x0btotal_steps\x18\x02 \x01(\x05\x12\x0f\n\x07preview\x18\x03 \x01(\x0c\x12\x19\n\x11preview_mime_type\x18\x04 \x01(\t\"\x84\x01\n\x12GenerateImageEvent\x12\x31\n\x08progress\x18\x01 \x01(\x0b\x32\x1d.generator.GenerationProgressH\x00\x12\x32\n\x06result\x18\x02 \x01(\x0b\x32 .generator.GenerateImageResponseH\x00\x42\x07\n\x05\x65vent\"\x13\n\x11ListModelsRequest\"(\n\x11ListModelResponse\x12\x13\n\x0bmodel_paths\x18\x01 \x03(\t\"%\n\x0fSetModelRequest\x12\x12\n\nmodel_path\x18\x01 \x01(\t\"&\n\x10SetModelResponse\x12\x12\n\nmodel_path\x18\x01 \x01(\t\"\x18\n\x16GetCurrentModelRequest\"-\n\x17GetCurrentModelResponse\x12\x12\n\nmodel_path\x18\x01 \x01(\t\"\x13\n\x11\x43learModelRequest\"K\n\x12\x43learModelResponse\x12\x12\n\nmodel_path\x18\x01 \x01(\t\x12!\n\x05loras\x18\x02 \x03(\x0b\x32\x12.generator.SetLora\"\x18\n\x16GetCurrentLorasRequest\"<\n\x17GetCurrentLorasResponse\x12!\n\x05loras\x18\x01 \x03(\x0b\x32\x12.generator.SetLora\"\x13\n\x11\x43learLorasRequest\"7\n\x12\x43learLorasResponse\x12!\n\x05loras\x18\x01 \x03(\x0b\x32\x12.generator.SetLora\"\x12\n\x10ListLorasRequest\"&\n\x11ListLorasResponse\x12\x11\n\tlora_path\x18\x01 \x03(\t\"\'\n\x07SetLora\x12\x0e\n\x06weight\x18\x01 \x01(\x02\x12\x0c\n\x04path\x18\x02 \x01(\t\"3\n\x0eSetLoraRequest\x12!\n\x05loras\x18\x01 \x03(\x0b\x32\x12.generator.SetLora\"4\n\x0fSetLoraResponse\x12!\n\x05loras\x18\x01 \x03(\x0b\x32\x12.generator.SetLora2\x9e\x06\n\x0cImageService\x12R\n\rGenerateImage\x12\x1f.generator.GenerateImageRequest\x1a .generator.GenerateImageResponse\x12W\n\x13GenerateImageStream\x12\x1f.generator.GenerateImageRequest\x1a\x1d.generator.GenerateImageEvent0\x01\x12H\n\nListModels\x12\x1c.generator.ListModelsRequest\x1a\x1c.generator.ListModelResponse\x12\x43\n\x08SetModel\x12\x1a.generator.SetModelRequest\x1a\x1b.generator.SetModelResponse\x12X\n\x0fGetCurrentModel\x12!.generator.GetCurrentModelRequest\x1a\".generator.GetCurrentModelResponse\x12I\n\nClearModel\x12\x1c.generator.ClearModelRequest\x1a\x1d.generator.ClearModelResponse\x12\x46\n\tListLoras\x12\x1b.generator.ListLorasRequest\x1a\x1c.generator.ListLorasResponse\x12@\n\x07SetLora\x12\x19.generator.SetLoraRequest\x1a\x1a.generator.SetLoraResponse\x12X\n\x0fGetCurrentLoras\x12!.generator.GetCurrentLorasRequest\x1a\".generator.GetCurrentLorasResponse\x12I\n\nClearLoras\x12\x1c.generator.ClearLorasRequest\x1a\x1d.generator.ClearLorasResponseB\x08Z\x06proto/b\x06proto3')
 
 _globals = globals()
 _builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
@@ -32,44 +32,48 @@ _builder.BuildTopDescriptorsAndMessages(DESCRIPTOR, 'img_service_pb2', _globals)
 if not _descriptor._USE_C_DESCRIPTORS:
   _globals['DESCRIPTOR']._loaded_options = None
   _globals['DESCRIPTOR']._serialized_options = b'Z\006proto/'
-  _globals['_GENERATEIMAGEREQUEST']._serialized_start=32
-  _globals['_GENERATEIMAGEREQUEST']._serialized_end=104
-  _globals['_GENERATEIMAGERESPONSE']._serialized_start=106
-  _globals['_GENERATEIMAGERESPONSE']._serialized_end=186
-  _globals['_LISTMODELSREQUEST']._serialized_start=188
-  _globals['_LISTMODELSREQUEST']._serialized_end=207
-  _globals['_LISTMODELRESPONSE']._serialized_start=209
-  _globals['_LISTMODELRESPONSE']._serialized_end=249
-  _globals['_SETMODELREQUEST']._serialized_start=251
-  _globals['_SETMODELREQUEST']._serialized_end=288
-  _globals['_SETMODELRESPONSE']._serialized_start=290
-  _globals['_SETMODELRESPONSE']._serialized_end=328
-  _globals['_GETCURRENTMODELREQUEST']._serialized_start=330
-  _globals['_GETCURRENTMODELREQUEST']._serialized_end=354
-  _globals['_GETCURRENTMODELRESPONSE']._serialized_start=356
-  _globals['_GETCURRENTMODELRESPONSE']._serialized_end=401
-  _globals['_CLEARMODELREQUEST']._serialized_start=403
-  _globals['_CLEARMODELREQUEST']._serialized_end=422
-  _globals['_CLEARMODELRESPONSE']._serialized_start=424
-  _globals['_CLEARMODELRESPONSE']._serialized_end=499
-  _globals['_GETCURRENTLORASREQUEST']._serialized_start=501
-  _globals['_GETCURRENTLORASREQUEST']._serialized_end=525
-  _globals['_GETCURRENTLORASRESPONSE']._serialized_start=527
-  _globals['_GETCURRENTLORASRESPONSE']._serialized_end=587
-  _globals['_CLEARLORASREQUEST']._serialized_start=589
-  _globals['_CLEARLORASREQUEST']._serialized_end=608
-  _globals['_CLEARLORASRESPONSE']._serialized_start=610
-  _globals['_CLEARLORASRESPONSE']._serialized_end=665
-  _globals['_LISTLORASREQUEST']._serialized_start=667
-  _globals['_LISTLORASREQUEST']._serialized_end=685
-  _globals['_LISTLORASRESPONSE']._serialized_start=687
-  _globals['_LISTLORASRESPONSE']._serialized_end=725
-  _globals['_SETLORA']._serialized_start=727
-  _globals['_SETLORA']._serialized_end=766
-  _globals['_SETLORAREQUEST']._serialized_start=768
-  _globals['_SETLORAREQUEST']._serialized_end=819
-  _globals['_SETLORARESPONSE']._serialized_start=821
-  _globals['_SETLORARESPONSE']._serialized_end=873
-  _globals['_IMAGESERVICE']._serialized_start=876
-  _globals['_IMAGESERVICE']._serialized_end=1585
+  _globals['_GENERATEIMAGEREQUEST']._serialized_start=33
+  _globals['_GENERATEIMAGEREQUEST']._serialized_end=311
+  _globals['_GENERATEIMAGERESPONSE']._serialized_start=313
+  _globals['_GENERATEIMAGERESPONSE']._serialized_end=423
+  _globals['_GENERATIONPROGRESS']._serialized_start=425
+  _globals['_GENERATIONPROGRESS']._serialized_end=524
+  _globals['_GENERATEIMAGEEVENT']._serialized_start=527
+  _globals['_GENERATEIMAGEEVENT']._serialized_end=659
+  _globals['_LISTMODELSREQUEST']._serialized_start=661
+  _globals['_LISTMODELSREQUEST']._serialized_end=680
+  _globals['_LISTMODELRESPONSE']._serialized_start=682
+  _globals['_LISTMODELRESPONSE']._serialized_end=722
+  _globals['_SETMODELREQUEST']._serialized_start=724
+  _globals['_SETMODELREQUEST']._serialized_end=761
+  _globals['_SETMODELRESPONSE']._serialized_start=763
+  _globals['_SETMODELRESPONSE']._serialized_end=801
+  _globals['_GETCURRENTMODELREQUEST']._serialized_start=803
+  _globals['_GETCURRENTMODELREQUEST']._serialized_end=827
+  _globals['_GETCURRENTMODELRESPONSE']._serialized_start=829
+  _globals['_GETCURRENTMODELRESPONSE']._serialized_end=874
+  _globals['_CLEARMODELREQUEST']._serialized_start=876
+  _globals['_CLEARMODELREQUEST']._serialized_end=895
+  _globals['_CLEARMODELRESPONSE']._serialized_start=897
+  _globals['_CLEARMODELRESPONSE']._serialized_end=972
+  _globals['_GETCURRENTLORASREQUEST']._serialized_start=974
+  _globals['_GETCURRENTLORASREQUEST']._serialized_end=998
+  _globals['_GETCURRENTLORASRESPONSE']._serialized_start=1000
+  _globals['_GETCURRENTLORASRESPONSE']._serialized_end=1060
+  _globals['_CLEARLORASREQUEST']._serialized_start=1062
+  _globals['_CLEARLORASREQUEST']._serialized_end=1081
+  _globals['_CLEARLORASRESPONSE']._serialized_start=1083
+  _globals['_CLEARLORASRESPONSE']._serialized_end=1138
+  _globals['_LISTLORASREQUEST']._serialized_start=1140
+  _globals['_LISTLORASREQUEST']._serialized_end=1158
+  _globals['_LISTLORASRESPONSE']._serialized_start=1160
+  _globals['_LISTLORASRESPONSE']._serialized_end=1198
+  _globals['_SETLORA']._serialized_start=1200
+  _globals['_SETLORA']._serialized_end=1239
+  _globals['_SETLORAREQUEST']._serialized_start=1241
+  _globals['_SETLORAREQUEST']._serialized_end=1292
+  _globals['_SETLORARESPONSE']._serialized_start=1294
+  _globals['_SETLORARESPONSE']._serialized_end=1346
+  _globals['_IMAGESERVICE']._serialized_start=1349
+  _globals['_IMAGESERVICE']._serialized_end=2147
 # @@protoc_insertion_point(module_scope)
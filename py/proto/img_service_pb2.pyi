@@ -24,15 +24,51 @@ class GenerateImageRequest(google.protobuf.message.Message):
 
     POSITIVE_PROMPT_FIELD_NUMBER: builtins.int
     NEGATIVE_PROMPT_FIELD_NUMBER: builtins.int
+    SEED_FIELD_NUMBER: builtins.int
+    STEPS_FIELD_NUMBER: builtins.int
+    GUIDANCE_SCALE_FIELD_NUMBER: builtins.int
+    WIDTH_FIELD_NUMBER: builtins.int
+    HEIGHT_FIELD_NUMBER: builtins.int
+    SCHEDULER_FIELD_NUMBER: builtins.int
+    CLIP_SKIP_FIELD_NUMBER: builtins.int
+    BATCH_SIZE_FIELD_NUMBER: builtins.int
+    PREVIEW_INTERVAL_FIELD_NUMBER: builtins.int
     positive_prompt: builtins.str
     negative_prompt: builtins.str
+    seed: builtins.int
+    """Unset lets the worker pick a random seed; the one used is echoed back."""
+    steps: builtins.int
+    """Zero values fall back to the worker defaults."""
+    guidance_scale: builtins.float
+    """Unset uses the worker default; 0 disables classifier-free guidance."""
+    width: builtins.int
+    height: builtins.int
+    scheduler: builtins.str
+    clip_skip: builtins.int
+    batch_size: builtins.int
+    preview_interval: builtins.int
+    """Streaming only: attach a low-resolution preview every N steps; 0 disables previews."""
     def __init__(
         self,
         *,
         positive_prompt: builtins.str = ...,
         negative_prompt: builtins.str = ...,
+        seed: builtins.int | None = ...,
+        steps: builtins.int = ...,
+        guidance_scale: builtins.float | None = ...,
+        width: builtins.int = ...,
+        height: builtins.int = ...,
+        scheduler: builtins.str = ...,
+        clip_skip: builtins.int = ...,
+        batch_size: builtins.int = ...,
+        preview_interval: builtins.int = ...,
     ) -> None: ...
-    def ClearField(self, field_name: typing.Literal["negative_prompt", b"negative_prompt", "positive_prompt", b"positive_prompt"]) -> None: ...
+    def HasField(self, field_name: typing.Literal["_guidance_scale", b"_guidance_scale", "_seed", b"_seed", "guidance_scale", b"guidance_scale", "seed", b"seed"]) -> builtins.bool: ...
+    def ClearField(self, field_name: typing.Literal["_guidance_scale", b"_guidance_scale", "_seed", b"_seed", "batch_size", b"batch_size", "clip_skip", b"clip_skip", "guidance_scale", b"guidance_scale", "height", b"height", "negative_prompt", b"negative_prompt", "positive_prompt", b"positive_prompt", "preview_interval", b"preview_interval", "scheduler", b"scheduler", "seed", b"seed", "steps", b"steps", "width", b"width"]) -> None: ...
+    @typing.overload
+    def WhichOneof(self, oneof_group: typing.Literal["_guidance_scale", b"_guidance_scale"]) -> typing.Literal["guidance_scale"] | None: ...
+    @typing.overload
+    def WhichOneof(self, oneof_group: typing.Literal["_seed", b"_seed"]) -> typing.Literal["seed"] | None: ...
 
 Global___GenerateImageRequest: typing_extensions.TypeAlias = GenerateImageRequest
 
@@ -43,20 +79,75 @@ class GenerateImageResponse(google.protobuf.message.Message):
     IMAGE_FIELD_NUMBER: builtins.int
     MIME_TYPE_FIELD_NUMBER: builtins.int
     FILENAME_HINT_FIELD_NUMBER: builtins.int
+    SEED_FIELD_NUMBER: builtins.int
+    IMAGES_FIELD_NUMBER: builtins.int
     image: builtins.bytes
+    """First image of the batch."""
     mime_type: builtins.str
     filename_hint: builtins.str
+    seed: builtins.int
+    """Seed of the first image; image i of a batch used seed + i."""
+    @property
+    def images(self) -> google.protobuf.internal.containers.RepeatedScalarFieldContainer[builtins.bytes]: ...
     def __init__(
         self,
         *,
         image: builtins.bytes = ...,
         mime_type: builtins.str = ...,
         filename_hint: builtins.str = ...,
+        seed: builtins.int = ...,
+        images: collections.abc.Iterable[builtins.bytes] | None = ...,
     ) -> None: ...
-    def ClearField(self, field_name: typing.Literal["filename_hint", b"filename_hint", "image", b"image", "mime_type", b"mime_type"]) -> None: ...
+    def ClearField(self, field_name: typing.Literal["filename_hint", b"filename_hint", "image", b"image", "images", b"images", "mime_type", b"mime_type", "seed", b"seed"]) -> None: ...
 
 Global___GenerateImageResponse: typing_extensions.TypeAlias = GenerateImageResponse
 
+@typing.final
+class GenerationProgress(google.protobuf.message.Message):
+    DESCRIPTOR: google.protobuf.descriptor.Descriptor
+
+    STEP_FIELD_NUMBER: builtins.int
+    TOTAL_STEPS_FIELD_NUMBER: builtins.int
+    PREVIEW_FIELD_NUMBER: builtins.int
+    PREVIEW_MIME_TYPE_FIELD_NUMBER: builtins.int
+    step: builtins.int
+    total_steps: builtins.int
+    preview: builtins.bytes
+    preview_mime_type: builtins.str
+    def __init__(
+        self,
+        *,
+        step: builtins.int = ...,
+        total_steps: builtins.int = ...,
+        preview: builtins.bytes = ...,
+        preview_mime_type: builtins.str = ...,
+    ) -> None: ...
+    def ClearField(self, field_name: typing.Literal["preview", b"preview", "preview_mime_type", b"preview_mime_type", "step", b"step", "total_steps", b"total_steps"]) -> None: ...
+
+Global___GenerationProgress: typing_extensions.TypeAlias = GenerationProgress
+
+@typing.final
+class GenerateImageEvent(google.protobuf.message.Message):
+    DESCRIPTOR: google.protobuf.descriptor.Descriptor
+
+    PROGRESS_FIELD_NUMBER: builtins.int
+    RESULT_FIELD_NUMBER: builtins.int
+    @property
+    def progress(self) -> Global___GenerationProgress: ...
+    @property
+    def result(self) -> Global___GenerateImageResponse: ...
+    def __init__(
+        self,
+        *,
+        progress: Global___GenerationProgress | None = ...,
+        result: Global___GenerateImageResponse | None = ...,
+    ) -> None: ...
+    def HasField(self, field_name: typing.Literal["event", b"event", "progress", b"progress", "result", b"result"]) -> builtins.bool: ...
+    def ClearField(self, field_name: typing.Literal["event", b"event", "progress", b"progress", "result", b"result"]) -> None: ...
+    def WhichOneof(self, oneof_group: typing.Literal["event", b"event"]) -> typing.Literal["progress", "result"] | None: ...
+
+Global___GenerateImageEvent: typing_extensions.TypeAlias = GenerateImageEvent
+
 @typing.final
 class ListModelsRequest(google.protobuf.message.Message):
     """List Modeles"""
@@ -39,6 +39,11 @@ class ImageServiceStub(object):
                 request_serializer=img__service__pb2.GenerateImageRequest.SerializeToString,
                 response_deserializer=img__service__pb2.GenerateImageResponse.FromString,
                 _registered_method=True)
+        self.GenerateImageStream = channel.unary_stream(
+                '/generator.ImageService/GenerateImageStream',
+                request_serializer=img__service__pb2.GenerateImageRequest.SerializeToString,
+                response_deserializer=img__service__pb2.GenerateImageEvent.FromString,
+                _registered_method=True)
         self.ListModels = channel.unary_unary(
                 '/generator.ImageService/ListModels',
                 request_serializer=img__service__pb2.ListModelsRequest.SerializeToString,
@@ -90,6 +95,14 @@ class ImageServiceServicer(object):
         context.set_details('Method not implemented!')
         raise NotImplementedError('Method not implemented!')
 
+    def GenerateImageStream(self, request, context):
+        """Same as GenerateImage but reports step progress (and optional previews)
+        before the final result.
+        """
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
     def ListModels(self, request, context):
         """Missing associated documentation comment in .proto file."""
         context.set_code(grpc.StatusCode.UNIMPLEMENTED)
@@ -146,6 +159,11 @@ def add_ImageServiceServicer_to_server(servicer, server):
                     request_deserializer=img__service__pb2.GenerateImageRequest.FromString,
                     response_serializer=img__service__pb2.GenerateImageResponse.SerializeToString,
             ),
+            'GenerateImageStream': grpc.unary_stream_rpc_method_handler(
+                    servicer.GenerateImageStream,
+                    request_deserializer=img__service__pb2.GenerateImageRequest.FromString,
+                    response_serializer=img__service__pb2.GenerateImageEvent.SerializeToString,
+            ),
             'ListModels': grpc.unary_unary_rpc_method_handler(
                     servicer.ListModels,
                     request_deserializer=img__service__pb2.ListModelsRequest.FromString,
@@ -224,6 +242,33 @@ class ImageService(object):
             metadata,
             _registered_method=True)
 
+    @staticmethod
+    def GenerateImageStream(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_stream(
+            request,
+            target,
+            '/generator.ImageService/GenerateImageStream',
+            img__service__pb2.GenerateImageRequest.SerializeToString,
+            img__service__pb2.GenerateImageEvent.FromString,
+            options,
+            channel_credentials,
+            insecure,
+            call_credentials,
+            compression,
+            wait_for_ready,
+            timeout,
+            metadata,
+            _registered_method=True)
+
     @staticmethod
     def ListModels(request,
             target,
@@ -1,19 +1,33 @@
 import io
 import math
 import os
+import queue
+import random
+import threading
 from logging import Logger
 from pathlib import Path
 
 import grpc
 import torch
-from diffusers import StableDiffusionXLPipeline
+from diffusers import (
+    DDIMScheduler,
+    DPMSolverMultistepScheduler,
+    EulerAncestralDiscreteScheduler,
+    EulerDiscreteScheduler,
+    HeunDiscreteScheduler,
+    LMSDiscreteScheduler,
+    StableDiffusionXLPipeline,
+    UniPCMultistepScheduler,
+)
 from proto.img_service_pb2 import (
     ClearModelRequest,
     ClearModelResponse,
     ClearLorasRequest,
     ClearLorasResponse,
+    GenerateImageEvent,
     GenerateImageRequest,
     GenerateImageResponse,
+    GenerationProgress,
     GetCurrentModelRequest,
     GetCurrentModelResponse,
     GetCurrentLorasRequest,
@@ -26,6 +40,37 @@ from proto.img_service_pb2 import (
 )
 from proto.img_service_pb2_grpc import ImageServiceServicer
 
+DEFAULT_STEPS = 30
+DEFAULT_GUIDANCE_SCALE = 7.0
+DEFAULT_SIZE = 1024
+
+# Linear latent -> RGB approximation for SDXL latents; good enough for previews
+# without running the VAE.
+SDXL_LATENT_RGB_FACTORS = [
+    [0.3651, 0.4232, 0.4341],
+    [-0.2533, -0.0042, 0.1068],
+    [0.1076, 0.1111, -0.0362],
+    [-0.3165, -0.2492, -0.2188],
+]
+SDXL_LATENT_RGB_BIAS = [0.1084, -0.0175, -0.0011]
+
+# Keys must stay in sync with the scheduler names the Go API accepts.
+SCHEDULERS = {
+    "euler": (EulerDiscreteScheduler, {}),
+    "euler_a": (EulerAncestralDiscreteScheduler, {}),
+    "heun": (HeunDiscreteScheduler, {}),
+    "lms": (LMSDiscreteScheduler, {}),
+    "ddim": (DDIMScheduler, {}),
+    "unipc": (UniPCMultistepScheduler, {}),
+    "dpmpp_2m": (DPMSolverMultistepScheduler, {}),
+    "dpmpp_2m_karras": (DPMSolverMultistepScheduler, {"use_karras_sigmas": True}),
+    "dpmpp_2m_sde": (DPMSolverMultistepScheduler, {"algorithm_type": "sde-dpmsolver++"}),
+    "dpmpp_2m_sde_karras": (
+        DPMSolverMultistepScheduler,
+        {"algorithm_type": "sde-dpmsolver++", "use_karras_sigmas": True},
+    ),
+}
+
 class ImageService(ImageServiceServicer):
 
     def __init__(self, log: Logger):
@@ -156,6 +201,7 @@ class ImageService(ImageServiceServicer):
         self,
         positive_prompt: str,
         negative_prompt: str | None,
+        clip_skip: int | None = None,
     ) -> tuple[torch.Tensor, torch.Tensor, torch.Tensor, torch.Tensor]:
         tokenizers = []
         text_encoders = []
@@ -189,7 +235,8 @@ class ImageService(ImageServiceServicer):
             )
             num_chunks = max_chunks
 
-        clip_skip = getattr(self.pipe, "clip_skip", None)
+        if clip_skip is None:
+            clip_skip = getattr(self.pipe, "clip_skip", None)
 
         positive_embeds_parts: list[torch.Tensor] = []
         pooled_positive = None
@@ -256,17 +303,47 @@ class ImageService(ImageServiceServicer):
                 return True
         return False
 
-    def GenerateImage(self, request: GenerateImageRequest, context):
+    def _pipeline_for(self, name: str, context) -> StableDiffusionXLPipeline:
+        # Requests can run concurrently, so each one gets a pipeline of its
+        # own that shares the loaded components (no weights are copied) but
+        # has a fresh scheduler: schedulers keep per-run step state, and
+        # swapping the shared pipe.scheduler would leak into other requests.
+        default = getattr(self, "default_scheduler", None)
+        if default is None:
+            default = self.pipe.scheduler
+            self.default_scheduler = default
+
+        if name:
+            entry = SCHEDULERS.get(name)
+            if entry is None:
+                context.abort(grpc.StatusCode.INVALID_ARGUMENT, f"Unknown scheduler: {name}")
+            scheduler_cls, overrides = entry
+        else:
+            scheduler_cls, overrides = type(default), {}
+        scheduler = scheduler_cls.from_config(default.config, **overrides)
+        return type(self.pipe)(**{**self.pipe.components, "scheduler": scheduler})
+
+    def _prepare_generation(self, request: GenerateImageRequest, context) -> tuple[StableDiffusionXLPipeline, dict, int]:
         if not hasattr(self, "pipe") or self.pipe is None:
             context.abort(grpc.StatusCode.FAILED_PRECONDITION, "Model must be set before generating images.")
 
+        steps = request.steps or DEFAULT_STEPS
+        guidance_scale = request.guidance_scale if request.HasField("guidance_scale") else DEFAULT_GUIDANCE_SCALE
+        width = request.width or DEFAULT_SIZE
+        height = request.height or DEFAULT_SIZE
+        batch_size = max(1, request.batch_size)
+        clip_skip = request.clip_skip or None
+        seed = request.seed if request.HasField("seed") else random.randrange(2**32)
+
+        pipe = self._pipeline_for(request.scheduler, context)
+
         try:
             (
                 prompt_embeds,
                 negative_prompt_embeds,
                 pooled_prompt_embeds,
                 negative_pooled_prompt_embeds,
-            ) = self._encode_long_prompts_for_sdxl(request.positive_prompt, request.negative_prompt)
+            ) = self._encode_long_prompts_for_sdxl(request.positive_prompt, request.negative_prompt, clip_skip)
             prompt = None
             negative_prompt = None
         except Exception:
@@ -278,28 +355,107 @@ class ImageService(ImageServiceServicer):
             prompt = request.positive_prompt
             negative_prompt = request.negative_prompt
 
-        image = self.pipe(
-            height=1024,
-            width=1024,
+        device = self._execution_device_for_pipe()
+        generators = [torch.Generator(device=device).manual_seed(seed + i) for i in range(batch_size)]
+
+        kwargs = dict(
+            height=height,
+            width=width,
             prompt=prompt,
             negative_prompt=negative_prompt,
             prompt_embeds=prompt_embeds,
             negative_prompt_embeds=negative_prompt_embeds,
             pooled_prompt_embeds=pooled_prompt_embeds,
             negative_pooled_prompt_embeds=negative_pooled_prompt_embeds,
-            num_inference_steps=30,
-            guidance_scale=7,
-        ).images[0]
+            num_inference_steps=steps,
+            guidance_scale=guidance_scale,
+            num_images_per_prompt=batch_size,
+            generator=generators,
+            clip_skip=clip_skip if prompt is not None else None,
+        )
+        return pipe, kwargs, seed
 
-        buf = io.BytesIO()
-        image.save(buf, format="png")
+    def _generate_response(self, seed: int, images) -> GenerateImageResponse:
+        encoded: list[bytes] = []
+        for image in images:
+            buf = io.BytesIO()
+            image.save(buf, format="png")
+            encoded.append(buf.getvalue())
 
         return GenerateImageResponse(
-            image=buf.getvalue(),
+            image=encoded[0],
             mime_type="image/png",
-            filename_hint="sdxl.png"
+            filename_hint=f"sdxl-{seed}.png",
+            seed=seed,
+            images=encoded,
         )
-            
+
+    def _latent_preview(self, latents: torch.Tensor) -> bytes:
+        from PIL import Image
+
+        with torch.no_grad():
+            latent = latents[0].float()
+            factors = torch.tensor(SDXL_LATENT_RGB_FACTORS, device=latent.device)
+            bias = torch.tensor(SDXL_LATENT_RGB_BIAS, device=latent.device)
+            rgb = torch.einsum("chw,cr->hwr", latent, factors) + bias
+            rgb = ((rgb.clamp(-1, 1) + 1) * 127.5).to(torch.uint8).cpu().numpy()
+
+        buf = io.BytesIO()
+        Image.fromarray(rgb, mode="RGB").save(buf, format="jpeg", quality=70)
+        return buf.getvalue()
+
+    def GenerateImage(self, request: GenerateImageRequest, context):
+        pipe, kwargs, seed = self._prepare_generation(request, context)
+        images = pipe(**kwargs).images
+        return self._generate_response(seed, images)
+
+    def GenerateImageStream(self, request: GenerateImageRequest, context):
+        pipe, kwargs, seed = self._prepare_generation(request, context)
+        total_steps = kwargs["num_inference_steps"]
+        preview_interval = request.preview_interval
+        events: queue.Queue = queue.Queue()
+
+        def on_step_end(pipe, step, timestep, callback_kwargs):
+            if not context.is_active():
+                # Client went away; stop denoising early.
+                pipe._interrupt = True
+                return callback_kwargs
+
+            current = step + 1
+            progress = GenerationProgress(step=current, total_steps=total_steps)
+            if preview_interval > 0 and current % preview_interval == 0 and current < total_steps:
+                try:
+                    progress.preview = self._latent_preview(callback_kwargs["latents"])
+                    progress.preview_mime_type = "image/jpeg"
+                except Exception:
+                    self.log.exception("Failed to render latent preview")
+            events.put(GenerateImageEvent(progress=progress))
+            return callback_kwargs
+
+        def run():
+            try:
+                images = pipe(
+                    **kwargs,
+                    callback_on_step_end=on_step_end,
+                    callback_on_step_end_tensor_inputs=["latents"],
+                ).images
+                events.put(GenerateImageEvent(result=self._generate_response(seed, images)))
+            except Exception as e:
+                events.put(e)
+            finally:
+                events.put(None)
+
+        threading.Thread(target=run, daemon=True).start()
+
+        while True:
+            item = events.get()
+            if item is None:
+                return
+            if isinstance(item, Exception):
+                self.log.error(f"Streaming generation failed: {item}")
+                context.abort(grpc.StatusCode.INTERNAL, f"Generation failed: {item}")
+            yield item
+
     def SetModel(self, request: SetModelRequest, context):
         model_path = Path(request.model_path)
         if not model_path.exists() or model_path.is_dir():
@@ -312,6 +468,7 @@ class ImageService(ImageServiceServicer):
             str(model_path),
             torch_dtype=torch.float16,
         ).to("cuda")
+        self.default_scheduler = self.pipe.scheduler
         self.model_path = str(model_path)
         self.current_loras = []
 
@@ -335,6 +492,7 @@ class ImageService(ImageServiceServicer):
 
         self.model_path = ""
         self.current_loras = []
+        self.default_scheduler = None
         try:
             torch.cuda.empty_cache()
         except Exception: